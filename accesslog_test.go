@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/decred/slog"
+)
+
+// TestLogRequests checks that a line with the request and the status of its
+// response is logged at the debug level only.
+func TestLogRequests(t *testing.T) {
+	var buf bytes.Buffer
+	prevLog := log
+	log = slog.NewBackend(&buf).Logger("DHUB")
+	defer func() { log = prevLog }()
+
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	handler := hub.logRequests(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "404 Not Found.", http.StatusNotFound)
+		},
+	))
+
+	// Nothing is logged above the debug level.
+	log.SetLevel(slog.LevelInfo)
+	r := httptest.NewRequest("GET", "/missing", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if buf.Len() != 0 {
+		t.Fatalf("request logged at the info level: %q", buf.String())
+	}
+
+	log.SetLevel(slog.LevelDebug)
+	r = httptest.NewRequest("GET", "/missing", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := buf.String()
+	want := "[DBG] DHUB: 192.0.2.1 GET /missing 404 "
+	if !strings.Contains(line, want) {
+		t.Fatalf("got log line %q, want it to contain %q", line, want)
+	}
+}
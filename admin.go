@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// isAdmin returns true if the request carries the configured admin token as
+// an "Authorization: Bearer" header. The token is compared in constant time.
+// No request is considered to be from an admin if no token is configured.
+func (h *lightningHub) isAdmin(r *http.Request) bool {
+	if h.cfg.AdminToken == "" {
+		return false
+	}
+
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return false
+	}
+	token := strings.TrimPrefix(auth, prefix)
+
+	return subtle.ConstantTimeCompare(
+		[]byte(token), []byte(h.cfg.AdminToken),
+	) == 1
+}
+
+// adminChannelsResponse is the response of the admin channels endpoint.
+type adminChannelsResponse struct {
+	Visibility string           `json:"visibility"`
+	Channels   []*lnrpc.Channel `json:"channels"`
+}
+
+// AdminChannels returns the hub's channels as JSON, including the private
+// ones which are never shown publicly. The visibility query param selects
+// the public, private or all channels.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) AdminChannels(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	visibility, err := parseVisibility(r.URL.Query().Get("visibility"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, &adminChannelsResponse{
+		Visibility: visibility,
+		Channels:   filterChannels(homeInfo.ActiveChannels, visibility),
+	})
+}
+
+// AdminRefresh refreshes the hub's data from dcrlnd right away, e.g. after
+// the operator opened a channel out-of-band, and returns the refreshed stats
+// as JSON.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) AdminRefresh(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	// The refresh is shared with the stale reads, so dcrlnd isn't sent
+	// concurrent bursts of RPCs. A refresh already in flight may have
+	// fetched its data before the operator's change though, in which case
+	// a new one is triggered once it's done.
+	requested := time.Now()
+	var refresh *pendingRefresh
+	for refresh == nil || refresh.started.Before(requested) {
+		refresh = h.triggerRefresh()
+		select {
+		case <-refresh.done:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if refresh.err != nil {
+		log.Errorf("unable to refresh data from dcrlnd: %v",
+			refresh.err)
+		http.Error(w, "unable to refresh data from dcrlnd",
+			http.StatusBadGateway)
+		return
+	}
+
+	log.Infof("Data refreshed from dcrlnd on admin request")
+	writeJSON(w, newInfoResponse(h.cachedContext()))
+}
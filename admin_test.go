@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// adminRefresh sends an admin refresh request to the passed hub.
+func adminRefresh(hub *lightningHub, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/admin/refresh", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	hub.AdminRefresh(w, r)
+
+	return w
+}
+
+// TestAdminRefresh checks that an admin refresh caches and returns fresh
+// data, and reports a failure to reach dcrlnd.
+func TestAdminRefresh(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminToken = "token"
+	lnd := &fakeLnd{info: testnetInfo()}
+	hub := newTestHub(t, cfg, lnd)
+
+	if w := adminRefresh(hub, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without the token, got %d",
+			w.Code)
+	}
+	if n := lnd.callCount("GetInfo"); n != 0 {
+		t.Fatalf("unauthorized request refreshed the data")
+	}
+	if w := adminRefresh(hub, "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 with a wrong token, got %d",
+			w.Code)
+	}
+	if n := lnd.callCount("GetInfo"); n != 0 {
+		t.Fatalf("request with a wrong token refreshed the data")
+	}
+
+	w := adminRefresh(hub, "token")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	var info infoResponse
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	if info.Pubkey != "02aa" {
+		t.Fatalf("expected node 02aa, got %q", info.Pubkey)
+	}
+	if hub.cachedContext() == nil {
+		t.Fatal("refreshed data not cached")
+	}
+
+	lnd.errs = map[string][]error{
+		"GetInfo": {status.Error(codes.Internal, "wallet locked")},
+	}
+	if w := adminRefresh(hub, "token"); w.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502 on failure, got %d", w.Code)
+	}
+}
+
+// TestAdminRefreshInFlight checks that an admin refresh waits for a refresh
+// already in flight instead of racing it, then fetches data again since the
+// refresh in flight may predate the operator's change.
+func TestAdminRefreshInFlight(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminToken = "token"
+	lnd := &fakeLnd{info: testnetInfo(), delay: 50 * time.Millisecond}
+	hub := newTestHub(t, cfg, lnd)
+
+	inFlight := hub.triggerRefresh()
+	w := adminRefresh(hub, "token")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	select {
+	case <-inFlight.done:
+	default:
+		t.Fatal("admin refresh returned before the refresh in flight")
+	}
+	if n := lnd.callCount("GetInfo"); n != 2 {
+		t.Fatalf("expected 2 sequential refreshes, got %d GetInfo "+
+			"calls", n)
+	}
+}
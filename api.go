@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// writeJSON writes v as the JSON response of the request.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("unable to encode JSON response: %v", err)
+	}
+}
+
+// setCacheHeaders lets clients cache API responses built from the cached
+// context for as long as it's expected to stay unchanged.
+func (h *lightningHub) setCacheHeaders(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d",
+		int(h.cfg.RefreshInterval.Seconds())))
+}
+
+// infoResponse is the response of the info API.
+type infoResponse struct {
+	Pubkey           string         `json:"pubkey"`
+	Alias            string         `json:"alias"`
+	NodeAddr         string         `json:"node_addr"`
+	NodeURIs         []string       `json:"node_uris"`
+	Network          string         `json:"network"`
+	ChannelsCount    uint32         `json:"channels_count"`
+	InactiveChannels uint32         `json:"inactive_channels"`
+	Capacity         int64          `json:"capacity"`
+	Balance          dcrutil.Amount `json:"balance"`
+}
+
+// newInfoResponse returns the info API response for the passed context.
+func newInfoResponse(homeInfo *templateContext) *infoResponse {
+	return &infoResponse{
+		Pubkey:           homeInfo.NodePubkey,
+		Alias:            homeInfo.Alias,
+		NodeAddr:         homeInfo.NodeAddr,
+		NodeURIs:         homeInfo.NodeURIs,
+		Network:          homeInfo.Network,
+		ChannelsCount:    homeInfo.ChannelsCount,
+		InactiveChannels: homeInfo.InactiveCount,
+		Capacity:         homeInfo.Capacity,
+		Balance:          homeInfo.Balance,
+	}
+}
+
+// APIInfo returns the node and channel stats shown on the home page as JSON.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) APIInfo(w http.ResponseWriter, r *http.Request) {
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.setCacheHeaders(w)
+	writeJSON(w, newInfoResponse(homeInfo))
+}
+
+const (
+	// defaultChannelsLimit is the number of channels returned by the
+	// channels API when no limit is requested.
+	defaultChannelsLimit = 100
+
+	// maxChannelsLimit is the maximum number of channels returned by a
+	// single request to the channels API. Larger limits are clamped.
+	maxChannelsLimit = 1000
+)
+
+// channelsResponse is the response of the channels API.
+type channelsResponse struct {
+	Count      int              `json:"count"`
+	Total      int              `json:"total"`
+	Offset     int              `json:"offset"`
+	NextOffset *int             `json:"next_offset,omitempty"`
+	Channels   []*lnrpc.Channel `json:"channels"`
+}
+
+// parsePage parses the limit and offset query params selecting a page of the
+// channels API. The limit defaults to defaultChannelsLimit and is clamped to
+// maxChannelsLimit.
+func parsePage(values url.Values) (int, int, error) {
+	limit := defaultChannelsLimit
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("limit: invalid value %q, must "+
+				"be a positive number", v)
+		}
+		limit = n
+	}
+	if limit > maxChannelsLimit {
+		limit = maxChannelsLimit
+	}
+
+	var offset int
+	if v := values.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("offset: invalid value %q, must "+
+				"be a positive number", v)
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// APIChannels returns a page of the channels the visitor is allowed to see as
+// JSON (see channelVisibility), filtered and sorted according to the query
+// params (see parseChannelQuery and parsePage). The channels are sorted by
+// channel point unless another sort is requested, so the pages are stable
+// across requests.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) APIChannels(w http.ResponseWriter, r *http.Request) {
+	visibility, ok := h.channelVisibility(r)
+	if !ok {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	query, err := parseChannelQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	channels := filterChannels(homeInfo.ActiveChannels, visibility)
+	channels = query.apply(channels)
+
+	res := &channelsResponse{
+		Total:    len(channels),
+		Offset:   offset,
+		Channels: []*lnrpc.Channel{},
+	}
+	if offset < len(channels) {
+		end := offset + limit
+		if end < len(channels) {
+			res.NextOffset = &end
+		} else {
+			end = len(channels)
+		}
+		res.Channels = channels[offset:end]
+	}
+	res.Count = len(res.Channels)
+
+	h.setCacheHeaders(w)
+	writeJSON(w, res)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestAPIInfo checks that the info API returns the stats of the cached
+// context.
+func TestAPIInfo(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		NodePubkey:    "02aa",
+		Alias:         "hub",
+		NodeAddr:      "02aa@127.0.0.1:9735",
+		NodeURIs:      []string{"02aa@127.0.0.1:9735", "02aa@[::1]:9735"},
+		Network:       "testnet",
+		ChannelsCount: 3,
+		InactiveCount: 1,
+		Capacity:      150000000,
+		Balance:       300000,
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	hub.APIInfo(w, httptest.NewRequest("GET", "/api/v1/info", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var res infoResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	want := infoResponse{
+		Pubkey:           "02aa",
+		Alias:            "hub",
+		NodeAddr:         "02aa@127.0.0.1:9735",
+		NodeURIs:         []string{"02aa@127.0.0.1:9735", "02aa@[::1]:9735"},
+		Network:          "testnet",
+		ChannelsCount:    3,
+		InactiveChannels: 1,
+		Capacity:         150000000,
+		Balance:          300000,
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Fatalf("got info %+v, want %+v", res, want)
+	}
+}
+
+// TestAPIChannelsPages checks that walking the pages of the channels API
+// returns every public channel exactly once, in channel point order.
+func TestAPIChannelsPages(t *testing.T) {
+	// The channels are cached out of order, with a private one which
+	// must never be listed.
+	chanPoints := []string{"ee:0", "aa:1", "cc:0", "gg:0", "aa:0",
+		"ff:0", "bb:0"}
+	var channels []*lnrpc.Channel
+	for _, chanPoint := range chanPoints {
+		channels = append(channels, &lnrpc.Channel{
+			ChannelPoint: chanPoint,
+		})
+	}
+	channels = append(channels, &lnrpc.Channel{
+		ChannelPoint: "dd:0",
+		Private:      true,
+	})
+
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	err := hub.setContext(&templateContext{
+		DcrlndVersion:  "0.2.1",
+		ActiveChannels: channels,
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	var listed []string
+	offset := 0
+	for pages := 0; ; pages++ {
+		if pages > len(chanPoints) {
+			t.Fatalf("pages don't end after %d requests", pages)
+		}
+
+		path := fmt.Sprintf("/api/channels?limit=3&offset=%d", offset)
+		w := httptest.NewRecorder()
+		hub.APIChannels(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d, want 200", path, w.Code)
+		}
+
+		var res channelsResponse
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("%s: unable to decode response: %v", path, err)
+		}
+		if res.Total != len(chanPoints) {
+			t.Fatalf("%s: got total %d, want %d", path, res.Total,
+				len(chanPoints))
+		}
+		if res.Offset != offset || res.Count != len(res.Channels) {
+			t.Fatalf("%s: got offset %d and count %d with %d "+
+				"channels", path, res.Offset, res.Count,
+				len(res.Channels))
+		}
+		for _, channel := range res.Channels {
+			listed = append(listed, channel.ChannelPoint)
+		}
+
+		if res.NextOffset == nil {
+			break
+		}
+		if *res.NextOffset != offset+res.Count {
+			t.Fatalf("%s: got next offset %d, want %d", path,
+				*res.NextOffset, offset+res.Count)
+		}
+		offset = *res.NextOffset
+	}
+
+	want := []string{"aa:0", "aa:1", "bb:0", "cc:0", "ee:0", "ff:0",
+		"gg:0"}
+	if fmt.Sprint(listed) != fmt.Sprint(want) {
+		t.Fatalf("got channels %v, want %v", listed, want)
+	}
+
+	// Past the last page, there are no channels left.
+	w := httptest.NewRecorder()
+	hub.APIChannels(w, httptest.NewRequest(
+		"GET", "/api/channels?offset=100", nil,
+	))
+	var res channelsResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	if res.Count != 0 || res.NextOffset != nil {
+		t.Fatalf("got %d channels and next offset %v past the last "+
+			"page", res.Count, res.NextOffset)
+	}
+}
+
+// TestAPIChannelsVisibility checks that the channels API is behind the admin
+// auth when the hub only publishes aggregates, and lists the private channels
+// to admins.
+func TestAPIChannelsVisibility(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PublicAggregateOnly = true
+	cfg.AdminToken = "secret"
+	hub := newTestHub(t, cfg, &fakeLnd{})
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		ActiveChannels: []*lnrpc.Channel{{
+			ChannelPoint: "aa:0",
+		}, {
+			ChannelPoint: "bb:0",
+			Private:      true,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	hub.APIChannels(w, httptest.NewRequest("GET", "/api/channels", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("visitor: got status %d, want 401", w.Code)
+	}
+
+	r := httptest.NewRequest("GET", "/api/channels", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	hub.APIChannels(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin: got status %d, want 200", w.Code)
+	}
+	var res channelsResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	if res.Total != 2 {
+		t.Fatalf("admin: got %d channels, want 2", res.Total)
+	}
+}
+
+// TestParsePage checks the validation and clamping of the page params.
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"defaults", "", defaultChannelsLimit, 0, false},
+		{"page", "limit=10&offset=20", 10, 20, false},
+		{"clamped", "limit=5000", maxChannelsLimit, 0, false},
+		{"zero limit", "limit=0", 0, 0, true},
+		{"negative offset", "offset=-1", 0, 0, true},
+		{"invalid limit", "limit=ten", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		values, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatalf("%s: invalid query: %v", test.name, err)
+		}
+
+		limit, offset, err := parsePage(values)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if limit != test.wantLimit || offset != test.wantOffset {
+			t.Errorf("%s: got limit %d and offset %d, want %d and "+
+				"%d", test.name, limit, offset, test.wantLimit,
+				test.wantOffset)
+		}
+	}
+}
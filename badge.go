@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxBadgeLabelLen is the maximum length of a badge label chosen
+	// through the label query param.
+	maxBadgeLabelLen = 32
+
+	// badgeCharWidth is the approximate width in pixels of a character of
+	// the badge font, used to size the badge to its text.
+	badgeCharWidth = 7
+
+	// badgePadding is the horizontal padding in pixels around each of the
+	// badge's texts.
+	badgePadding = 10
+
+	// badgeLabelColor is the background color of the label part of the
+	// badges.
+	badgeLabelColor = "#555"
+)
+
+// badgeTemplate is the SVG of the badges, formatted with the total width,
+// label, value, label width, value width and color, followed by the
+// positions of the label and value texts.
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">
+<title>%[2]s: %[3]s</title>
+<rect width="%[4]d" height="20" fill="` + badgeLabelColor + `"/>
+<rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="14">%[2]s</text>
+<text x="%[8]d" y="14">%[3]s</text>
+</g>
+</svg>
+`
+
+// badgeValue returns the default label and the value of the named badge from
+// the passed context.
+func badgeValue(name string, homeInfo *templateContext) (string, string) {
+	switch name {
+	case "capacity":
+		return "capacity", dcrutil.Amount(homeInfo.Capacity).String()
+	default:
+		return "channels", strconv.FormatUint(
+			uint64(homeInfo.ChannelsCount), 10,
+		)
+	}
+}
+
+// Badge renders a small SVG badge showing the hub's number of channels or
+// capacity, to be embedded on other websites through an img tag. The label
+// and color query params override the badge's label and the configured
+// color.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Badge(w http.ResponseWriter, r *http.Request) {
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	label, value := badgeValue(mux.Vars(r)["name"], homeInfo)
+	if l := r.URL.Query().Get("label"); l != "" {
+		if len(l) > maxBadgeLabelLen {
+			http.Error(w, fmt.Sprintf("label longer than %d bytes",
+				maxBadgeLabelLen), http.StatusBadRequest)
+			return
+		}
+		label = l
+	}
+
+	color := h.cfg.BadgeColor
+	if c := r.URL.Query().Get("color"); c != "" {
+		if !nodeColorPattern.MatchString(c) {
+			http.Error(w, "color must be in the #rrggbb format",
+				http.StatusBadRequest)
+			return
+		}
+		color = c
+	}
+
+	labelWidth := len([]rune(label))*badgeCharWidth + 2*badgePadding
+	valueWidth := len([]rune(value))*badgeCharWidth + 2*badgePadding
+
+	h.setCacheHeaders(w)
+	w.Header().Set("Content-Type", "image/svg+xml")
+	fmt.Fprintf(w, badgeTemplate, labelWidth+valueWidth,
+		html.EscapeString(label), html.EscapeString(value), labelWidth,
+		valueWidth, color, labelWidth/2, labelWidth+valueWidth/2)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestBadge checks that the badges embed the current value from the cached
+// context, with the requested label and color.
+func TestBadge(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BadgeColor = defaultBadgeColor
+	hub := newTestHub(t, cfg, &fakeLnd{})
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		ChannelsCount: 42,
+		Capacity:      150000000,
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/badge/{name:channels|capacity}.svg", hub.Badge)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		want     []string
+	}{{
+		name:     "channels",
+		path:     "/badge/channels.svg",
+		wantCode: http.StatusOK,
+		want: []string{"<title>channels: 42</title>",
+			`fill="` + defaultBadgeColor + `"`},
+	}, {
+		name:     "capacity",
+		path:     "/badge/capacity.svg",
+		wantCode: http.StatusOK,
+		want:     []string{"<title>capacity: 1.5 DCR</title>"},
+	}, {
+		name:     "custom label and color",
+		path:     "/badge/channels.svg?label=hub%20%3Cchans%3E&color=%23ff0000",
+		wantCode: http.StatusOK,
+		want: []string{"<title>hub &lt;chans&gt;: 42</title>",
+			`fill="#ff0000"`},
+	}, {
+		name:     "invalid color",
+		path:     "/badge/channels.svg?color=red",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name: "label too long",
+		path: "/badge/channels.svg?label=" +
+			strings.Repeat("a", maxBadgeLabelLen+1),
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "unknown badge",
+		path:     "/badge/balance.svg",
+		wantCode: http.StatusNotFound,
+	}}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+			continue
+		}
+		if test.wantCode != http.StatusOK {
+			continue
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+			t.Errorf("%s: got content type %q", test.name, ct)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc == "" {
+			t.Errorf("%s: no Cache-Control header", test.name)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("%s: badge doesn't contain %q: %s",
+					test.name, want, w.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// basicAuthExempt are the paths reachable without the site's credentials, so
+// that uptime probes keep working on a protected hub.
+var basicAuthExempt = map[string]bool{
+	"/healthz": true,
+}
+
+// basicAuthCache holds the SHA-256 digest of the last credentials which
+// matched the configured ones. Comparing a password to its bcrypt hash is
+// slow by design, so it's only done once, instead of on every request of
+// every visitor.
+type basicAuthCache struct {
+	mtx      sync.RWMutex
+	digest   [sha256.Size]byte
+	verified bool
+}
+
+// match returns true if the passed digest is the one of the verified
+// credentials.
+func (c *basicAuthCache) match(digest [sha256.Size]byte) bool {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.verified && subtle.ConstantTimeCompare(
+		digest[:], c.digest[:],
+	) == 1
+}
+
+// set caches the digest of verified credentials.
+func (c *basicAuthCache) set(digest [sha256.Size]byte) {
+	c.mtx.Lock()
+	c.digest = digest
+	c.verified = true
+	c.mtx.Unlock()
+}
+
+// checkBasicAuth returns true if the passed request carries the configured
+// basic auth credentials.
+func (h *lightningHub) checkBasicAuth(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	// The user name can't contain a colon, so the digest is unambiguous.
+	digest := sha256.Sum256([]byte(user + ":" + pass))
+	if h.basicAuthCache.match(digest) {
+		return true
+	}
+
+	// Both checks always run so that a wrong user name can't be told
+	// apart from a wrong password by the time the response takes.
+	userOK := subtle.ConstantTimeCompare(
+		[]byte(user), []byte(h.cfg.BasicAuthUser),
+	) == 1
+	passOK := bcrypt.CompareHashAndPassword(
+		[]byte(h.cfg.BasicAuthPass), []byte(pass),
+	) == nil
+
+	if !userOK || !passOK {
+		return false
+	}
+	h.basicAuthCache.set(digest)
+
+	return true
+}
+
+// basicAuth is a middleware requiring the configured basic auth credentials
+// on every request, when they're set. Requests carrying a valid admin token
+// are let through, since they can't send both in the Authorization header.
+func (h *lightningHub) basicAuth(next http.Handler) http.Handler {
+	if h.cfg.BasicAuthUser == "" {
+		return next
+	}
+
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"",
+		h.cfg.SiteName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if basicAuthExempt[r.URL.Path] || h.isAdmin(r) ||
+			h.checkBasicAuth(r) {
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", challenge)
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+	})
+}
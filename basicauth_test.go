@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestBasicAuth checks that the whole site requires the configured
+// credentials, and only when they're set.
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword(
+		[]byte("secret"), bcrypt.MinCost,
+	)
+	if err != nil {
+		t.Fatalf("unable to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		authUser string
+		path     string
+		user     string
+		pass     string
+		noAuth   bool
+		wantCode int
+	}{{
+		name:     "disabled",
+		path:     "/",
+		noAuth:   true,
+		wantCode: http.StatusOK,
+	}, {
+		name:     "correct credentials",
+		authUser: "alice",
+		path:     "/",
+		user:     "alice",
+		pass:     "secret",
+		wantCode: http.StatusOK,
+	}, {
+		name:     "wrong password",
+		authUser: "alice",
+		path:     "/",
+		user:     "alice",
+		pass:     "guess",
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "wrong user",
+		authUser: "alice",
+		path:     "/",
+		user:     "bob",
+		pass:     "secret",
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "no credentials",
+		authUser: "alice",
+		path:     "/",
+		noAuth:   true,
+		wantCode: http.StatusUnauthorized,
+	}, {
+		name:     "exempt path",
+		authUser: "alice",
+		path:     "/healthz",
+		noAuth:   true,
+		wantCode: http.StatusOK,
+	}}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.SiteName = "hub"
+		cfg.BasicAuthUser = test.authUser
+		cfg.BasicAuthPass = string(hash)
+		hub := newTestHub(t, cfg, &fakeLnd{})
+
+		r := httptest.NewRequest("GET", test.path, nil)
+		if !test.noAuth {
+			r.SetBasicAuth(test.user, test.pass)
+		}
+		w := httptest.NewRecorder()
+		hub.basicAuth(ok).ServeHTTP(w, r)
+
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+		}
+		challenge := w.Header().Get("WWW-Authenticate")
+		if (w.Code == http.StatusUnauthorized) != (challenge != "") {
+			t.Errorf("%s: got status %d with challenge %q",
+				test.name, w.Code, challenge)
+		}
+	}
+}
+
+// TestBasicAuthCache checks that verified credentials are cached, so the
+// bcrypt hash isn't checked on every request, while other credentials still
+// are.
+func TestBasicAuthCache(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword(
+		[]byte("secret"), bcrypt.MinCost,
+	)
+	if err != nil {
+		t.Fatalf("unable to hash password: %v", err)
+	}
+	cfg := newTestConfig()
+	cfg.BasicAuthUser = "alice"
+	cfg.BasicAuthPass = string(hash)
+	hub := newTestHub(t, cfg, &fakeLnd{})
+
+	request := func(user, pass string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetBasicAuth(user, pass)
+		return r
+	}
+
+	if !hub.checkBasicAuth(request("alice", "secret")) {
+		t.Fatal("correct credentials rejected")
+	}
+
+	// Once verified, the credentials are accepted from the cache alone,
+	// even with a hash they wouldn't match anymore.
+	hub.cfg.BasicAuthPass = "invalid hash"
+	if !hub.checkBasicAuth(request("alice", "secret")) {
+		t.Fatal("verified credentials not cached")
+	}
+	if hub.checkBasicAuth(request("alice", "secret2")) {
+		t.Fatal("wrong password accepted from the cache")
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+const (
+	// visibilityPublic selects only the public channels.
+	visibilityPublic = "public"
+
+	// visibilityPrivate selects only the private channels.
+	visibilityPrivate = "private"
+
+	// visibilityAll selects every channel.
+	visibilityAll = "all"
+)
+
+// parseVisibility validates a channel visibility, defaulting to all channels
+// when empty.
+func parseVisibility(visibility string) (string, error) {
+	switch visibility {
+	case "":
+		return visibilityAll, nil
+	case visibilityPublic, visibilityPrivate, visibilityAll:
+		return visibility, nil
+	default:
+		return "", fmt.Errorf("invalid visibility %q, must be one of "+
+			"public, private or all", visibility)
+	}
+}
+
+// filterChannels returns the channels matching the passed visibility.
+func filterChannels(channels []*lnrpc.Channel,
+	visibility string) []*lnrpc.Channel {
+
+	if visibility == visibilityAll {
+		return channels
+	}
+
+	filtered := make([]*lnrpc.Channel, 0, len(channels))
+	for _, channel := range channels {
+		if channel.Private == (visibility == visibilityPrivate) {
+			filtered = append(filtered, channel)
+		}
+	}
+
+	return filtered
+}
+
+const (
+	// sortCapacity sorts channels by capacity.
+	sortCapacity = "capacity"
+
+	// sortBalance sorts channels by the hub's balance.
+	sortBalance = "balance"
+
+	// sortUptime sorts channels by uptime.
+	sortUptime = "uptime"
+
+	// sortLocalRatio sorts channels by the ratio of their capacity held
+	// by the hub.
+	sortLocalRatio = "local_ratio"
+
+	// groupPeer groups channels by remote peer.
+	groupPeer = "peer"
+)
+
+// channelQuery filters and sorts a channel list.
+type channelQuery struct {
+	sortBy      string
+	descending  bool
+	active      *bool
+	minCapacity int64
+	peer        string
+	groupBy     string
+}
+
+// parseChannelQuery parses the channel query params:
+//
+//	sort         capacity, balance or local_ratio
+//	order        asc or desc (requires sort, defaults to asc)
+//	active       true, false or all (defaults to all)
+//	min_capacity minimum capacity in atoms
+//	peer         pubkey of the remote node
+//	group        peer to group the channels by remote node
+func parseChannelQuery(values url.Values) (*channelQuery, error) {
+	q := &channelQuery{}
+
+	switch sortBy := values.Get("sort"); sortBy {
+	case "", sortCapacity, sortBalance, sortLocalRatio:
+		q.sortBy = sortBy
+	case sortUptime:
+		return nil, fmt.Errorf("sort: uptime isn't reported by dcrlnd")
+	default:
+		return nil, fmt.Errorf("sort: invalid value %q, must be one "+
+			"of capacity, balance or local_ratio", sortBy)
+	}
+
+	switch order := values.Get("order"); order {
+	case "":
+	case "asc", "desc":
+		if q.sortBy == "" {
+			return nil, fmt.Errorf("order: requires sort to be set")
+		}
+		q.descending = order == "desc"
+	default:
+		return nil, fmt.Errorf("order: invalid value %q, must be "+
+			"asc or desc", order)
+	}
+
+	switch active := values.Get("active"); active {
+	case "", "all":
+	case "true", "false":
+		isActive := active == "true"
+		q.active = &isActive
+	default:
+		return nil, fmt.Errorf("active: invalid value %q, must be "+
+			"true, false or all", active)
+	}
+
+	if v := values.Get("min_capacity"); v != "" {
+		minCapacity, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || minCapacity < 0 {
+			return nil, fmt.Errorf("min_capacity: invalid amount "+
+				"%q, must be a positive number of atoms", v)
+		}
+		q.minCapacity = minCapacity
+	}
+
+	if peer := values.Get("peer"); peer != "" {
+		if _, err := hex.DecodeString(peer); err != nil ||
+			len(peer) != 66 {
+
+			return nil, fmt.Errorf("peer: invalid pubkey %q", peer)
+		}
+		q.peer = peer
+	}
+
+	switch groupBy := values.Get("group"); groupBy {
+	case "", groupPeer:
+		q.groupBy = groupBy
+	default:
+		return nil, fmt.Errorf("group: invalid value %q, must be "+
+			"peer", groupBy)
+	}
+
+	return q, nil
+}
+
+// localRatio returns the ratio of the channel's capacity held by the hub.
+func localRatio(channel *lnrpc.Channel) float64 {
+	if channel.Capacity == 0 {
+		return 0
+	}
+
+	return float64(channel.LocalBalance) / float64(channel.Capacity)
+}
+
+// apply returns the channels matching the query, sorted as requested, or by
+// channel point when no sort is requested. The passed slice isn't modified.
+func (q *channelQuery) apply(channels []*lnrpc.Channel) []*lnrpc.Channel {
+	matching := make([]*lnrpc.Channel, 0, len(channels))
+	for _, channel := range channels {
+		switch {
+		case q.active != nil && channel.Active != *q.active:
+			continue
+		case channel.Capacity < q.minCapacity:
+			continue
+		case q.peer != "" && channel.RemotePubkey != q.peer:
+			continue
+		}
+		matching = append(matching, channel)
+	}
+
+	// Always start from the same order, so channels comparing equal on
+	// the requested sort keep a stable order across requests.
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].ChannelPoint < matching[j].ChannelPoint
+	})
+
+	var less func(a, b *lnrpc.Channel) bool
+	switch q.sortBy {
+	case sortCapacity:
+		less = func(a, b *lnrpc.Channel) bool {
+			return a.Capacity < b.Capacity
+		}
+	case sortBalance:
+		less = func(a, b *lnrpc.Channel) bool {
+			return a.LocalBalance < b.LocalBalance
+		}
+	case sortLocalRatio:
+		less = func(a, b *lnrpc.Channel) bool {
+			return localRatio(a) < localRatio(b)
+		}
+	default:
+		return matching
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if q.descending {
+			return less(matching[j], matching[i])
+		}
+		return less(matching[i], matching[j])
+	})
+
+	return matching
+}
+
+// channelVisibility returns the visibility of the channels listed to the
+// visitor: all of them for admins and only the public ones for everyone else.
+// Like on the home page, the channels are only listed to everyone when the hub
+// isn't configured to keep them behind the admin auth, otherwise false is
+// returned for visitors that aren't admins.
+func (h *lightningHub) channelVisibility(r *http.Request) (string, bool) {
+	switch {
+	case h.isAdmin(r):
+		return visibilityAll, true
+	case h.cfg.PublicAggregateOnly:
+		return "", false
+	default:
+		return visibilityPublic, true
+	}
+}
+
+// channelRow is a channel listed on the channels page.
+type channelRow struct {
+	*lnrpc.Channel
+
+	// Policy is the hub's fee policy on the channel, nil if it's unknown.
+	Policy *lnrpc.ChannelFeeReport
+}
+
+// peerGroup is the channels to a single peer, listed together on the
+// channels page when grouping by peer.
+type peerGroup struct {
+	RemotePubkey  string
+	Capacity      int64
+	LocalBalance  int64
+	RemoteBalance int64
+
+	// Channels are the channels to the peer, in the order of the list
+	// they were grouped from.
+	Channels []channelRow
+}
+
+// groupByPeer groups the passed channels by remote peer, summing their
+// capacity and balances. The groups are ordered by their first channel in
+// the passed list, so they follow the requested sort.
+func (h *lightningHub) groupByPeer(rows []channelRow) []*peerGroup {
+	var groups []*peerGroup
+	byPubkey := make(map[string]*peerGroup)
+	for _, row := range rows {
+		group, ok := byPubkey[row.RemotePubkey]
+		if !ok {
+			group = &peerGroup{RemotePubkey: row.RemotePubkey}
+			byPubkey[row.RemotePubkey] = group
+			groups = append(groups, group)
+		}
+
+		group.Capacity += row.Capacity
+		group.LocalBalance += row.LocalBalance
+		group.RemoteBalance += row.RemoteBalance
+		group.Channels = append(group.Channels, row)
+	}
+
+	return groups
+}
+
+// channelsPage is the context used to render the channels page.
+type channelsPage struct {
+	*baseContext
+
+	// Channels are the channels the visitor is allowed to see, filtered
+	// and sorted according to the query.
+	Channels []channelRow
+
+	// Groups are the same channels grouped by peer, only set when
+	// grouping by peer is requested.
+	Groups []*peerGroup
+
+	// Sort is the field the channels are sorted by, if any.
+	Sort string
+
+	// Group is what the channels are grouped by, if any.
+	Group string
+}
+
+// Channels renders the details of every channel the visitor is allowed to
+// see, filtered and sorted according to the query params (see
+// parseChannelQuery). It's served from the cached context, along with the
+// hub's fee policy of each channel which is cached separately, so it doesn't
+// add much load on dcrlnd.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Channels(w http.ResponseWriter, r *http.Request) {
+	channelsTemplate := h.lookupTemplate("channels.html")
+	if channelsTemplate == nil {
+		log.Error("unable to lookup channels")
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	visibility, ok := h.channelVisibility(r)
+	if !ok {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	query, err := parseChannelQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		h.renderUnavailable(w)
+		return
+	}
+
+	channels := filterChannels(homeInfo.ActiveChannels, visibility)
+	channels = query.apply(channels)
+
+	fees := h.channelFees()
+	rows := make([]channelRow, 0, len(channels))
+	for _, channel := range channels {
+		rows = append(rows, channelRow{
+			Channel: channel,
+			Policy:  fees[channel.ChannelPoint],
+		})
+	}
+
+	page := &channelsPage{
+		baseContext: h.baseContext("Channels"),
+		Channels:    rows,
+		Sort:        query.sortBy,
+		Group:       query.groupBy,
+	}
+	if query.groupBy == groupPeer {
+		page.Groups = h.groupByPeer(rows)
+	}
+
+	channelsTemplate.Execute(w, page)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestGroupByPeer checks that the channels to the same peer collapse into a
+// single group with summed totals, the groups following the channels' order.
+func TestGroupByPeer(t *testing.T) {
+	rows := []channelRow{{
+		Channel: &lnrpc.Channel{
+			RemotePubkey:  "02bb",
+			ChanId:        1,
+			Capacity:      100000,
+			LocalBalance:  60000,
+			RemoteBalance: 40000,
+		},
+	}, {
+		Channel: &lnrpc.Channel{
+			RemotePubkey:  "02cc",
+			ChanId:        2,
+			Capacity:      50000,
+			LocalBalance:  50000,
+			RemoteBalance: 0,
+		},
+	}, {
+		Channel: &lnrpc.Channel{
+			RemotePubkey:  "02bb",
+			ChanId:        3,
+			Capacity:      200000,
+			LocalBalance:  20000,
+			RemoteBalance: 180000,
+		},
+	}}
+
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	groups := hub.groupByPeer(rows)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+
+	group := groups[0]
+	if group.RemotePubkey != "02bb" {
+		t.Fatalf("got first group %v, want 02bb", group.RemotePubkey)
+	}
+	if group.Capacity != 300000 || group.LocalBalance != 80000 ||
+		group.RemoteBalance != 220000 {
+
+		t.Fatalf("got capacity %d, local balance %d and remote "+
+			"balance %d, want 300000, 80000 and 220000",
+			group.Capacity, group.LocalBalance, group.RemoteBalance)
+	}
+	if len(group.Channels) != 2 || group.Channels[0].ChanId != 1 ||
+		group.Channels[1].ChanId != 3 {
+
+		t.Fatalf("unexpected channels in group: %+v", group.Channels)
+	}
+
+	if groups[1].RemotePubkey != "02cc" || len(groups[1].Channels) != 1 {
+		t.Fatalf("unexpected second group: %+v", groups[1])
+	}
+}
+
+// TestChannelsSort checks that the channels page lists the cached channels
+// in the order requested by the sort param.
+func TestChannelsSort(t *testing.T) {
+	lnd := &fakeLnd{info: testnetInfo()}
+	hub := newTestHub(t, newTestConfig(), lnd)
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		ActiveChannels: []*lnrpc.Channel{{
+			RemotePubkey:  "02aaaa",
+			ChannelPoint:  "aa:0",
+			Active:        true,
+			Capacity:      300000,
+			LocalBalance:  100000,
+			RemoteBalance: 200000,
+		}, {
+			RemotePubkey:  "02bbbb",
+			ChannelPoint:  "bb:0",
+			Active:        true,
+			Capacity:      100000,
+			LocalBalance:  90000,
+			RemoteBalance: 10000,
+		}, {
+			RemotePubkey:  "02cccc",
+			ChannelPoint:  "cc:0",
+			Capacity:      200000,
+			LocalBalance:  50000,
+			RemoteBalance: 150000,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantOrder []string
+	}{{
+		name:      "channel point",
+		query:     "",
+		wantCode:  http.StatusOK,
+		wantOrder: []string{"02aaaa", "02bbbb", "02cccc"},
+	}, {
+		name:      "capacity",
+		query:     "?sort=capacity",
+		wantCode:  http.StatusOK,
+		wantOrder: []string{"02bbbb", "02cccc", "02aaaa"},
+	}, {
+		name:      "capacity descending",
+		query:     "?sort=capacity&order=desc",
+		wantCode:  http.StatusOK,
+		wantOrder: []string{"02aaaa", "02cccc", "02bbbb"},
+	}, {
+		name:      "balance",
+		query:     "?sort=balance",
+		wantCode:  http.StatusOK,
+		wantOrder: []string{"02cccc", "02bbbb", "02aaaa"},
+	}, {
+		name:      "local ratio descending",
+		query:     "?sort=local_ratio&order=desc",
+		wantCode:  http.StatusOK,
+		wantOrder: []string{"02bbbb", "02aaaa", "02cccc"},
+	}, {
+		name:     "uptime",
+		query:    "?sort=uptime",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "invalid",
+		query:    "?sort=name",
+		wantCode: http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/channels"+test.query, nil)
+		w := httptest.NewRecorder()
+		hub.Channels(w, r)
+
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+			continue
+		}
+		if test.wantOrder == nil {
+			continue
+		}
+
+		body := w.Body.String()
+		last := -1
+		for _, pubkey := range test.wantOrder {
+			i := strings.Index(body, pubkey)
+			switch {
+			case i < 0:
+				t.Errorf("%s: %s not listed", test.name, pubkey)
+			case i < last:
+				t.Errorf("%s: got %s out of order", test.name,
+					pubkey)
+			}
+			last = i
+		}
+	}
+}
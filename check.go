@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// checkNode checks that the passed dcrlnd node can be reached, is on the
+// configured network and runs a supported version. Only GetInfo is called,
+// so unlike fetching the home page, which creates the donation invoice and
+// address, checking a node leaves it untouched.
+func checkNode(lnd lndClient, cfg *config) (*lnrpc.GetInfoResponse, error) {
+	infoReq := &lnrpc.GetInfoRequest{}
+	var nodeInfo *lnrpc.GetInfoResponse
+	err := retryRPC(cfg, "GetInfo", func() (err error) {
+		ctx, cancel := cfg.rpcContext()
+		defer cancel()
+		nodeInfo, err = lnd.GetInfo(ctx, infoReq)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rpc GetInfo() failed: %w", err)
+	}
+
+	if err := checkNodeNetwork(nodeInfo, cfg); err != nil {
+		return nil, err
+	}
+
+	err = checkDcrlndVersion(nodeInfo.Version, cfg)
+	switch {
+	case err != nil && cfg.StrictVersion:
+		return nil, err
+	case err != nil:
+		log.Warnf("%v, some features may not work", err)
+	}
+
+	return nodeInfo, nil
+}
+
+// dialAndCheckNode connects to the dcrlnd node of the passed config, checks
+// it and closes the connection.
+func dialAndCheckNode(cfg *config) (*lnrpc.GetInfoResponse, error) {
+	conn, err := dialLnd(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return checkNode(lnrpc.NewLightningClient(conn), cfg)
+}
+
+// runCheck checks the hub's node and the additional ones, as done by the
+// --check flag.
+func runCheck(cfg *config) error {
+	nodeInfo, err := dialAndCheckNode(cfg)
+	if err != nil {
+		return err
+	}
+	log.Infof("Check passed: reached node %s on %s",
+		nodeInfo.IdentityPubkey, cfg.Network)
+
+	for _, node := range cfg.nodes {
+		nodeInfo, err := dialAndCheckNode(nodeDialConfig(cfg, node))
+		if err != nil {
+			return fmt.Errorf("node %v: %v", node.Name, err)
+		}
+		log.Infof("Check passed: reached node %v (%s)", node.Name,
+			nodeInfo.IdentityPubkey)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestCheckNode checks that the check mode only succeeds with a node on the
+// configured network, and never changes the node's state.
+func TestCheckNode(t *testing.T) {
+	mainnetInfo := testnetInfo()
+	mainnetInfo.Chains[0].Network = "mainnet"
+
+	tests := []struct {
+		name    string
+		lnd     *fakeLnd
+		wantErr bool
+	}{{
+		name: "reachable",
+		lnd:  &fakeLnd{info: testnetInfo()},
+	}, {
+		name:    "wrong network",
+		lnd:     &fakeLnd{info: mainnetInfo},
+		wantErr: true,
+	}, {
+		name: "unreachable",
+		lnd: &fakeLnd{
+			errs: map[string][]error{
+				"GetInfo": {status.Error(
+					codes.Unavailable, "connection refused",
+				)},
+			},
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		nodeInfo, err := checkNode(test.lnd, newTestConfig())
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if err == nil && nodeInfo.IdentityPubkey != "02aa" {
+			t.Errorf("%s: got node %q, want 02aa", test.name,
+				nodeInfo.IdentityPubkey)
+		}
+
+		// Only GetInfo may be called.
+		test.lnd.mtx.Lock()
+		for name, n := range test.lnd.calls {
+			if name != "GetInfo" {
+				t.Errorf("%s: unexpected %d %v calls",
+					test.name, n, name)
+			}
+		}
+		test.lnd.mtx.Unlock()
+	}
+}
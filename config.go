@@ -1,23 +1,75 @@
 package main
 
 import (
+	"crypto/x509"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/jessevdk/go-flags"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	defaultConfigFilename = "dcrlnhub.conf"
-	defaultLogLevel       = "info"
-	defaultLogFilename    = "dcrlnhub.log"
-	defaultBindAddr       = ":80"
-	defaultUseLeHTTPS     = false
+	defaultConfigFilename   = "dcrlnhub.conf"
+	defaultLogLevel         = "info"
+	defaultLogFilename      = "dcrlnhub.log"
+	defaultLogFormat        = logFormatText
+	defaultMacaroonFilename = "admin.macaroon"
+	defaultBindAddr         = ":80"
+	defaultHTTPSBindAddr    = ":443"
+	defaultUseLeHTTPS       = false
 
 	defaultDcrlndRPCHost = "127.0.0.1:10009"
+
+	defaultDonationAmountPolicy = donationPolicyCap
+	defaultDonationAmount       = 100000
+	defaultMinDonationAmount    = 1000
+	defaultMaxDonationAmount    = 100000000
+	defaultDonationExpiry       = time.Hour
+	defaultMaxInactiveRatio     = 0.5
+	defaultSelfTestAmount       = 1000
+	defaultMinChannelSize       = 20000
+	defaultMaxChannelSize       = 100000000
+	defaultWalletReserve        = 100000
+	defaultRateLimit            = 5
+	defaultMaxWSClients         = 100
+	defaultRequestTimeout       = 30 * time.Second
+	defaultReachabilityInterval = 10 * time.Minute
+	defaultGraphInterval        = 10 * time.Minute
+	defaultFeeBumpTargetConf    = 2
+	defaultRefreshInterval      = 30 * time.Second
+	defaultHistorySize          = 2880
+	defaultBadgeColor           = "#2970ff"
+	defaultThemeColor           = "#2970ff"
+	defaultStaticDir            = "static"
+	defaultMaxStaleness         = time.Minute
+	defaultStaleRefreshTimeout  = 5 * time.Second
+	defaultSiteName             = "dcrlnhub"
+	defaultShutdownTimeout      = 10 * time.Second
+	defaultHTTPReadTimeout      = 30 * time.Second
+	defaultHTTPWriteTimeout     = 60 * time.Second
+	defaultHTTPIdleTimeout      = 2 * time.Minute
+	defaultMaxHeaderBytes       = 1 << 20
+	defaultStartupAttempts      = 5
+	defaultStartupTimeout       = 2 * time.Minute
+	defaultRPCTimeout           = 10 * time.Second
+	defaultMinDcrlndVersion     = "0.2.0"
+	defaultRPCRetries           = 2
+	defaultRPCRetryBackoff      = 250 * time.Millisecond
+	defaultTagline              = "The hub of <em>All</em> ln channels!"
+	defaultHSTSMaxAge           = 365 * 24 * time.Hour
+
+	// defaultContentSecurityPolicy only allows the hub's own resources.
+	// Inline styles are allowed for the node's color and the widget, and
+	// data: images for the QR codes rendered inline by browsers.
+	defaultContentSecurityPolicy = "default-src 'self'; " +
+		"img-src 'self' data:; style-src 'self' 'unsafe-inline'; " +
+		"frame-ancestors 'self'"
 )
 
 var (
@@ -25,43 +77,235 @@ var (
 	defaultConfigFile = filepath.Join(
 		defaultDataDir, defaultConfigFilename,
 	)
-	defaultLogPath = filepath.Join(
-		defaultDataDir, "logs", "decred", "testnet",
-		defaultLogFilename,
-	)
-	defaultDcrlndDir         = dcrutil.AppDataDir("dcrlnd", false)
-	defaultDcrlndTLSCertPath = filepath.Join(
-		defaultDcrlndDir, "tls.cert",
-	)
-	defaultDcrlndMacaroonPath = filepath.Join(
-		defaultDcrlndDir, "data",
-		"chain", "decred",
-		"testnet", "admin.macaroon",
-	)
+	defaultDcrlndDir = dcrutil.AppDataDir("dcrlnd", false)
 )
 
+// networkPaths are the default paths of the files used on a network.
+type networkPaths struct {
+	// TLSCertPath is the path of dcrlnd's TLS certificate, which dcrlnd
+	// shares across networks.
+	TLSCertPath string
+
+	// MacaroonPath is the path of dcrlnd's admin macaroon, kept in a
+	// directory per network.
+	MacaroonPath string
+
+	// LogPath is the path of the hub's log file.
+	LogPath string
+}
+
+// defaultPathsForNetwork returns the default paths of the files used on the
+// passed network, following the layout of dcrlnd's and the hub's data
+// directories.
+func defaultPathsForNetwork(network string) networkPaths {
+	return networkPaths{
+		TLSCertPath: filepath.Join(defaultDcrlndDir, "tls.cert"),
+		MacaroonPath: filepath.Join(
+			defaultDcrlndDir, "data", "chain", "decred", network,
+			defaultMacaroonFilename,
+		),
+		LogPath: filepath.Join(
+			defaultDataDir, "logs", "decred", network,
+			defaultLogFilename,
+		),
+	}
+}
+
 type config struct {
-	ConfigFile   string `short:"C" long:"configfile" description:"path to config file (default:.dcrlnhub/dcrlnhub.conf)"`
-	BindAddr     string `long:"bind_addr" description:"port to listen for http"`
-	RPCHost      string `long:"rpchost" description:"dcrlnd's rpc listening address."`
-	TLSCertPath  string `long:"certpath" description:"TLS certificate path for dcrlnd's RPC and REST services"`
-	MacaroonPath string `long:"macpath" decription:"path to macaroon file to authenticate services"`
-	UseLeHTTPS   bool   `long:"use_le_https" description:"use https via lets encrypt"`
-	Domain       string `long:"domain" description:"the domain of the hub, required for TLS"`
+	ConfigFile    string        `short:"C" long:"configfile" env:"DCRLNHUB_CONFIGFILE" description:"path to config file (default:.dcrlnhub/dcrlnhub.conf)"`
+	BindAddr      string        `long:"bind_addr" env:"DCRLNHUB_BIND_ADDR" description:"port to listen for http"`
+	RPCHost       string        `long:"rpchost" env:"DCRLNHUB_RPCHOST" description:"dcrlnd's rpc listening address, as host:port"`
+	TLSCertPath   string        `long:"certpath" env:"DCRLNHUB_CERTPATH" description:"TLS certificate path for dcrlnd's RPC and REST services (default: tls.cert in dcrlnd's data directory)"`
+	TLSCertPEM    string        `long:"tlscert_pem" env:"DCRLNHUB_TLSCERT_PEM" description:"PEM encoded TLS certificate of dcrlnd, used instead of the certificate file; newlines may be escaped as \\n"`
+	MacaroonHex   string        `long:"macaroon_hex" env:"DCRLNHUB_MACAROON_HEX" description:"hex encoded macaroon to authenticate services, used instead of the macaroon file"`
+	MacaroonPath  string        `long:"macpath" env:"DCRLNHUB_MACPATH" description:"path to macaroon file to authenticate services (default: dcrlnd's admin macaroon for the selected network)"`
+	SOCKSProxy    string        `long:"socksproxy" env:"DCRLNHUB_SOCKSPROXY" description:"SOCKS5 proxy (host:port) used to connect to dcrlnd, e.g. to reach it over Tor"`
+	DialTimeout   time.Duration `long:"dial_timeout" env:"DCRLNHUB_DIAL_TIMEOUT" description:"wait up to this long for the connection to dcrlnd to be established, reporting failures immediately instead of on the first request (0 to not wait)"`
+	UseLeHTTPS    bool          `long:"use_le_https" env:"DCRLNHUB_USE_LE_HTTPS" description:"use https via lets encrypt"`
+	HTTPSBindAddr string        `long:"https_bind_addr" env:"DCRLNHUB_HTTPS_BIND_ADDR" description:"address to listen for https when using lets encrypt, bind_addr then serves the ACME challenges and redirects to https"`
+	Domain        string        `long:"domain" env:"DCRLNHUB_DOMAIN" description:"the domain of the hub, required for TLS; a comma-separated list to serve the hub on several domains, the first one being used in absolute URLs"`
+	StartDegraded bool          `long:"start_degraded" env:"DCRLNHUB_START_DEGRADED" description:"keep running and serve a node unavailable page if dcrlnd can't be reached at startup, retrying in the background"`
+
+	// Nodes are the additional dcrlnd nodes whose stats are aggregated
+	// with the hub's node, set in the [node] section of the config file.
+	Nodes nodesConfig `group:"node" namespace:"node"`
+
+	StartupAttempts int           `long:"startup_attempts" env:"DCRLNHUB_STARTUP_ATTEMPTS" description:"number of attempts to reach dcrlnd at startup, with an exponential backoff between them, before giving up"`
+	StartupTimeout  time.Duration `long:"startup_timeout" env:"DCRLNHUB_STARTUP_TIMEOUT" description:"maximum time to keep trying to reach dcrlnd at startup (0 for no limit)"`
+
+	RPCTimeout      time.Duration `long:"rpc_timeout" env:"DCRLNHUB_RPC_TIMEOUT" description:"maximum time to wait for dcrlnd to answer an RPC (0 for no limit)"`
+	RPCRetries      int           `long:"rpc_retries" env:"DCRLNHUB_RPC_RETRIES" description:"number of times the RPCs fetching the home page are retried on transient errors, with an exponential backoff between them (0 to disable)"`
+	RPCRetryBackoff time.Duration `long:"rpc_retry_backoff" env:"DCRLNHUB_RPC_RETRY_BACKOFF" description:"time to wait before the first retry of a failed RPC, doubled after every retry"`
+
+	MinDcrlndVersion string `long:"min_dcrlnd_version" env:"DCRLNHUB_MIN_DCRLND_VERSION" description:"minimum version of dcrlnd the hub supports, as major.minor.patch; older versions are warned about"`
+	StrictVersion    bool   `long:"strict_version" env:"DCRLNHUB_STRICT_VERSION" description:"refuse to start, or to leave degraded mode, if dcrlnd is older than min_dcrlnd_version, instead of only warning"`
+
+	Check bool `long:"check" env:"DCRLNHUB_CHECK" description:"check the config and that dcrlnd can be reached on the configured network, then exit without serving anything"`
+
+	ShutdownTimeout time.Duration `long:"shutdown_timeout" env:"DCRLNHUB_SHUTDOWN_TIMEOUT" description:"maximum time to wait for in-flight requests to finish when shutting down"`
+
+	HTTPReadTimeout  time.Duration `long:"http_read_timeout" env:"DCRLNHUB_HTTP_READ_TIMEOUT" description:"maximum time to read a request, headers and body, on the plain http server (0 for no limit)"`
+	HTTPWriteTimeout time.Duration `long:"http_write_timeout" env:"DCRLNHUB_HTTP_WRITE_TIMEOUT" description:"maximum time to write a response on the plain http server, should be longer than request_timeout (0 for no limit)"`
+	HTTPIdleTimeout  time.Duration `long:"http_idle_timeout" env:"DCRLNHUB_HTTP_IDLE_TIMEOUT" description:"maximum time to keep an idle keep-alive connection open on the plain http server (0 to use http_read_timeout)"`
+	MaxHeaderBytes   int           `long:"max_header_bytes" env:"DCRLNHUB_MAX_HEADER_BYTES" description:"maximum size in bytes of the request headers on the plain http server"`
+
+	RefreshInterval     time.Duration `long:"refresh_interval" env:"DCRLNHUB_REFRESH_INTERVAL" description:"how often the data shown by the hub is refreshed from dcrlnd in the background"`
+	MaxStaleness        time.Duration `long:"max_staleness" env:"DCRLNHUB_MAX_STALENESS" description:"maximum age of the data served, older data is refreshed from dcrlnd before responding"`
+	StaleRefreshTimeout time.Duration `long:"stale_refresh_timeout" env:"DCRLNHUB_STALE_REFRESH_TIMEOUT" description:"maximum time to wait for stale data to be refreshed before serving it anyway"`
+
+	RequestTimeout time.Duration            `long:"request_timeout" env:"DCRLNHUB_REQUEST_TIMEOUT" description:"maximum time to respond to a request, 0 to disable"`
+	RouteTimeouts  map[string]time.Duration `long:"route_timeout" env:"DCRLNHUB_ROUTE_TIMEOUT" env-delim:"," description:"maximum time to respond to the requests of a route overriding request_timeout, as route:duration (e.g. healthz:2s), may be specified multiple times"`
+
+	MaxWSClients int `long:"max_ws_clients" env:"DCRLNHUB_MAX_WS_CLIENTS" description:"maximum number of concurrent clients of the live stats WebSocket"`
+
+	AllowedOrigins []string `long:"allowed_origin" env:"DCRLNHUB_ALLOWED_ORIGIN" env-delim:"," description:"origin allowed to call the JSON API from a browser, e.g. https://example.com, or * for any, may be specified multiple times (default: none)"`
+	TrustedProxies []string `long:"trusted_proxy" env:"DCRLNHUB_TRUSTED_PROXY" env-delim:"," description:"IP address or CIDR of a reverse proxy whose X-Forwarded-* headers are trusted, may be specified multiple times"`
+	ValidateHost   bool     `long:"validate_host" env:"DCRLNHUB_VALIDATE_HOST" description:"reject requests for pages with absolute URLs whose host doesn't match the configured domain"`
+	ForceScheme    string   `long:"force_scheme" env:"DCRLNHUB_FORCE_SCHEME" description:"scheme used to build absolute URLs regardless of how the request was received" choice:"http" choice:"https"`
+
+	AdminClientCA       string `long:"admin_client_ca" env:"DCRLNHUB_ADMIN_CLIENT_CA" description:"path to a PEM encoded CA certificate, requiring the admin endpoints to be accessed with a client certificate signed by it, in addition to the admin token (requires use_le_https)"`
+	AdminToken          string `long:"admin_token" env:"DCRLNHUB_ADMIN_TOKEN" description:"shared secret granting access to the operator views when sent as an \"Authorization: Bearer\" header"`
+	PublicAggregateOnly bool   `long:"public_aggregate_only" env:"DCRLNHUB_PUBLIC_AGGREGATE_ONLY" description:"only show aggregate stats publicly, requiring the admin token to see the channel list"`
+
+	BasicAuthUser string `long:"basic_auth_user" env:"DCRLNHUB_BASIC_AUTH_USER" description:"user name required to access the whole site with HTTP basic auth, keeping it private (requires basic_auth_pass)"`
+	BasicAuthPass string `long:"basic_auth_pass" env:"DCRLNHUB_BASIC_AUTH_PASS" description:"bcrypt hash of the password required with basic_auth_user, e.g. as generated by htpasswd -nB"`
+
+	// FeaturedPeers are the peers recommended to visitors on the home
+	// page, set in the [featured_peer] section of the config file.
+	FeaturedPeers featuredPeersConfig `group:"featured_peer" namespace:"featured_peer"`
+
+	SiteName string `long:"site_name" env:"DCRLNHUB_SITE_NAME" description:"name of the hub shown on every page"`
+	Tagline  string `long:"tagline" env:"DCRLNHUB_TAGLINE" description:"tagline shown below the hub's name on every page, may contain HTML"`
+
+	AllowIndexing   bool     `long:"allow_indexing" env:"DCRLNHUB_ALLOW_INDEXING" description:"allow search engines to index the public pages"`
+	NoIndexPrefixes []string `long:"noindex_prefix" env:"DCRLNHUB_NOINDEX_PREFIX" env-delim:"," description:"path prefix of the pages never indexed by search engines, may be specified multiple times (default: /api/, /admin/ and /status)"`
+
+	SanitizeTags  []string `long:"sanitize_tag" env:"DCRLNHUB_SANITIZE_TAG" env-delim:"," description:"HTML tag allowed in operator-provided content, may be specified multiple times (default: a common set of formatting tags)"`
+	SanitizeAttrs []string `long:"sanitize_attr" env:"DCRLNHUB_SANITIZE_ATTR" env-delim:"," description:"HTML attribute allowed on the allowed tags in operator-provided content, may be specified multiple times (default: href and title)"`
+
+	EnableMainnetWrites  bool   `long:"enable_mainnet_writes" env:"DCRLNHUB_ENABLE_MAINNET_WRITES" description:"allow the features spending funds or changing the node's state (channel opens, fee bumps...) on mainnet"`
+	ReadOnlyMacaroonPath string `long:"readonly_macpath" env:"DCRLNHUB_READONLY_MACPATH" description:"path to a read-only macaroon used instead of the configured macaroon when writes are disabled, which also disables donations since they create invoices and addresses"`
+
+	EnableFeeBump     bool          `long:"enable_fee_bump" env:"DCRLNHUB_ENABLE_FEE_BUMP" description:"allow admins to bump the fee of stuck channel funding transactions (requires a macaroon with onchain write permission)"`
+	FeeBumpAfter      time.Duration `long:"fee_bump_after" env:"DCRLNHUB_FEE_BUMP_AFTER" description:"automatically bump the fee of the funding transactions of the node's channels still unconfirmed after this long, requires enable_fee_bump (0 to disable)"`
+	FeeBumpTargetConf uint32        `long:"fee_bump_target_conf" env:"DCRLNHUB_FEE_BUMP_TARGET_CONF" description:"confirmation target in blocks of the automatic fee bumps"`
+
+	MaxInactiveRatio float64 `long:"max_inactive_ratio" env:"DCRLNHUB_MAX_INACTIVE_RATIO" description:"maximum ratio of inactive to total channels before the hub reports itself as degraded"`
+
+	SelfTestAmount int64 `long:"selftest_amount" env:"DCRLNHUB_SELFTEST_AMOUNT" description:"default amount in atoms of the admin routing test"`
+
+	MinChannelSize       int64 `long:"min_channel_size" env:"DCRLNHUB_MIN_CHANNEL_SIZE" description:"minimum size in atoms of the channels visitors can request"`
+	MaxChannelSize       int64 `long:"max_channel_size" env:"DCRLNHUB_MAX_CHANNEL_SIZE" description:"maximum size in atoms of the channels visitors can request"`
+	WalletReserve        int64 `long:"wallet_reserve" env:"DCRLNHUB_WALLET_RESERVE" description:"confirmed on-chain balance in atoms kept aside for fees, which the channels visitors request can't use"`
+	SuggestedChannelSize int64 `long:"suggested_channel_size" env:"DCRLNHUB_SUGGESTED_CHANNEL_SIZE" description:"channel size in atoms suggested to visitors, between min_channel_size and max_channel_size (default: the median size of the node's channels)"`
+	RateLimit            int   `long:"rate_limit" env:"DCRLNHUB_RATE_LIMIT" description:"maximum number of channel open and peer connection requests per minute from a single IP address (0 to disable)"`
+
+	ReachabilityInterval time.Duration `long:"reachability_interval" env:"DCRLNHUB_REACHABILITY_INTERVAL" description:"how often to check that each of the node's advertised addresses accepts connections (0 to disable)"`
+	GraphInterval        time.Duration `long:"graph_interval" env:"DCRLNHUB_GRAPH_INTERVAL" description:"how often the node's channels and capacity in the public graph are fetched (0 to disable)"`
+	TorProxy             string        `long:"torproxy" env:"DCRLNHUB_TORPROXY" description:"SOCKS5 proxy (host:port) used to check the node's onion addresses"`
+
+	StaticDir string `long:"staticdir" env:"DCRLNHUB_STATICDIR" description:"directory of the HTML templates and static files, relative to the working directory unless absolute"`
+
+	HSTSMaxAge            time.Duration `long:"hsts_max_age" env:"DCRLNHUB_HSTS_MAX_AGE" description:"how long browsers must only reach the hub over https, sent with use_le_https (0 to disable)"`
+	ContentSecurityPolicy string        `long:"content_security_policy" env:"DCRLNHUB_CONTENT_SECURITY_POLICY" description:"Content-Security-Policy header of the responses (empty to disable)"`
+
+	BadgeColor string `long:"badge_color" env:"DCRLNHUB_BADGE_COLOR" description:"color of the value part of the embeddable badges, in the #rrggbb format"`
+	ThemeColor string `long:"theme_color" env:"DCRLNHUB_THEME_COLOR" description:"color of the hub's icon and of the browser UI when installed as a web app, in the #rrggbb format"`
+
+	DevMode bool `long:"devmode" env:"DCRLNHUB_DEVMODE" description:"reload the templates on every request so they can be edited without restarting, for development only"`
+
+	EnableMetrics bool `long:"enable_metrics" env:"DCRLNHUB_ENABLE_METRICS" description:"serve Prometheus metrics at /metrics"`
+
+	HistorySize int    `long:"history_size" env:"DCRLNHUB_HISTORY_SIZE" description:"number of points of channel and capacity history kept, one being recorded on every refresh"`
+	HistoryFile string `long:"history_file" env:"DCRLNHUB_HISTORY_FILE" description:"path of the file persisting the channel and capacity history across restarts (default: kept in memory only)"`
+
+	LogFormat  string `long:"log_format" env:"DCRLNHUB_LOG_FORMAT" description:"format of the log lines: text or json" choice:"text" choice:"json"`
+	DebugLevel string `long:"debuglevel" env:"DCRLNHUB_DEBUGLEVEL" description:"logging level: trace, debug, info, warn, error, critical or off, reloaded from the config file on SIGHUP"`
+
+	NoDataDir bool `long:"no_datadir" env:"DCRLNHUB_NO_DATADIR" description:"don't create the data directory, logging only to stderr and keeping no state on disk"`
+
+	DonationAmount       int64         `long:"donation_amount" env:"DCRLNHUB_DONATION_AMOUNT" description:"amount in atoms of the donation invoice shown on the home page"`
+	MinDonationAmount    int64         `long:"min_donation_amount" env:"DCRLNHUB_MIN_DONATION_AMOUNT" description:"minimum amount in atoms of the donations donors can choose"`
+	MaxDonationAmount    int64         `long:"max_donation_amount" env:"DCRLNHUB_MAX_DONATION_AMOUNT" description:"maximum amount in atoms of the donations donors can choose"`
+	DonationExpiry       time.Duration `long:"donation_expiry" env:"DCRLNHUB_DONATION_EXPIRY" description:"how long the donation invoice shown on the home page is valid, a new one is created on every refresh"`
+	StaticDonationAddr   string        `long:"static_donation_addr" env:"DCRLNHUB_STATIC_DONATION_ADDR" description:"fixed on-chain donation address (default: a new address of the node's wallet on every refresh)"`
+	DonationAmountPolicy string        `long:"donation_amount_policy" env:"DCRLNHUB_DONATION_AMOUNT_POLICY" description:"how to handle donation amounts above the node's inbound capacity: cap, warn or allow" choice:"cap" choice:"warn" choice:"allow"`
+
+	PrefetchDonationInvoice bool `long:"prefetch_donation_invoice" env:"DCRLNHUB_PREFETCH_DONATION_INVOICE" description:"render the QR code of the donation invoice as soon as it's created, so it's served instantly, e.g. on kiosks"`
 
 	Network string
-	MainNet bool `long:"mainnet" description:"use the main network."`
-	TestNet bool `long:"testnet" description:"use the test network."`
-	SimNet  bool `long:"simnet" description:"use the simulation network."`
+	MainNet bool `long:"mainnet" env:"DCRLNHUB_MAINNET" description:"use the main network."`
+	TestNet bool `long:"testnet" env:"DCRLNHUB_TESTNET" description:"use the test network."`
+	SimNet  bool `long:"simnet" env:"DCRLNHUB_SIMNET" description:"use the simulation network."`
+
+	// trustedProxyNets are the parsed TrustedProxies.
+	trustedProxyNets []*net.IPNet
+
+	// nodes are the parsed Nodes.
+	nodes []nodeConfig
+
+	// featuredPeers are the parsed FeaturedPeers.
+	featuredPeers []featuredPeerConfig
+
+	// domains are the parsed Domain.
+	domains []string
+
+	// adminClientCAs are the certificates loaded from AdminClientCA.
+	adminClientCAs *x509.CertPool
 }
 
 func loadConfig() (*config, []string, error) {
 	// Default config.
 	cfg := config{
-		BindAddr:     defaultBindAddr,
-		TLSCertPath:  defaultDcrlndTLSCertPath,
-		MacaroonPath: defaultDcrlndMacaroonPath,
-		UseLeHTTPS:   defaultUseLeHTTPS,
+		BindAddr:   defaultBindAddr,
+		RPCHost:    defaultDcrlndRPCHost,
+		UseLeHTTPS: defaultUseLeHTTPS,
+
+		HTTPSBindAddr: defaultHTTPSBindAddr,
+		LogFormat:     defaultLogFormat,
+
+		StartupAttempts: defaultStartupAttempts,
+		StartupTimeout:  defaultStartupTimeout,
+		RPCTimeout:      defaultRPCTimeout,
+		RPCRetries:      defaultRPCRetries,
+		RPCRetryBackoff: defaultRPCRetryBackoff,
+
+		MinDcrlndVersion: defaultMinDcrlndVersion,
+
+		DonationAmountPolicy: defaultDonationAmountPolicy,
+		DonationAmount:       defaultDonationAmount,
+		MinDonationAmount:    defaultMinDonationAmount,
+		MaxDonationAmount:    defaultMaxDonationAmount,
+		DonationExpiry:       defaultDonationExpiry,
+		MaxInactiveRatio:     defaultMaxInactiveRatio,
+		SelfTestAmount:       defaultSelfTestAmount,
+		MinChannelSize:       defaultMinChannelSize,
+		MaxChannelSize:       defaultMaxChannelSize,
+		WalletReserve:        defaultWalletReserve,
+		RateLimit:            defaultRateLimit,
+		MaxWSClients:         defaultMaxWSClients,
+		RequestTimeout:       defaultRequestTimeout,
+		ReachabilityInterval: defaultReachabilityInterval,
+		GraphInterval:        defaultGraphInterval,
+		FeeBumpTargetConf:    defaultFeeBumpTargetConf,
+		RefreshInterval:      defaultRefreshInterval,
+		HistorySize:          defaultHistorySize,
+		MaxStaleness:         defaultMaxStaleness,
+		StaleRefreshTimeout:  defaultStaleRefreshTimeout,
+		SiteName:             defaultSiteName,
+		ShutdownTimeout:      defaultShutdownTimeout,
+		HTTPReadTimeout:      defaultHTTPReadTimeout,
+		HTTPWriteTimeout:     defaultHTTPWriteTimeout,
+		HTTPIdleTimeout:      defaultHTTPIdleTimeout,
+		MaxHeaderBytes:       defaultMaxHeaderBytes,
+		Tagline:              defaultTagline,
+		DebugLevel:           defaultLogLevel,
+		BadgeColor:           defaultBadgeColor,
+		ThemeColor:           defaultThemeColor,
+		StaticDir:            defaultStaticDir,
+
+		HSTSMaxAge:            defaultHSTSMaxAge,
+		ContentSecurityPolicy: defaultContentSecurityPolicy,
 	}
 
 	// Pre-parse the command line options to see if an alternative config
@@ -88,11 +332,17 @@ func loadConfig() (*config, []string, error) {
 		preCfg.ConfigFile = defaultConfigFile
 	}
 
-	// Load additional config from file.
+	// The config is resolved with the following precedence, from highest
+	// to lowest: command line flags, DCRLNHUB_* environment variables,
+	// config file and defaults.
+	//
+	// The config file is loaded with its own parser, so the values it
+	// sets are seen as defaults by the command line parser below. That
+	// parser only applies the environment variables to the options which
+	// aren't set on the command line, overriding the config file.
 	var configFileError error
-	parser := flags.NewParser(&cfg, flags.Default)
-
-	err = flags.NewIniParser(parser).ParseFile(preCfg.ConfigFile)
+	fileParser := flags.NewParser(&cfg, flags.Default)
+	err = flags.NewIniParser(fileParser).ParseFile(preCfg.ConfigFile)
 	if err != nil {
 		if _, ok := err.(*os.PathError); !ok {
 			fmt.Fprintf(os.Stderr, "Error parsing config "+
@@ -103,7 +353,9 @@ func loadConfig() (*config, []string, error) {
 		configFileError = err
 	}
 
-	// Parse command line options again to ensure they take precedence.
+	// Parse command line options again to ensure they take precedence,
+	// applying the environment variables to the remaining options.
+	parser := flags.NewParser(&cfg, flags.Default)
 	remainingArgs, err := parser.Parse()
 	if err != nil {
 		if e, ok := err.(*flags.Error); !ok || e.Type != flags.ErrHelp {
@@ -112,6 +364,10 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Remember the config file actually used, so it can be read again
+	// on reload.
+	cfg.ConfigFile = preCfg.ConfigFile
+
 	funcName := "loadConfig"
 
 	// Multiple networks can't be selected simultaneously.
@@ -138,47 +394,409 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
-	// Update dcrlnd config paths from TLSCert and macaroons
-	cfg.TLSCertPath = strings.Replace(
-		cfg.TLSCertPath, "testnet",
-		cfg.Network, 1,
-	)
-	cfg.MacaroonPath = strings.Replace(
-		cfg.MacaroonPath, "testnet",
-		cfg.Network, 1,
-	)
+	// Default to testnet when no network was selected.
+	networkAssumed := numNets == 0
+	if networkAssumed {
+		cfg.Network = "testnet"
+	}
 
-	// Create the home directory if it doesn't already exist.
-	err = os.MkdirAll(defaultDataDir, 0700)
-	if err != nil {
-		// Show a nicer error message if it's because a symlink is
-		// linked to a directory that does not exist (probably because
-		// it's not mounted).
-		if e, ok := err.(*os.PathError); ok && os.IsExist(err) {
-			if link, lerr := os.Readlink(e.Path); lerr == nil {
-				str := "is symlink %s -> %s mounted?"
-				err = fmt.Errorf(str, e.Path, link)
+	// dcrlnd keeps its macaroons in a directory per network, so the
+	// default paths are derived from the selected network. A path set by
+	// the user is used as it is.
+	paths := defaultPathsForNetwork(cfg.Network)
+	if cfg.TLSCertPath == "" {
+		cfg.TLSCertPath = paths.TLSCertPath
+	}
+	macaroonDerived := cfg.MacaroonPath == ""
+	if macaroonDerived {
+		cfg.MacaroonPath = paths.MacaroonPath
+	}
+
+	// The log format must be set before anything is logged.
+	setLogFormat(cfg.LogFormat)
+
+	// When running without a data directory, e.g. on a read-only
+	// filesystem, nothing is written to disk and the logs only go to
+	// stderr.
+	if !cfg.NoDataDir {
+		// Create the home directory if it doesn't already exist.
+		err = os.MkdirAll(defaultDataDir, 0700)
+		if err != nil {
+			// Show a nicer error message if it's because a symlink
+			// is linked to a directory that does not exist
+			// (probably because it's not mounted).
+			if e, ok := err.(*os.PathError); ok && os.IsExist(err) {
+				if link, lerr := os.Readlink(e.Path); lerr == nil {
+					str := "is symlink %s -> %s mounted?"
+					err = fmt.Errorf(str, e.Path, link)
+				}
 			}
+
+			str := "%s: Failed to create home directory: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
 		}
 
-		str := "%s: Failed to create home directory: %v"
-		err := fmt.Errorf(str, funcName, err)
+		// Initialize log rotation.  After log rotation has been
+		// initialized, the logger variables may be used.
+		initLogRotator(paths.LogPath)
+	}
+	if !validLogLevel(cfg.DebugLevel) {
+		err := fmt.Errorf("%s: invalid debuglevel %q", funcName,
+			cfg.DebugLevel)
 		fmt.Fprintln(os.Stderr, err)
 		return nil, nil, err
 	}
+	setLogLevels(cfg.DebugLevel)
+
+	if networkAssumed {
+		log.Infof("No network selected, assuming %s", cfg.Network)
+	}
+	switch {
+	case cfg.readOnly():
+		log.Infof("Writes are disabled, using the read-only macaroon %s",
+			cfg.ReadOnlyMacaroonPath)
+	case cfg.ReadOnlyMacaroonPath != "":
+		log.Warnf("Writes are enabled, ignoring the read-only macaroon")
+	}
+
+	// Catch a bad dcrlnd address now, as the dial doesn't block and would
+	// only fail on the first request.
+	if _, port, err := net.SplitHostPort(cfg.RPCHost); err != nil ||
+		port == "" {
 
-	// Initialize log rotation.  After log rotation has been initialized, the
-	// logger variables may be used.
-	defaultLogPath = strings.Replace(defaultLogPath, "testnet", cfg.Network, 1)
-	initLogRotator(defaultLogPath)
-	setLogLevels(defaultLogLevel)
+		err := fmt.Errorf("%s: invalid rpchost %q, expected host:port",
+			funcName, cfg.RPCHost)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.SOCKSProxy != "" {
+		if _, _, err := net.SplitHostPort(cfg.SOCKSProxy); err != nil {
+			err := fmt.Errorf("%s: invalid socksproxy %q, expected "+
+				"host:port", funcName, cfg.SOCKSProxy)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
 
-	if cfg.UseLeHTTPS && cfg.Domain == "" {
+	domains, err := parseDomains(cfg.Domain)
+	if err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	cfg.domains = domains
+
+	if cfg.UseLeHTTPS && len(cfg.domains) == 0 {
 		err := fmt.Errorf("%s: domain must be specified to use Let's Encrypt HTTPS", funcName)
 		fmt.Fprintln(os.Stderr, err)
 		return nil, nil, err
 	}
 
+	if cfg.UseLeHTTPS && cfg.HTTPSBindAddr == cfg.BindAddr {
+		err := fmt.Errorf("%s: https_bind_addr and bind_addr must be "+
+			"different to use Let's Encrypt HTTPS", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if len(cfg.NoIndexPrefixes) == 0 {
+		cfg.NoIndexPrefixes = defaultNoIndexPrefixes
+	}
+
+	// Use the default sanitizer allowlists unless the operator provided
+	// their own.
+	if len(cfg.SanitizeTags) == 0 {
+		cfg.SanitizeTags = defaultSanitizeTags
+	}
+	if len(cfg.SanitizeAttrs) == 0 {
+		cfg.SanitizeAttrs = defaultSanitizeAttrs
+	}
+
+	cfg.nodes, err = parseNodeConfigs(cfg.Nodes)
+	if err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	cfg.featuredPeers, err = parseFeaturedPeers(cfg.FeaturedPeers)
+	if err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.TLSCertPEM != "" {
+		if _, err := parseCertPEM(cfg.tlsCertPEM()); err != nil {
+			err := fmt.Errorf("%s: invalid tlscert_pem: %v",
+				funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	// Fail early with an actionable error when the files needed to reach
+	// dcrlnd are missing, instead of once the hub is half set up.
+	if err := checkDcrlndFiles(&cfg, macaroonDerived); err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	cfg.trustedProxyNets, err = parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		err := fmt.Errorf("%s: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.HTTPReadTimeout < 0 || cfg.HTTPWriteTimeout < 0 ||
+		cfg.HTTPIdleTimeout < 0 {
+
+		err := fmt.Errorf("%s: http_read_timeout, http_write_timeout "+
+			"and http_idle_timeout can't be negative", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.MaxHeaderBytes < 1 {
+		err := fmt.Errorf("%s: max_header_bytes must be positive",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.StartupAttempts < 1 {
+		err := fmt.Errorf("%s: startup_attempts must be at least 1",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if _, err := parseSemver(cfg.MinDcrlndVersion); err != nil {
+		err := fmt.Errorf("%s: invalid min_dcrlnd_version: %v",
+			funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.RPCTimeout < 0 {
+		err := fmt.Errorf("%s: rpc_timeout can't be negative",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.RPCRetries < 0 {
+		err := fmt.Errorf("%s: rpc_retries can't be negative",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.RefreshInterval <= 0 {
+		err := fmt.Errorf("%s: refresh_interval must be positive",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.MaxInactiveRatio < 0 || cfg.MaxInactiveRatio > 1 {
+		err := fmt.Errorf("%s: max_inactive_ratio must be between 0 "+
+			"and 1", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.SelfTestAmount <= 0 ||
+		dcrutil.Amount(cfg.SelfTestAmount) > maxSelfTestAmount {
+
+		err := fmt.Errorf("%s: selftest_amount must be between 1 and "+
+			"%d atoms", funcName, int64(maxSelfTestAmount))
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.MinDonationAmount <= 0 ||
+		cfg.MinDonationAmount > cfg.MaxDonationAmount {
+
+		err := fmt.Errorf("%s: min_donation_amount must be positive "+
+			"and no greater than max_donation_amount", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.DonationAmount < cfg.MinDonationAmount ||
+		cfg.DonationAmount > cfg.MaxDonationAmount {
+
+		err := fmt.Errorf("%s: donation_amount must be between "+
+			"min_donation_amount and max_donation_amount", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// An invoice expiring before the next refresh would leave visitors
+	// with an unpayable invoice until then.
+	if cfg.DonationExpiry < cfg.RefreshInterval {
+		err := fmt.Errorf("%s: donation_expiry must be at least "+
+			"refresh_interval", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.MinChannelSize <= 0 || cfg.MinChannelSize > cfg.MaxChannelSize {
+		err := fmt.Errorf("%s: min_channel_size must be positive and "+
+			"no greater than max_channel_size", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.SuggestedChannelSize != 0 &&
+		(cfg.SuggestedChannelSize < cfg.MinChannelSize ||
+			cfg.SuggestedChannelSize > cfg.MaxChannelSize) {
+
+		err := fmt.Errorf("%s: suggested_channel_size must be between "+
+			"min_channel_size and max_channel_size", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.FeeBumpAfter < 0 {
+		err := fmt.Errorf("%s: fee_bump_after can't be negative",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.FeeBumpAfter > 0 && !cfg.EnableFeeBump {
+		err := fmt.Errorf("%s: fee_bump_after requires enable_fee_bump",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.FeeBumpTargetConf == 0 {
+		err := fmt.Errorf("%s: fee_bump_target_conf must be positive",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.WalletReserve < 0 {
+		err := fmt.Errorf("%s: wallet_reserve can't be negative",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// The static directory is resolved once, so the hub doesn't depend on
+	// the working directory afterwards.
+	cfg.StaticDir, err = filepath.Abs(cleanAndExpandPath(cfg.StaticDir))
+	if err != nil {
+		err := fmt.Errorf("%s: invalid staticdir: %v", funcName, err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if info, err := os.Stat(cfg.StaticDir); err != nil || !info.IsDir() {
+		err := fmt.Errorf("%s: staticdir %v isn't a directory, set "+
+			"staticdir to the directory of the templates", funcName,
+			cfg.StaticDir)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if !nodeColorPattern.MatchString(cfg.BadgeColor) {
+		err := fmt.Errorf("%s: invalid badge_color %q, expected "+
+			"#rrggbb", funcName, cfg.BadgeColor)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.StaticDonationAddr != "" {
+		err := checkDonationAddr(cfg.StaticDonationAddr, cfg.Network)
+		if err != nil {
+			err := fmt.Errorf("%s: invalid static_donation_addr: "+
+				"%v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if !nodeColorPattern.MatchString(cfg.ThemeColor) {
+		err := fmt.Errorf("%s: invalid theme_color %q, expected "+
+			"#rrggbb", funcName, cfg.ThemeColor)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.HistorySize < 1 {
+		err := fmt.Errorf("%s: history_size must be at least 1",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.HistoryFile != "" && cfg.NoDataDir {
+		err := fmt.Errorf("%s: history_file can't be set together "+
+			"with no_datadir", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.HistoryFile != "" {
+		cfg.HistoryFile = cleanAndExpandPath(cfg.HistoryFile)
+	}
+
+	if cfg.RateLimit < 0 {
+		err := fmt.Errorf("%s: rate_limit can't be negative", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.ValidateHost && len(cfg.domains) == 0 {
+		err := fmt.Errorf("%s: domain must be specified to validate "+
+			"the request host", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	if cfg.AdminClientCA != "" {
+		if !cfg.UseLeHTTPS {
+			err := fmt.Errorf("%s: admin_client_ca requires "+
+				"use_le_https", funcName)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+
+		cfg.adminClientCAs, err = loadCertPool(
+			cleanAndExpandPath(cfg.AdminClientCA),
+		)
+		if err != nil {
+			err := fmt.Errorf("%s: unable to load admin_client_ca: "+
+				"%v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if (cfg.BasicAuthUser == "") != (cfg.BasicAuthPass == "") {
+		err := fmt.Errorf("%s: basic_auth_user and basic_auth_pass "+
+			"must be set together", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+	if cfg.BasicAuthPass != "" {
+		if _, err := bcrypt.Cost([]byte(cfg.BasicAuthPass)); err != nil {
+			err := fmt.Errorf("%s: basic_auth_pass must be a bcrypt "+
+				"hash: %v", funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, nil, err
+		}
+	}
+
+	if cfg.PublicAggregateOnly && cfg.AdminToken == "" {
+		log.Warnf("public_aggregate_only is set without an admin_token, " +
+			"the channel list won't be shown to anyone")
+	}
+
 	// Warn about missing config file only after all other configuration is
 	// done.  This prevents the warning on help messages and invalid
 	// options.  Note this should go directly before the return.
@@ -188,3 +806,39 @@ func loadConfig() (*config, []string, error) {
 
 	return &cfg, remainingArgs, nil
 }
+
+// reloadConfig reads the config again from the same sources and with the same
+// precedence as loadConfig, and applies the settings which can be changed
+// while the hub is running, which is only the log level. The other settings
+// require a restart.
+func reloadConfig(cfg *config) error {
+	newCfg := config{DebugLevel: defaultLogLevel}
+
+	fileParser := flags.NewParser(&newCfg, flags.None)
+	err := flags.NewIniParser(fileParser).ParseFile(cfg.ConfigFile)
+	if err != nil {
+		if _, ok := err.(*os.PathError); !ok {
+			return fmt.Errorf("unable to parse config file: %v", err)
+		}
+	}
+
+	parser := flags.NewParser(&newCfg, flags.None)
+	if _, err := parser.Parse(); err != nil {
+		return err
+	}
+
+	if !validLogLevel(newCfg.DebugLevel) {
+		return fmt.Errorf("invalid debuglevel %q", newCfg.DebugLevel)
+	}
+	if newCfg.DebugLevel != cfg.DebugLevel {
+		log.Infof("Changing log level from %s to %s", cfg.DebugLevel,
+			newCfg.DebugLevel)
+		setLogLevels(newCfg.DebugLevel)
+		cfg.DebugLevel = newCfg.DebugLevel
+	}
+
+	log.Infof("Config reloaded, only the log level is applied, " +
+		"restart the hub to apply any other change")
+
+	return nil
+}
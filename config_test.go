@@ -0,0 +1,399 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/decred/slog"
+)
+
+// TestDefaultPathsForNetwork checks that the default macaroon and log paths
+// are in the directory of the selected network, while dcrlnd's TLS
+// certificate is shared across networks.
+func TestDefaultPathsForNetwork(t *testing.T) {
+	tests := []struct {
+		network string
+		want    networkPaths
+	}{{
+		network: "mainnet",
+		want: networkPaths{
+			TLSCertPath: filepath.Join(defaultDcrlndDir, "tls.cert"),
+			MacaroonPath: filepath.Join(defaultDcrlndDir, "data",
+				"chain", "decred", "mainnet", "admin.macaroon"),
+			LogPath: filepath.Join(defaultDataDir, "logs", "decred",
+				"mainnet", "dcrlnhub.log"),
+		},
+	}, {
+		network: "testnet",
+		want: networkPaths{
+			TLSCertPath: filepath.Join(defaultDcrlndDir, "tls.cert"),
+			MacaroonPath: filepath.Join(defaultDcrlndDir, "data",
+				"chain", "decred", "testnet", "admin.macaroon"),
+			LogPath: filepath.Join(defaultDataDir, "logs", "decred",
+				"testnet", "dcrlnhub.log"),
+		},
+	}, {
+		network: "simnet",
+		want: networkPaths{
+			TLSCertPath: filepath.Join(defaultDcrlndDir, "tls.cert"),
+			MacaroonPath: filepath.Join(defaultDcrlndDir, "data",
+				"chain", "decred", "simnet", "admin.macaroon"),
+			LogPath: filepath.Join(defaultDataDir, "logs", "decred",
+				"simnet", "dcrlnhub.log"),
+		},
+	}}
+
+	for _, test := range tests {
+		got := defaultPathsForNetwork(test.network)
+		if got != test.want {
+			t.Errorf("%s: got paths %+v, want %+v", test.network,
+				got, test.want)
+		}
+	}
+}
+
+// TestLoadConfigPrecedence checks that the environment variables override
+// the config file, and yield to the command line flags.
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "tls.cert")
+	macPath := filepath.Join(dir, "admin.macaroon")
+	configPath := filepath.Join(dir, "dcrlnhub.conf")
+	for _, path := range []string{certPath, macPath} {
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		file         string
+		env          map[string]string
+		args         []string
+		wantBindAddr string
+		wantRPCHost  string
+	}{{
+		name:         "defaults",
+		wantBindAddr: defaultBindAddr,
+		wantRPCHost:  defaultDcrlndRPCHost,
+	}, {
+		name:         "file",
+		file:         "bind_addr=:9000\nrpchost=10.0.0.1:10009\n",
+		wantBindAddr: ":9000",
+		wantRPCHost:  "10.0.0.1:10009",
+	}, {
+		name: "env over file",
+		file: "bind_addr=:9000\nrpchost=10.0.0.1:10009\n",
+		env: map[string]string{
+			"DCRLNHUB_BIND_ADDR": ":9001",
+		},
+		wantBindAddr: ":9001",
+		wantRPCHost:  "10.0.0.1:10009",
+	}, {
+		name: "env over defaults",
+		env: map[string]string{
+			"DCRLNHUB_RPCHOST": "10.0.0.2:10009",
+		},
+		wantBindAddr: defaultBindAddr,
+		wantRPCHost:  "10.0.0.2:10009",
+	}, {
+		name: "flags over env",
+		file: "bind_addr=:9000\nrpchost=10.0.0.1:10009\n",
+		env: map[string]string{
+			"DCRLNHUB_BIND_ADDR": ":9001",
+			"DCRLNHUB_RPCHOST":   "10.0.0.2:10009",
+		},
+		args:         []string{"--bind_addr=:9002"},
+		wantBindAddr: ":9002",
+		wantRPCHost:  "10.0.0.2:10009",
+	}}
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	for _, test := range tests {
+		err := ioutil.WriteFile(
+			configPath, []byte("[Application Options]\n"+test.file),
+			0600,
+		)
+		if err != nil {
+			t.Fatalf("unable to write config file: %v", err)
+		}
+		for key, value := range test.env {
+			os.Setenv(key, value)
+		}
+
+		os.Args = append([]string{
+			"dcrlnhub",
+			"--configfile=" + configPath,
+			"--testnet",
+			"--no_datadir",
+			"--certpath=" + certPath,
+			"--macpath=" + macPath,
+		}, test.args...)
+		cfg, _, err := loadConfig()
+
+		for key := range test.env {
+			os.Unsetenv(key)
+		}
+
+		if err != nil {
+			t.Errorf("%s: unable to load config: %v", test.name, err)
+			continue
+		}
+		if cfg.BindAddr != test.wantBindAddr {
+			t.Errorf("%s: got bind_addr %q, want %q", test.name,
+				cfg.BindAddr, test.wantBindAddr)
+		}
+		if cfg.RPCHost != test.wantRPCHost {
+			t.Errorf("%s: got rpchost %q, want %q", test.name,
+				cfg.RPCHost, test.wantRPCHost)
+		}
+	}
+}
+
+// TestLoadConfigRPCHost checks that an rpchost which isn't a host:port is
+// rejected before any dial attempt.
+func TestLoadConfigRPCHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "tls.cert")
+	macPath := filepath.Join(dir, "admin.macaroon")
+	for _, path := range []string{certPath, macPath} {
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		rpcHost string
+		wantErr bool
+	}{
+		{"empty host", "", true},
+		{"missing port", "10.0.0.1", true},
+		{"empty port", "10.0.0.1:", true},
+		{"valid host", "10.0.0.1:10009", false},
+	}
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	for _, test := range tests {
+		os.Args = []string{
+			"dcrlnhub",
+			"--configfile=" + filepath.Join(dir, "dcrlnhub.conf"),
+			"--testnet",
+			"--no_datadir",
+			"--certpath=" + certPath,
+			"--macpath=" + macPath,
+			"--rpchost=" + test.rpcHost,
+		}
+		cfg, _, err := loadConfig()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if err == nil && cfg.RPCHost != test.rpcHost {
+			t.Errorf("%s: got rpchost %q, want %q", test.name,
+				cfg.RPCHost, test.rpcHost)
+		}
+	}
+}
+
+// TestLoadConfigExplicitPaths checks that the certificate and macaroon paths
+// set by the user are kept as they are on every network, rather than being
+// replaced with the network's default paths.
+func TestLoadConfigExplicitPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "tls.cert")
+	macPath := filepath.Join(dir, "admin.macaroon")
+	for _, path := range []string{certPath, macPath} {
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+
+	for _, network := range []string{"mainnet", "testnet", "simnet"} {
+		os.Args = []string{
+			"dcrlnhub",
+			"--configfile=" + filepath.Join(dir, "dcrlnhub.conf"),
+			"--" + network,
+			"--no_datadir",
+			"--certpath=" + certPath,
+			"--macpath=" + macPath,
+		}
+		cfg, _, err := loadConfig()
+		if err != nil {
+			t.Errorf("%s: unable to load config: %v", network, err)
+			continue
+		}
+		if cfg.TLSCertPath != certPath {
+			t.Errorf("%s: got certpath %q, want %q", network,
+				cfg.TLSCertPath, certPath)
+		}
+		if cfg.MacaroonPath != macPath {
+			t.Errorf("%s: got macpath %q, want %q", network,
+				cfg.MacaroonPath, macPath)
+		}
+	}
+}
+
+// TestLoadConfigDefaultNetwork checks that testnet is selected when no
+// network flag is given.
+func TestLoadConfigDefaultNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "tls.cert")
+	macPath := filepath.Join(dir, "admin.macaroon")
+	for _, path := range []string{certPath, macPath} {
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+	os.Args = []string{
+		"dcrlnhub",
+		"--configfile=" + filepath.Join(dir, "dcrlnhub.conf"),
+		"--no_datadir",
+		"--certpath=" + certPath,
+		"--macpath=" + macPath,
+	}
+
+	cfg, _, err := loadConfig()
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+	if cfg.Network != "testnet" {
+		t.Fatalf("got network %q, want testnet", cfg.Network)
+	}
+}
+
+// TestLoadConfigHistoryFileNoDataDir checks that a history file can't be set
+// when no state is kept on disk.
+func TestLoadConfigHistoryFileNoDataDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "tls.cert")
+	macPath := filepath.Join(dir, "admin.macaroon")
+	for _, path := range []string{certPath, macPath} {
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+
+	origArgs := os.Args
+	defer func() {
+		os.Args = origArgs
+	}()
+	os.Args = []string{
+		"dcrlnhub",
+		"--configfile=" + filepath.Join(dir, "dcrlnhub.conf"),
+		"--no_datadir",
+		"--certpath=" + certPath,
+		"--macpath=" + macPath,
+		"--history_file=" + filepath.Join(dir, "history.json"),
+	}
+
+	if _, _, err := loadConfig(); err == nil {
+		t.Fatal("expected history_file with no_datadir to be rejected")
+	}
+}
+
+// TestReloadConfig checks that reloading the config applies the log level
+// set in the config file, and keeps the current one when it's invalid.
+func TestReloadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	origArgs := os.Args
+	origLevel := log.Level()
+	defer func() {
+		os.Args = origArgs
+		log.SetLevel(origLevel)
+	}()
+	os.Args = []string{"dcrlnhub"}
+
+	tests := []struct {
+		name      string
+		level     string
+		wantErr   bool
+		wantLevel slog.Level
+	}{
+		{"debug", "debug", false, slog.LevelDebug},
+		{"warn", "warn", false, slog.LevelWarn},
+		{"invalid", "verbose", true, slog.LevelWarn},
+	}
+
+	cfg := newTestConfig()
+	cfg.ConfigFile = filepath.Join(dir, "dcrlnhub.conf")
+	cfg.DebugLevel = "info"
+	setLogLevels(cfg.DebugLevel)
+	wantDebugLevel := cfg.DebugLevel
+	for _, test := range tests {
+		err := ioutil.WriteFile(cfg.ConfigFile, []byte(
+			"[Application Options]\ndebuglevel="+test.level+"\n",
+		), 0600)
+		if err != nil {
+			t.Fatalf("unable to write config file: %v", err)
+		}
+
+		err = reloadConfig(cfg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+		}
+		if err == nil {
+			wantDebugLevel = test.level
+		}
+		if cfg.DebugLevel != wantDebugLevel {
+			t.Errorf("%s: got debuglevel %q, want %q", test.name,
+				cfg.DebugLevel, wantDebugLevel)
+		}
+		if level := log.Level(); level != test.wantLevel {
+			t.Errorf("%s: got log level %v, want %v", test.name,
+				level, test.wantLevel)
+		}
+	}
+}
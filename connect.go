@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// checkNodePubkey returns an error if the passed string isn't a hex encoded
+// compressed public key, as used to identify Lightning nodes.
+func checkNodePubkey(pubkey string) error {
+	pubkeyBytes, err := hex.DecodeString(pubkey)
+	if err != nil {
+		return fmt.Errorf("invalid node pubkey, expected hex")
+	}
+	if len(pubkeyBytes) != secp256k1.PubKeyBytesLenCompressed {
+		return fmt.Errorf("invalid node pubkey, expected a compressed " +
+			"public key")
+	}
+	if _, err := secp256k1.ParsePubKey(pubkeyBytes); err != nil {
+		return fmt.Errorf("invalid node pubkey: %v", err)
+	}
+
+	return nil
+}
+
+// isAlreadyConnected returns true if the passed error was returned by
+// ConnectPeer because we're already connected to the peer.
+func isAlreadyConnected(err error) bool {
+	return strings.Contains(err.Error(), "already connected")
+}
+
+// parseNodeAddr parses a node address in the pubkey@host format.
+func parseNodeAddr(s string) (*lnrpc.LightningAddress, error) {
+	parts := strings.Split(strings.TrimSpace(s), "@")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid node address, expected " +
+			"pubkey@host")
+	}
+	if err := checkNodePubkey(parts[0]); err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.LightningAddress{
+		Pubkey: parts[0],
+		Host:   parts[1],
+	}, nil
+}
+
+// Connect connects the node to the peer submitted through the home page
+// form, for visitors who want to peer with the hub before asking for a
+// channel. The connection is made persistent, so dcrlnd reconnects to the
+// peer on its own. The outcome is rendered back into the home page.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Connect(w http.ResponseWriter, r *http.Request) {
+	homeTemplate := h.lookupTemplate("index.html")
+	if homeTemplate == nil {
+		log.Error("unable to lookup index")
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+	page := h.homePage(r, homeInfo)
+
+	addr, err := parseNodeAddr(r.FormValue("addr"))
+	if err != nil {
+		page.ConnectError = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		homeTemplate.Execute(w, page)
+		return
+	}
+
+	connectReq := &lnrpc.ConnectPeerRequest{
+		Addr: addr,
+		Perm: true,
+	}
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	_, err = h.client().ConnectPeer(ctx, connectReq)
+	switch {
+	case err == nil:
+		log.Infof("Connected to peer %v@%v", addr.Pubkey, addr.Host)
+		page.ConnectMessage = "Your node is now connected to the hub!"
+
+	case isAlreadyConnected(err):
+		page.ConnectMessage = "Your node is already connected to the hub."
+
+	default:
+		log.Errorf("unable to connect to %v@%v: %v", addr.Pubkey,
+			addr.Host, err)
+		page.ConnectError = fmt.Sprintf("unable to connect to your "+
+			"node: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	homeTemplate.Execute(w, page)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestConnect checks that the submitted node address is parsed and passed
+// to ConnectPeer as a permanent peer, an existing connection being reported
+// as a success.
+func TestConnect(t *testing.T) {
+	addr := featuredPubkey1 + "@203.0.113.9:9735"
+
+	tests := []struct {
+		name        string
+		addr        string
+		err         error
+		wantCode    int
+		wantConnect bool
+		wantBody    string
+	}{{
+		name:        "connected",
+		addr:        addr,
+		wantCode:    http.StatusOK,
+		wantConnect: true,
+		wantBody:    "now connected",
+	}, {
+		name: "already connected",
+		addr: addr,
+		err: status.Error(codes.Unknown, "already connected to "+
+			"peer: "+addr),
+		wantCode:    http.StatusOK,
+		wantConnect: true,
+		wantBody:    "already connected",
+	}, {
+		name:        "unreachable",
+		addr:        addr,
+		err:         status.Error(codes.Unknown, "dial timeout"),
+		wantCode:    http.StatusBadGateway,
+		wantConnect: true,
+		wantBody:    "unable to connect",
+	}, {
+		name:     "missing host",
+		addr:     featuredPubkey1,
+		wantCode: http.StatusBadRequest,
+		wantBody: "expected pubkey@host",
+	}, {
+		name:     "missing pubkey",
+		addr:     "@203.0.113.9:9735",
+		wantCode: http.StatusBadRequest,
+		wantBody: "expected pubkey@host",
+	}, {
+		name:     "invalid pubkey",
+		addr:     "02aa@203.0.113.9:9735",
+		wantCode: http.StatusBadRequest,
+		wantBody: "invalid node pubkey",
+	}}
+
+	for _, test := range tests {
+		lnd := &fakeLnd{info: testnetInfo()}
+		if test.err != nil {
+			lnd.errs = map[string][]error{
+				"ConnectPeer": {test.err},
+			}
+		}
+		hub := newTestHub(t, newTestConfig(), lnd)
+		homeCtx, err := fetchHomePage(lnd, hub.cfg)
+		if err != nil {
+			t.Fatalf("unable to fetch home page: %v", err)
+		}
+		if err := hub.setContext(homeCtx); err != nil {
+			t.Fatalf("unable to set context: %v", err)
+		}
+
+		form := url.Values{"addr": {test.addr}}
+		r := httptest.NewRequest("POST", "/connect",
+			strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type",
+			"application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		hub.Connect(w, r)
+
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+		}
+		if !strings.Contains(w.Body.String(), test.wantBody) {
+			t.Errorf("%s: response doesn't contain %q", test.name,
+				test.wantBody)
+		}
+
+		req, _ := lnd.lastRequest("ConnectPeer").(*lnrpc.ConnectPeerRequest)
+		if (req != nil) != test.wantConnect {
+			t.Errorf("%s: got ConnectPeer request %v", test.name,
+				req)
+			continue
+		}
+		if req == nil {
+			continue
+		}
+		if req.Addr.Pubkey != featuredPubkey1 ||
+			req.Addr.Host != "203.0.113.9:9735" || !req.Perm {
+
+			t.Errorf("%s: got ConnectPeer request %v", test.name,
+				req)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// corsAllowedMethods are the methods allowed on cross-origin requests
+	// to the API, which is read-only.
+	corsAllowedMethods = "GET, OPTIONS"
+
+	// corsMaxAge is how long in seconds browsers may cache the result of
+	// a preflight request.
+	corsMaxAge = "3600"
+)
+
+// corsOriginAllowed returns true if the passed origin may make cross-origin
+// requests to the API. A "*" entry allows every origin.
+func (h *lightningHub) corsOriginAllowed(origin string) bool {
+	for _, allowed := range h.cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cors wraps an API handler, adding the CORS headers to the responses to the
+// allowed origins and answering their preflight requests. Requests from
+// other origins get no CORS headers, so browsers keep blocking them.
+func (h *lightningHub) cors(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on the origin, so caches must not
+		// share it across origins.
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && h.corsOriginAllowed(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if r.Method != http.MethodOptions {
+			next(w, r)
+			return
+		}
+
+		// Answer the preflight request ourselves.
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Methods",
+				corsAllowedMethods)
+			headers := r.Header.Get("Access-Control-Request-Headers")
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers",
+					headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
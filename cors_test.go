@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCORS checks that the CORS headers are only sent to the allowed
+// origins, and that their preflight requests are answered.
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowed     []string
+		method      string
+		origin      string
+		wantAllowed bool
+		wantCode    int
+	}{{
+		name:        "allowed origin",
+		allowed:     []string{"https://dash.example.com"},
+		method:      "GET",
+		origin:      "https://dash.example.com",
+		wantAllowed: true,
+		wantCode:    http.StatusOK,
+	}, {
+		name:     "other origin",
+		allowed:  []string{"https://dash.example.com"},
+		method:   "GET",
+		origin:   "https://evil.example.com",
+		wantCode: http.StatusOK,
+	}, {
+		name:     "no origin allowed",
+		method:   "GET",
+		origin:   "https://dash.example.com",
+		wantCode: http.StatusOK,
+	}, {
+		name:        "any origin",
+		allowed:     []string{"*"},
+		method:      "GET",
+		origin:      "https://dash.example.com",
+		wantAllowed: true,
+		wantCode:    http.StatusOK,
+	}, {
+		name:        "allowed preflight",
+		allowed:     []string{"https://dash.example.com"},
+		method:      "OPTIONS",
+		origin:      "https://dash.example.com",
+		wantAllowed: true,
+		wantCode:    http.StatusNoContent,
+	}, {
+		name:     "other preflight",
+		allowed:  []string{"https://dash.example.com"},
+		method:   "OPTIONS",
+		origin:   "https://evil.example.com",
+		wantCode: http.StatusNoContent,
+	}}
+
+	var served bool
+	api := func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.AllowedOrigins = test.allowed
+		hub := newTestHub(t, cfg, &fakeLnd{})
+
+		served = false
+		r := httptest.NewRequest(test.method, "/api/v1/info", nil)
+		r.Header.Set("Origin", test.origin)
+		r.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		w := httptest.NewRecorder()
+		hub.cors(api)(w, r)
+
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+		}
+		if served != (test.method != "OPTIONS") {
+			t.Errorf("%s: got API served %v", test.name, served)
+		}
+
+		allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+		switch {
+		case test.wantAllowed && allowOrigin != test.origin:
+			t.Errorf("%s: got allowed origin %q, want %q",
+				test.name, allowOrigin, test.origin)
+		case !test.wantAllowed && allowOrigin != "":
+			t.Errorf("%s: got allowed origin %q, want none",
+				test.name, allowOrigin)
+		}
+
+		preflight := test.method == "OPTIONS" && test.wantAllowed
+		methods := w.Header().Get("Access-Control-Allow-Methods")
+		if (methods != "") != preflight {
+			t.Errorf("%s: got allowed methods %q", test.name,
+				methods)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// maxDonationMemoLen is the maximum length of the memo donors can add
+	// to their donation invoice.
+	maxDonationMemoLen = 100
+)
+
+// donatePage is the context used to render the donation page.
+type donatePage struct {
+	*baseContext
+
+	// Amount and Memo are the amount and memo of the donation.
+	Amount dcrutil.Amount
+	Memo   string
+
+	// Invoice is the payment request of the donation invoice, which
+	// expires at Expiry.
+	Invoice string
+	Expiry  time.Time
+
+	// URI is the invoice's lightning: URI.
+	URI template.URL
+
+	// QRCode is the invoice's QR code as a PNG data URI.
+	QRCode template.URL
+}
+
+// parseDonation parses the amount and memo of a donation, falling back to the
+// configured amount when none is set.
+func (h *lightningHub) parseDonation(r *http.Request) (dcrutil.Amount,
+	string, error) {
+
+	amt := dcrutil.Amount(h.cfg.DonationAmount)
+	if v := r.FormValue("amount"); v != "" {
+		atoms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid amount, expected atoms")
+		}
+		amt = dcrutil.Amount(atoms)
+	}
+	minAmt := dcrutil.Amount(h.cfg.MinDonationAmount)
+	maxAmt := dcrutil.Amount(h.cfg.MaxDonationAmount)
+	if amt < minAmt || amt > maxAmt {
+		return 0, "", fmt.Errorf("the donation must be between %v "+
+			"and %v", minAmt, maxAmt)
+	}
+
+	// The memo ends up in the invoice, so keep it short and printable.
+	memo := strings.TrimSpace(r.FormValue("memo"))
+	if len(memo) > maxDonationMemoLen {
+		return 0, "", fmt.Errorf("the memo can't be longer than %d "+
+			"characters", maxDonationMemoLen)
+	}
+	for _, c := range memo {
+		if !unicode.IsPrint(c) {
+			return 0, "", fmt.Errorf("the memo contains invalid " +
+				"characters")
+		}
+	}
+
+	return amt, memo, nil
+}
+
+// Donate creates a donation invoice for the amount and memo chosen by the
+// donor, and renders it along with its QR code.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Donate(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.readOnly() {
+		http.Error(w, "donations are disabled", http.StatusForbidden)
+		return
+	}
+
+	donateTemplate := h.lookupTemplate("donate.html")
+	if donateTemplate == nil {
+		log.Error("unable to lookup donate")
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	amt, memo, err := h.parseDonation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		h.renderUnavailable(w)
+		return
+	}
+
+	amt, invoice, expiry, err := addDonationInvoice(
+		h.client(), h.cfg, amt, memo, homeInfo.HubInboundCapacity,
+	)
+	if err != nil {
+		log.Errorf("unable to create donation invoice: %v", err)
+		http.Error(w, "unable to create the donation invoice",
+			http.StatusBadGateway)
+		return
+	}
+
+	png, err := qrcode.Encode(invoice, qrcode.Medium, qrSize)
+	if err != nil {
+		log.Errorf("unable to encode QR code: %v", err)
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	donateTemplate.Execute(w, &donatePage{
+		baseContext: h.baseContext("Donate"),
+		Amount:      amt,
+		Memo:        memo,
+		Invoice:     invoice,
+		Expiry:      expiry,
+		URI:         lightningURI(invoice),
+		QRCode: template.URL("data:image/png;base64," +
+			base64.StdEncoding.EncodeToString(png)),
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestDonate checks that donors can choose the amount of their donation
+// within the configured bounds, falling back to the configured amount.
+func TestDonate(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantValue int64
+		wantMemo  string
+	}{{
+		name:      "valid amount",
+		query:     "?amount=50000&memo=thanks",
+		wantCode:  http.StatusOK,
+		wantValue: 50000,
+		wantMemo:  donationInvoiceMemo("thanks"),
+	}, {
+		name:      "missing amount",
+		query:     "",
+		wantCode:  http.StatusOK,
+		wantValue: 1000,
+		wantMemo:  donationInvoiceMemo(""),
+	}, {
+		name:     "below minimum",
+		query:    "?amount=10",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "above maximum",
+		query:    "?amount=100000001",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "not a number",
+		query:    "?amount=1dcr",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "memo too long",
+		query:    "?memo=" + strings.Repeat("a", maxDonationMemoLen+1),
+		wantCode: http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		lnd := &fakeLnd{
+			invoice: &lnrpc.AddInvoiceResponse{
+				PaymentRequest: "lntdcr1donation",
+			},
+		}
+		cfg := newTestConfig()
+		cfg.MinDonationAmount = 1000
+		cfg.MaxDonationAmount = 100000000
+		hub := newTestHub(t, cfg, lnd)
+		err := hub.setContext(&templateContext{DcrlndVersion: "0.2.1"})
+		if err != nil {
+			t.Fatalf("unable to set context: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		hub.Donate(w, httptest.NewRequest("GET", "/donate"+test.query,
+			nil))
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+			continue
+		}
+
+		if test.wantCode != http.StatusOK {
+			if n := lnd.callCount("AddInvoice"); n != 0 {
+				t.Errorf("%s: got %d AddInvoice calls, want none",
+					test.name, n)
+			}
+			continue
+		}
+		invoice := lnd.lastRequest("AddInvoice").(*lnrpc.Invoice)
+		if invoice.Value != test.wantValue {
+			t.Errorf("%s: got invoice of %d atoms, want %d",
+				test.name, invoice.Value, test.wantValue)
+		}
+		if invoice.Memo != test.wantMemo {
+			t.Errorf("%s: got memo %q, want %q", test.name,
+				invoice.Memo, test.wantMemo)
+		}
+		if !strings.Contains(w.Body.String(), "lntdcr1donation") {
+			t.Errorf("%s: payment request not rendered", test.name)
+		}
+	}
+}
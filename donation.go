@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dcrutilv1 "github.com/decred/dcrd/dcrutil"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+const (
+	// donationMemo is the memo set on every donation invoice created by
+	// the hub, followed by the donor's memo if any. It's used to tell
+	// donations apart from other invoices.
+	donationMemo = "dcrlnhub donation"
+
+	// donationsFilename is the name of the file, within the network's data
+	// directory, used to persist the donations received.
+	donationsFilename = "donations.json"
+
+	// invoiceSubscriptionRetryInterval is how long to wait before
+	// subscribing to invoices again after the subscription failed.
+	invoiceSubscriptionRetryInterval = 10 * time.Second
+
+	// listInvoicesPageSize is the number of invoices requested per
+	// ListInvoices call when reconciling the donations.
+	listInvoicesPageSize = 100
+
+	// donationPolicyCap lowers donation amounts above the inbound capacity
+	// down to the inbound capacity.
+	donationPolicyCap = "cap"
+
+	// donationPolicyWarn keeps donation amounts above the inbound capacity
+	// as they are, but logs a warning.
+	donationPolicyWarn = "warn"
+
+	// donationPolicyAllow keeps donation amounts as they are.
+	donationPolicyAllow = "allow"
+)
+
+// lightningURI returns the lightning: URI of the passed payment request, so
+// wallets can open it from a link. The URI is typed as safe for the templates,
+// which would otherwise reject its scheme. An empty payment request yields an
+// empty URI.
+func lightningURI(payReq string) template.URL {
+	if payReq == "" {
+		return ""
+	}
+
+	return template.URL("lightning:" + url.PathEscape(payReq))
+}
+
+// decredURI returns the decred: URI of the passed on-chain address, requesting
+// the passed amount unless it's zero. Like lightningURI, the URI is typed as
+// safe for the templates.
+func decredURI(addr string, amount dcrutil.Amount) template.URL {
+	if addr == "" {
+		return ""
+	}
+
+	uri := "decred:" + url.PathEscape(addr)
+	if amount > 0 {
+		uri += "?amount=" + strconv.FormatFloat(amount.ToCoin(), 'f', -1, 64)
+	}
+
+	return template.URL(uri)
+}
+
+// fetchDonationInvoice creates a new donation invoice for the configured
+// amount, checked against the node's inbound capacity. It returns the payment
+// request along with the time the invoice expires.
+func fetchDonationInvoice(lnd lndClient, cfg *config,
+	inbound dcrutil.Amount) (string, time.Time, error) {
+
+	_, invoice, expiry, err := addDonationInvoice(
+		lnd, cfg, dcrutil.Amount(cfg.DonationAmount), "", inbound,
+	)
+	return invoice, expiry, err
+}
+
+// donationInvoiceMemo returns the memo of a donation invoice with the passed
+// donor provided memo, which may be empty.
+func donationInvoiceMemo(memo string) string {
+	if memo == "" {
+		return donationMemo
+	}
+
+	return donationMemo + ": " + memo
+}
+
+// isDonationMemo returns true if the passed invoice memo is the memo of a
+// donation invoice.
+func isDonationMemo(memo string) bool {
+	return memo == donationMemo || strings.HasPrefix(memo, donationMemo+": ")
+}
+
+// addDonationInvoice creates a new donation invoice for the passed amount,
+// checked against the node's inbound capacity, and donor provided memo. It
+// returns the amount of the invoice, which may have been capped, and its
+// payment request along with the time the invoice expires.
+func addDonationInvoice(lnd lndClient, cfg *config,
+	amt dcrutil.Amount, memo string, inbound dcrutil.Amount) (
+	dcrutil.Amount, string, time.Time, error) {
+
+	amt, err := checkDonationAmount(cfg.DonationAmountPolicy, amt, inbound)
+	if err != nil {
+		return 0, "", time.Time{}, err
+	}
+
+	invoice := &lnrpc.Invoice{
+		Memo:   donationInvoiceMemo(memo),
+		Value:  int64(amt),
+		Expiry: int64(cfg.DonationExpiry.Seconds()),
+	}
+	ctx, cancel := cfg.rpcContext()
+	defer cancel()
+	invoiceRes, err := lnd.AddInvoice(ctx, invoice)
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("rpc AddInvoice() "+
+			"failed: %w", err)
+	}
+
+	expiry := time.Now().Add(cfg.DonationExpiry)
+	return amt, invoiceRes.PaymentRequest, expiry, nil
+}
+
+// checkDonationAddr validates the static donation address, which must be an
+// address of the hub's network.
+func checkDonationAddr(addr, network string) error {
+	decoded, err := dcrutilv1.DecodeAddress(addr)
+	if err != nil {
+		return err
+	}
+
+	// The network names of the address params carry a version suffix,
+	// e.g. testnet3.
+	if !strings.HasPrefix(decoded.Net().Name, network) {
+		return fmt.Errorf("address of %v instead of %v",
+			decoded.Net().Name, network)
+	}
+
+	return nil
+}
+
+// donationAddress returns the on-chain donation address shown on the home
+// page. The configured static address is always used if set, otherwise a new
+// address of the node's wallet is derived on every refresh, so donations never
+// share an address.
+func donationAddress(lnd lndClient, cfg *config) (string, error) {
+	if cfg.StaticDonationAddr != "" {
+		return cfg.StaticDonationAddr, nil
+	}
+
+	newAddrReq := &lnrpc.NewAddressRequest{
+		Type: lnrpc.AddressType_PUBKEY_HASH,
+	}
+	ctx, cancel := cfg.rpcContext()
+	defer cancel()
+	newAddrRes, err := lnd.NewAddress(ctx, newAddrReq)
+	if err != nil {
+		return "", fmt.Errorf("rpc NewAddress() failed: %w", err)
+	}
+
+	return newAddrRes.Address, nil
+}
+
+// checkDonationAmount validates the amount of a donation invoice against the
+// node's current inbound capacity, since there's no point in issuing an
+// invoice the donor won't be able to pay. Depending on the configured policy
+// the returned amount is either capped to the inbound capacity or left as it
+// is.
+func checkDonationAmount(policy string, amt,
+	inbound dcrutil.Amount) (dcrutil.Amount, error) {
+
+	if amt <= inbound {
+		return amt, nil
+	}
+
+	switch policy {
+	case donationPolicyCap:
+		if inbound <= 0 {
+			return 0, fmt.Errorf("no inbound capacity available " +
+				"to receive donations")
+		}
+		log.Debugf("Capping donation amount %v to inbound capacity %v",
+			amt, inbound)
+		return inbound, nil
+
+	case donationPolicyWarn:
+		log.Warnf("Donation amount %v is above the inbound capacity %v "+
+			"and will likely fail to be paid", amt, inbound)
+		return amt, nil
+
+	case donationPolicyAllow:
+		return amt, nil
+
+	default:
+		return 0, fmt.Errorf("unknown donation amount policy %q", policy)
+	}
+}
+
+// donationState is the persisted state of the donations received.
+type donationState struct {
+	// TotalAtoms is the sum of every settled donation, in atoms.
+	TotalAtoms int64 `json:"total_atoms"`
+
+	// SettleIndex is the settle index of the last invoice accounted for.
+	SettleIndex uint64 `json:"settle_index"`
+}
+
+// donationTracker keeps a running total of the donations received, updated
+// from settled donation invoices and persisted to disk so it survives
+// restarts.
+type donationTracker struct {
+	mtx   sync.Mutex
+	path  string
+	state donationState
+}
+
+// newDonationTracker creates a tracker persisting its state at path, loading
+// any previously saved state. An empty path disables persistence.
+func newDonationTracker(path string) (*donationTracker, error) {
+	t := &donationTracker{path: path}
+	if path == "" {
+		return t, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return t, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &t.state); err != nil {
+		return nil, fmt.Errorf("unable to parse %v: %v", path, err)
+	}
+
+	return t, nil
+}
+
+// total returns the sum of every settled donation.
+func (t *donationTracker) total() dcrutil.Amount {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return dcrutil.Amount(t.state.TotalAtoms)
+}
+
+// settleIndex returns the settle index of the last invoice accounted for.
+func (t *donationTracker) settleIndex() uint64 {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.state.SettleIndex
+}
+
+// add accounts for the passed invoice if it's a settled donation that wasn't
+// accounted for yet, persisting the new state.
+func (t *donationTracker) add(invoice *lnrpc.Invoice) error {
+	if invoice.State != lnrpc.Invoice_SETTLED ||
+		!isDonationMemo(invoice.Memo) {
+		return nil
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if invoice.SettleIndex <= t.state.SettleIndex {
+		return nil
+	}
+	t.state.TotalAtoms += invoice.AmtPaidAtoms
+	t.state.SettleIndex = invoice.SettleIndex
+
+	log.Infof("Received donation of %v (total %v)",
+		dcrutil.Amount(invoice.AmtPaidAtoms),
+		dcrutil.Amount(t.state.TotalAtoms))
+
+	return t.save()
+}
+
+// save writes the current state to disk. The caller must hold the mutex.
+func (t *donationTracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(t.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave us with a
+	// truncated state.
+	tmpPath := t.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, t.path)
+}
+
+// reconcileDonations accounts for every donation settled since the last
+// persisted settle index, covering the ones received while the hub wasn't
+// running.
+//
+// ListInvoices returns the invoices in the order they were added, which isn't
+// the order they were settled in, so the settled ones are collected first and
+// accounted for by settle index. Otherwise an invoice settled after a later
+// one would be skipped, its settle index being already behind.
+func (h *lightningHub) reconcileDonations() error {
+	lastIndex := h.donations.settleIndex()
+
+	var settled []*lnrpc.Invoice
+	var offset uint64
+	for {
+		req := &lnrpc.ListInvoiceRequest{
+			IndexOffset:    offset,
+			NumMaxInvoices: listInvoicesPageSize,
+		}
+		ctx, cancel := h.cfg.rpcContext()
+		res, err := h.client().ListInvoices(ctx, req)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("rpc ListInvoices() failed: %w", err)
+		}
+
+		for _, invoice := range res.Invoices {
+			if invoice.State == lnrpc.Invoice_SETTLED &&
+				invoice.SettleIndex > lastIndex {
+
+				settled = append(settled, invoice)
+			}
+		}
+
+		if len(res.Invoices) < listInvoicesPageSize {
+			break
+		}
+		offset = res.LastIndexOffset
+	}
+
+	sort.Slice(settled, func(i, j int) bool {
+		return settled[i].SettleIndex < settled[j].SettleIndex
+	})
+	for _, invoice := range settled {
+		if err := h.donations.add(invoice); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trackDonations reconciles the donations received while the hub was down
+// and then keeps the donation total up to date from the invoice settlement
+// events sent by dcrlnd, subscribing again whenever the subscription fails,
+// until the hub is stopped.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) trackDonations() {
+	// The subscription is canceled as soon as the hub is stopped, rather
+	// than being left open until dcrlnd closes it.
+	ctx, cancel := context.WithCancel(ctxb)
+	defer cancel()
+	go func() {
+		<-h.quit
+		cancel()
+	}()
+
+	for {
+		if err := h.reconcileDonations(); err != nil {
+			log.Warnf("Unable to reconcile donations: %v", err)
+		}
+
+		err := h.subscribeDonations(ctx)
+		select {
+		case <-h.quit:
+			return
+		default:
+		}
+		log.Warnf("Invoice subscription failed, retrying in %v: %v",
+			invoiceSubscriptionRetryInterval, err)
+
+		select {
+		case <-time.After(invoiceSubscriptionRetryInterval):
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// subscribeDonations subscribes to the invoices settled after the last
+// accounted donation and accounts for every donation among them. It only
+// returns once the subscription fails or the passed context is canceled.
+func (h *lightningHub) subscribeDonations(ctx context.Context) error {
+	req := &lnrpc.InvoiceSubscription{
+		SettleIndex: h.donations.settleIndex(),
+	}
+	stream, err := h.client().SubscribeInvoices(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		invoice, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		if err := h.donations.add(invoice); err != nil {
+			log.Errorf("Unable to persist donation: %v", err)
+		}
+	}
+}
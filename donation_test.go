@@ -0,0 +1,175 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestReconcileDonationsSettleOrder checks that donations settled in another
+// order than they were added are all accounted for.
+func TestReconcileDonationsSettleOrder(t *testing.T) {
+	donation := func(addIndex, settleIndex uint64,
+		amt int64) *lnrpc.Invoice {
+
+		return &lnrpc.Invoice{
+			Memo:         donationInvoiceMemo(""),
+			State:        lnrpc.Invoice_SETTLED,
+			AddIndex:     addIndex,
+			SettleIndex:  settleIndex,
+			AmtPaidAtoms: amt,
+		}
+	}
+
+	// The first invoice added was settled last.
+	lnd := &fakeLnd{
+		invoices: &lnrpc.ListInvoiceResponse{
+			Invoices: []*lnrpc.Invoice{
+				donation(1, 3, 100),
+				donation(2, 1, 200),
+				{Memo: "not a donation", AddIndex: 3},
+				donation(4, 2, 400),
+			},
+		},
+	}
+	donations, err := newDonationTracker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &lightningHub{cfg: newTestConfig(), lnd: lnd, donations: donations}
+
+	if err := h.reconcileDonations(); err != nil {
+		t.Fatalf("unable to reconcile donations: %v", err)
+	}
+	if total := donations.total(); total != 700 {
+		t.Fatalf("expected a total of 700 atoms, got %d", int64(total))
+	}
+	if index := donations.settleIndex(); index != 3 {
+		t.Fatalf("expected settle index 3, got %d", index)
+	}
+
+	// Reconciling again doesn't account for anything twice.
+	if err := h.reconcileDonations(); err != nil {
+		t.Fatalf("unable to reconcile donations: %v", err)
+	}
+	if total := donations.total(); total != 700 {
+		t.Fatalf("expected a total of 700 atoms, got %d", int64(total))
+	}
+}
+
+// TestTrackDonationsQuit checks that tracking the donations stops as soon as
+// the hub is stopped, canceling the invoice subscription or the wait before
+// subscribing again.
+func TestTrackDonationsQuit(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+	}{
+		{"subscribed", nil},
+		{"subscription failed", []error{
+			status.Error(codes.Unavailable, "down"),
+		}},
+	}
+
+	for _, test := range tests {
+		lnd := &fakeLnd{
+			errs: map[string][]error{"SubscribeInvoices": test.errs},
+		}
+		hub := newTestHub(t, newTestConfig(), lnd)
+
+		done := make(chan struct{})
+		go func() {
+			hub.trackDonations()
+			close(done)
+		}()
+		for lnd.callCount("SubscribeInvoices") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		close(hub.quit)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: donations still tracked after the hub "+
+				"stopped", test.name)
+		}
+	}
+}
+
+// TestDonationAddress checks that a new donation address is derived on every
+// refresh unless a static one is configured.
+func TestDonationAddress(t *testing.T) {
+	lnd := &fakeLnd{
+		newAddr: &lnrpc.NewAddressResponse{Address: "TsNew"},
+	}
+	cfg := newTestConfig()
+
+	for i := 1; i <= 2; i++ {
+		addr, err := donationAddress(lnd, cfg)
+		if err != nil || addr != "TsNew" {
+			t.Fatalf("expected a new address, got %q (%v)", addr,
+				err)
+		}
+		if n := lnd.callCount("NewAddress"); n != i {
+			t.Fatalf("expected %d NewAddress calls, got %d", i, n)
+		}
+	}
+
+	cfg.StaticDonationAddr = "TsStatic"
+	addr, err := donationAddress(lnd, cfg)
+	if err != nil || addr != "TsStatic" {
+		t.Fatalf("expected the static address, got %q (%v)", addr, err)
+	}
+	if n := lnd.callCount("NewAddress"); n != 2 {
+		t.Fatalf("expected no NewAddress call with a static address, "+
+			"got %d calls", n-2)
+	}
+}
+
+// TestDonationURIs checks that the donation invoice and address are
+// formatted as lightning: and decred: URIs.
+func TestDonationURIs(t *testing.T) {
+	lnd := &fakeLnd{
+		info:    testnetInfo(),
+		invoice: &lnrpc.AddInvoiceResponse{PaymentRequest: "lntdcr1abc"},
+		newAddr: &lnrpc.NewAddressResponse{Address: "TsAddr"},
+	}
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if homeCtx.DonationURI != "lightning:lntdcr1abc" {
+		t.Errorf("got invoice URI %q, want lightning:lntdcr1abc",
+			homeCtx.DonationURI)
+	}
+	if homeCtx.DonationAddrURI != "decred:TsAddr" {
+		t.Errorf("got address URI %q, want decred:TsAddr",
+			homeCtx.DonationAddrURI)
+	}
+
+	tests := []struct {
+		name   string
+		addr   string
+		amount dcrutil.Amount
+		want   string
+	}{
+		{"no address", "", 1000, ""},
+		{"no amount", "TsAddr", 0, "decred:TsAddr"},
+		{"whole amount", "TsAddr", 2e8, "decred:TsAddr?amount=2"},
+		{"atoms", "TsAddr", 1000, "decred:TsAddr?amount=0.00001"},
+	}
+	for _, test := range tests {
+		got := decredURI(test.addr, test.amount)
+		if string(got) != test.want {
+			t.Errorf("%s: got URI %q, want %q", test.name, got,
+				test.want)
+		}
+	}
+	if uri := lightningURI(""); uri != "" {
+		t.Errorf("got URI %q for no invoice, want none", uri)
+	}
+}
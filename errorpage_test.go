@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestNotFound checks that a request to a path the hub doesn't serve is
+// answered with the templated 404 page.
+func TestNotFound(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.SiteName = "Test Hub"
+	hub := newTestHub(t, cfg, &fakeLnd{})
+
+	r := mux.NewRouter()
+	r.HandleFunc("/", hub.HomePage).Methods("GET")
+	r.NotFoundHandler = http.HandlerFunc(hub.NotFound)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/no/such/page", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want 404", w.Code)
+	}
+	ct := w.Header().Get("Content-Type")
+	if !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("got content type %q, want text/html", ct)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "<title>Test Hub - Page not found</title>") {
+		t.Fatalf("404 page isn't rendered with the shared layout: %s",
+			body)
+	}
+	if !strings.Contains(body, "The page you are looking for") {
+		t.Fatalf("404 page doesn't contain the templated body: %s",
+			body)
+	}
+}
+
+// TestRenderError checks that internal errors are rendered through the 500
+// template, and that statuses without a template fall back to plain text.
+func TestRenderError(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+
+	w := httptest.NewRecorder()
+	hub.renderError(w, http.StatusInternalServerError)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Something went wrong") {
+		t.Fatalf("500 page doesn't contain the templated body: %s",
+			w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	hub.renderError(w, http.StatusBadGateway)
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("got status %d, want 502", w.Code)
+	}
+	body := strings.TrimSpace(w.Body.String())
+	if body != "502 Bad Gateway." {
+		t.Fatalf("got body %q, want plain text error", body)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+const (
+	// maxChannelEvents is the maximum number of channel events kept in
+	// memory. Older events are dropped first.
+	maxChannelEvents = 100
+)
+
+const (
+	// channelEventOpen is the type of the event recorded when a channel
+	// is first seen open.
+	channelEventOpen = "open"
+
+	// channelEventClose is the type of the event recorded when a channel
+	// is no longer open.
+	channelEventClose = "close"
+)
+
+// channelEvent is a channel open or close observed by the hub.
+type channelEvent struct {
+	Type         string
+	ChannelPoint string
+	RemotePubkey string
+	Capacity     dcrutil.Amount
+	Private      bool
+	Time         time.Time
+}
+
+// channelEventLog records the channels opened and closed by the hub's node,
+// by comparing the channels of consecutive refreshes.
+type channelEventLog struct {
+	mtx      sync.Mutex
+	seeded   bool
+	channels map[string]*lnrpc.Channel
+	events   []channelEvent
+}
+
+// newChannelEventLog creates an empty channel event log.
+func newChannelEventLog() *channelEventLog {
+	return &channelEventLog{
+		channels: make(map[string]*lnrpc.Channel),
+	}
+}
+
+// observe records an event for every channel that appeared or disappeared
+// since the last observed channel list. The first list observed only seeds
+// the log, since we don't know when those channels were opened.
+func (l *channelEventLog) observe(channels []*lnrpc.Channel) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	current := make(map[string]*lnrpc.Channel, len(channels))
+	for _, channel := range channels {
+		current[channel.ChannelPoint] = channel
+		if _, ok := l.channels[channel.ChannelPoint]; ok || !l.seeded {
+			continue
+		}
+		l.record(channelEventOpen, channel, now)
+	}
+	for chanPoint, channel := range l.channels {
+		if _, ok := current[chanPoint]; !ok {
+			l.record(channelEventClose, channel, now)
+		}
+	}
+
+	l.channels = current
+	l.seeded = true
+}
+
+// record appends a new event, dropping the oldest one if the log is full.
+// The caller must hold the mutex.
+func (l *channelEventLog) record(eventType string, channel *lnrpc.Channel,
+	t time.Time) {
+
+	l.events = append(l.events, channelEvent{
+		Type:         eventType,
+		ChannelPoint: channel.ChannelPoint,
+		RemotePubkey: channel.RemotePubkey,
+		Capacity:     dcrutil.Amount(channel.Capacity),
+		Private:      channel.Private,
+		Time:         t,
+	})
+	if len(l.events) > maxChannelEvents {
+		l.events = l.events[len(l.events)-maxChannelEvents:]
+	}
+}
+
+// recent returns the recorded events, newest first.
+func (l *channelEventLog) recent() []channelEvent {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	events := make([]channelEvent, len(l.events))
+	for i, event := range l.events {
+		events[len(l.events)-1-i] = event
+	}
+
+	return events
+}
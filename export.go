@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+)
+
+// exportedChannel is a channel in the channel list exports.
+type exportedChannel struct {
+	RemotePubkey  string `json:"remote_pubkey"`
+	ChannelPoint  string `json:"channel_point"`
+	Capacity      int64  `json:"capacity"`
+	LocalBalance  int64  `json:"local_balance"`
+	RemoteBalance int64  `json:"remote_balance"`
+	Active        bool   `json:"active"`
+}
+
+// exportChannels returns the channels the visitor is allowed to see in the
+// export format, or writes an error and returns false.
+func (h *lightningHub) exportChannels(w http.ResponseWriter,
+	r *http.Request) ([]exportedChannel, bool) {
+
+	visibility, ok := h.channelVisibility(r)
+	if !ok {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	channels := filterChannels(homeInfo.ActiveChannels, visibility)
+	exported := make([]exportedChannel, 0, len(channels))
+	for _, channel := range channels {
+		exported = append(exported, exportedChannel{
+			RemotePubkey:  channel.RemotePubkey,
+			ChannelPoint:  channel.ChannelPoint,
+			Capacity:      channel.Capacity,
+			LocalBalance:  channel.LocalBalance,
+			RemoteBalance: channel.RemoteBalance,
+			Active:        channel.Active,
+		})
+	}
+
+	return exported, true
+}
+
+// ChannelsCSV exports the channels the visitor is allowed to see as a CSV
+// file.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) ChannelsCSV(w http.ResponseWriter, r *http.Request) {
+	channels, ok := h.exportChannels(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="channels.csv"`)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"remote_pubkey", "channel_point", "capacity", "local_balance",
+		"remote_balance", "active",
+	})
+	for _, channel := range channels {
+		cw.Write([]string{
+			channel.RemotePubkey,
+			channel.ChannelPoint,
+			strconv.FormatInt(channel.Capacity, 10),
+			strconv.FormatInt(channel.LocalBalance, 10),
+			strconv.FormatInt(channel.RemoteBalance, 10),
+			strconv.FormatBool(channel.Active),
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Errorf("unable to write channels CSV: %v", err)
+	}
+}
+
+// ChannelsJSON exports the channels the visitor is allowed to see as JSON.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) ChannelsJSON(w http.ResponseWriter, r *http.Request) {
+	channels, ok := h.exportChannels(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, channels)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestChannelsExports checks that the CSV and JSON channel exports parse back
+// to the public channels of the cached context.
+func TestChannelsExports(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		ActiveChannels: []*lnrpc.Channel{{
+			RemotePubkey:  "02bb",
+			ChannelPoint:  "aa:0",
+			Capacity:      100000,
+			LocalBalance:  60000,
+			RemoteBalance: 39000,
+			Active:        true,
+		}, {
+			RemotePubkey:  "02cc",
+			ChannelPoint:  "bb:1",
+			Capacity:      50000,
+			LocalBalance:  49000,
+			RemoteBalance: 0,
+		}, {
+			RemotePubkey: "02dd",
+			ChannelPoint: "cc:0",
+			Capacity:     70000,
+			Private:      true,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+	want := []exportedChannel{{
+		RemotePubkey:  "02bb",
+		ChannelPoint:  "aa:0",
+		Capacity:      100000,
+		LocalBalance:  60000,
+		RemoteBalance: 39000,
+		Active:        true,
+	}, {
+		RemotePubkey:  "02cc",
+		ChannelPoint:  "bb:1",
+		Capacity:      50000,
+		LocalBalance:  49000,
+		RemoteBalance: 0,
+	}}
+
+	w := httptest.NewRecorder()
+	hub.ChannelsJSON(w, httptest.NewRequest("GET", "/channels.json", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("got JSON content type %q", ct)
+	}
+	var gotJSON []exportedChannel
+	if err := json.Unmarshal(w.Body.Bytes(), &gotJSON); err != nil {
+		t.Fatalf("unable to parse JSON export: %v", err)
+	}
+	if !reflect.DeepEqual(gotJSON, want) {
+		t.Errorf("got JSON channels %+v, want %+v", gotJSON, want)
+	}
+
+	w = httptest.NewRecorder()
+	hub.ChannelsCSV(w, httptest.NewRequest("GET", "/channels.csv", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("got CSV content type %q", ct)
+	}
+	disposition := w.Header().Get("Content-Disposition")
+	if disposition != `attachment; filename="channels.csv"` {
+		t.Errorf("got CSV disposition %q", disposition)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("unable to parse CSV export: %v", err)
+	}
+	if len(records) != len(want)+1 {
+		t.Fatalf("got %d CSV records, want a header and %d channels",
+			len(records), len(want))
+	}
+	var gotCSV []exportedChannel
+	for _, record := range records[1:] {
+		parseInt := func(s string) int64 {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				t.Fatalf("invalid CSV amount %q: %v", s, err)
+			}
+			return n
+		}
+		active, err := strconv.ParseBool(record[5])
+		if err != nil {
+			t.Fatalf("invalid CSV active flag %q: %v", record[5], err)
+		}
+		gotCSV = append(gotCSV, exportedChannel{
+			RemotePubkey:  record[0],
+			ChannelPoint:  record[1],
+			Capacity:      parseInt(record[2]),
+			LocalBalance:  parseInt(record[3]),
+			RemoteBalance: parseInt(record[4]),
+			Active:        active,
+		})
+	}
+	if !reflect.DeepEqual(gotCSV, want) {
+		t.Errorf("got CSV channels %+v, want %+v", gotCSV, want)
+	}
+}
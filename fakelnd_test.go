@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/walletrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLnd is an lndClient serving canned responses, so the hub can be tested
+// without a running dcrlnd. A nil response is served as an empty one.
+type fakeLnd struct {
+	mtx sync.Mutex
+
+	info      *lnrpc.GetInfoResponse
+	nodeInfo  *lnrpc.NodeInfo
+	channels  *lnrpc.ListChannelsResponse
+	pending   *lnrpc.PendingChannelsResponse
+	closed    *lnrpc.ClosedChannelsResponse
+	feeReport *lnrpc.FeeReportResponse
+	balance   *lnrpc.WalletBalanceResponse
+	utxos     *lnrpc.ListUnspentResponse
+	newAddr   *lnrpc.NewAddressResponse
+	invoice   *lnrpc.AddInvoiceResponse
+	lookup    *lnrpc.Invoice
+	invoices  *lnrpc.ListInvoiceResponse
+	peers     *lnrpc.ListPeersResponse
+	chanPoint *lnrpc.ChannelPoint
+	routes    *lnrpc.QueryRoutesResponse
+	payment   *lnrpc.SendResponse
+
+	// errs are the errors returned by the RPCs, by name. Each call pops
+	// the first error of its RPC, except the last one which is returned
+	// by every later call, so {err} always fails and {err, err, nil}
+	// fails twice before succeeding.
+	errs map[string][]error
+
+	// delay is how long every RPC takes to respond, unless its context
+	// is done first.
+	delay time.Duration
+
+	// calls counts the calls of each RPC, and reqs holds the last request
+	// of each of them.
+	calls map[string]int
+	reqs  map[string]interface{}
+}
+
+// A compile-time check that fakeLnd implements the lndClient interface.
+var _ lndClient = (*fakeLnd)(nil)
+
+// call records a call of the named RPC, waits for the configured delay and
+// returns the error the RPC must fail with, if any.
+func (f *fakeLnd) call(ctx context.Context, name string,
+	req interface{}) error {
+
+	f.mtx.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+		f.reqs = make(map[string]interface{})
+	}
+	f.calls[name]++
+	f.reqs[name] = req
+
+	var err error
+	if errs := f.errs[name]; len(errs) > 0 {
+		err = errs[0]
+		if len(errs) > 1 {
+			f.errs[name] = errs[1:]
+		}
+	}
+	delay := f.delay
+	f.mtx.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			// Like grpc, report the context error as a status.
+			code := codes.Canceled
+			if ctx.Err() == context.DeadlineExceeded {
+				code = codes.DeadlineExceeded
+			}
+			return status.Error(code, ctx.Err().Error())
+		}
+	}
+
+	return err
+}
+
+// callCount returns how many times the named RPC was called.
+func (f *fakeLnd) callCount(name string) int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.calls[name]
+}
+
+// lastRequest returns the last request of the named RPC, nil if it wasn't
+// called.
+func (f *fakeLnd) lastRequest(name string) interface{} {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.reqs[name]
+}
+
+func (f *fakeLnd) GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest,
+	opts ...grpc.CallOption) (*lnrpc.GetInfoResponse, error) {
+
+	if err := f.call(ctx, "GetInfo", in); err != nil {
+		return nil, err
+	}
+	if f.info == nil {
+		return &lnrpc.GetInfoResponse{}, nil
+	}
+	return f.info, nil
+}
+
+func (f *fakeLnd) GetNodeInfo(ctx context.Context, in *lnrpc.NodeInfoRequest,
+	opts ...grpc.CallOption) (*lnrpc.NodeInfo, error) {
+
+	if err := f.call(ctx, "GetNodeInfo", in); err != nil {
+		return nil, err
+	}
+	if f.nodeInfo == nil {
+		return &lnrpc.NodeInfo{}, nil
+	}
+	return f.nodeInfo, nil
+}
+
+func (f *fakeLnd) ListChannels(ctx context.Context,
+	in *lnrpc.ListChannelsRequest,
+	opts ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error) {
+
+	if err := f.call(ctx, "ListChannels", in); err != nil {
+		return nil, err
+	}
+	if f.channels == nil {
+		return &lnrpc.ListChannelsResponse{}, nil
+	}
+	return f.channels, nil
+}
+
+func (f *fakeLnd) PendingChannels(ctx context.Context,
+	in *lnrpc.PendingChannelsRequest,
+	opts ...grpc.CallOption) (*lnrpc.PendingChannelsResponse, error) {
+
+	if err := f.call(ctx, "PendingChannels", in); err != nil {
+		return nil, err
+	}
+	if f.pending == nil {
+		return &lnrpc.PendingChannelsResponse{}, nil
+	}
+	return f.pending, nil
+}
+
+func (f *fakeLnd) ClosedChannels(ctx context.Context,
+	in *lnrpc.ClosedChannelsRequest,
+	opts ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error) {
+
+	if err := f.call(ctx, "ClosedChannels", in); err != nil {
+		return nil, err
+	}
+	if f.closed == nil {
+		return &lnrpc.ClosedChannelsResponse{}, nil
+	}
+	return f.closed, nil
+}
+
+func (f *fakeLnd) FeeReport(ctx context.Context, in *lnrpc.FeeReportRequest,
+	opts ...grpc.CallOption) (*lnrpc.FeeReportResponse, error) {
+
+	if err := f.call(ctx, "FeeReport", in); err != nil {
+		return nil, err
+	}
+	if f.feeReport == nil {
+		return &lnrpc.FeeReportResponse{}, nil
+	}
+	return f.feeReport, nil
+}
+
+func (f *fakeLnd) WalletBalance(ctx context.Context,
+	in *lnrpc.WalletBalanceRequest,
+	opts ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error) {
+
+	if err := f.call(ctx, "WalletBalance", in); err != nil {
+		return nil, err
+	}
+	if f.balance == nil {
+		return &lnrpc.WalletBalanceResponse{}, nil
+	}
+	return f.balance, nil
+}
+
+func (f *fakeLnd) ListUnspent(ctx context.Context,
+	in *lnrpc.ListUnspentRequest,
+	opts ...grpc.CallOption) (*lnrpc.ListUnspentResponse, error) {
+
+	if err := f.call(ctx, "ListUnspent", in); err != nil {
+		return nil, err
+	}
+	if f.utxos == nil {
+		return &lnrpc.ListUnspentResponse{}, nil
+	}
+	return f.utxos, nil
+}
+
+func (f *fakeLnd) NewAddress(ctx context.Context, in *lnrpc.NewAddressRequest,
+	opts ...grpc.CallOption) (*lnrpc.NewAddressResponse, error) {
+
+	if err := f.call(ctx, "NewAddress", in); err != nil {
+		return nil, err
+	}
+	if f.newAddr == nil {
+		return &lnrpc.NewAddressResponse{}, nil
+	}
+	return f.newAddr, nil
+}
+
+func (f *fakeLnd) AddInvoice(ctx context.Context, in *lnrpc.Invoice,
+	opts ...grpc.CallOption) (*lnrpc.AddInvoiceResponse, error) {
+
+	if err := f.call(ctx, "AddInvoice", in); err != nil {
+		return nil, err
+	}
+	if f.invoice == nil {
+		return &lnrpc.AddInvoiceResponse{}, nil
+	}
+	return f.invoice, nil
+}
+
+func (f *fakeLnd) LookupInvoice(ctx context.Context, in *lnrpc.PaymentHash,
+	opts ...grpc.CallOption) (*lnrpc.Invoice, error) {
+
+	if err := f.call(ctx, "LookupInvoice", in); err != nil {
+		return nil, err
+	}
+	if f.lookup == nil {
+		return &lnrpc.Invoice{}, nil
+	}
+	return f.lookup, nil
+}
+
+func (f *fakeLnd) ListInvoices(ctx context.Context,
+	in *lnrpc.ListInvoiceRequest,
+	opts ...grpc.CallOption) (*lnrpc.ListInvoiceResponse, error) {
+
+	if err := f.call(ctx, "ListInvoices", in); err != nil {
+		return nil, err
+	}
+	if f.invoices == nil {
+		return &lnrpc.ListInvoiceResponse{}, nil
+	}
+	return f.invoices, nil
+}
+
+func (f *fakeLnd) SubscribeInvoices(ctx context.Context,
+	in *lnrpc.InvoiceSubscription,
+	opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeInvoicesClient,
+	error) {
+
+	if err := f.call(ctx, "SubscribeInvoices", in); err != nil {
+		return nil, err
+	}
+	return &fakeInvoiceStream{ctx: ctx}, nil
+}
+
+// fakeInvoiceStream is an invoice subscription which never sends an invoice,
+// and fails once its context is canceled like grpc's streams do.
+type fakeInvoiceStream struct {
+	grpc.ClientStream
+	ctx context.Context
+}
+
+func (s *fakeInvoiceStream) Recv() (*lnrpc.Invoice, error) {
+	<-s.ctx.Done()
+	return nil, status.Error(codes.Canceled, s.ctx.Err().Error())
+}
+
+func (f *fakeLnd) ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest,
+	opts ...grpc.CallOption) (*lnrpc.ListPeersResponse, error) {
+
+	if err := f.call(ctx, "ListPeers", in); err != nil {
+		return nil, err
+	}
+	if f.peers == nil {
+		return &lnrpc.ListPeersResponse{}, nil
+	}
+	return f.peers, nil
+}
+
+func (f *fakeLnd) ConnectPeer(ctx context.Context,
+	in *lnrpc.ConnectPeerRequest,
+	opts ...grpc.CallOption) (*lnrpc.ConnectPeerResponse, error) {
+
+	if err := f.call(ctx, "ConnectPeer", in); err != nil {
+		return nil, err
+	}
+	return &lnrpc.ConnectPeerResponse{}, nil
+}
+
+func (f *fakeLnd) OpenChannelSync(ctx context.Context,
+	in *lnrpc.OpenChannelRequest,
+	opts ...grpc.CallOption) (*lnrpc.ChannelPoint, error) {
+
+	if err := f.call(ctx, "OpenChannelSync", in); err != nil {
+		return nil, err
+	}
+	if f.chanPoint == nil {
+		return &lnrpc.ChannelPoint{}, nil
+	}
+	return f.chanPoint, nil
+}
+
+func (f *fakeLnd) QueryRoutes(ctx context.Context,
+	in *lnrpc.QueryRoutesRequest,
+	opts ...grpc.CallOption) (*lnrpc.QueryRoutesResponse, error) {
+
+	if err := f.call(ctx, "QueryRoutes", in); err != nil {
+		return nil, err
+	}
+	if f.routes == nil {
+		return &lnrpc.QueryRoutesResponse{}, nil
+	}
+	return f.routes, nil
+}
+
+func (f *fakeLnd) SendToRouteSync(ctx context.Context,
+	in *lnrpc.SendToRouteRequest,
+	opts ...grpc.CallOption) (*lnrpc.SendResponse, error) {
+
+	if err := f.call(ctx, "SendToRouteSync", in); err != nil {
+		return nil, err
+	}
+	if f.payment == nil {
+		return &lnrpc.SendResponse{}, nil
+	}
+	return f.payment, nil
+}
+
+// BumpFee makes fakeLnd a walletKitClient as well.
+func (f *fakeLnd) BumpFee(ctx context.Context, in *walletrpc.BumpFeeRequest,
+	opts ...grpc.CallOption) (*walletrpc.BumpFeeResponse, error) {
+
+	if err := f.call(ctx, "BumpFee", in); err != nil {
+		return nil, err
+	}
+	return &walletrpc.BumpFeeResponse{}, nil
+}
+
+// newTestConfig returns a config suitable for tests, on testnet and without
+// retries so failures surface immediately.
+func newTestConfig() *config {
+	return &config{
+		Network:              "testnet",
+		RPCTimeout:           time.Second,
+		MaxStaleness:         time.Minute,
+		MinDcrlndVersion:     defaultMinDcrlndVersion,
+		DonationAmountPolicy: donationPolicyAllow,
+		DonationAmount:       1000,
+		DonationExpiry:       time.Hour,
+		MinChannelSize:       20000,
+		MaxChannelSize:       100000000,
+	}
+}
+
+// testnetInfo returns a GetInfo response of a testnet node.
+func testnetInfo() *lnrpc.GetInfoResponse {
+	return &lnrpc.GetInfoResponse{
+		IdentityPubkey: "02aa",
+		Alias:          "hub",
+		Version:        "0.2.1-beta commit=v0.2.1-beta",
+		Chains: []*lnrpc.Chain{
+			{Chain: "decred", Network: "testnet"},
+		},
+	}
+}
+
+// newTestHub returns a hub served by the passed fake node, rendering the
+// templates of the static directory.
+func newTestHub(t *testing.T, cfg *config, lnd lndClient) *lightningHub {
+	t.Helper()
+
+	tpl, err := template.New("dcrlnhub").ParseGlob("static/*.html")
+	if err != nil {
+		t.Fatalf("unable to parse templates: %v", err)
+	}
+
+	return &lightningHub{
+		cfg:         cfg,
+		lnd:         lnd,
+		template:    tpl,
+		sanitizer:   newSanitizer(defaultSanitizeTags, defaultSanitizeAttrs),
+		donations:   &donationTracker{},
+		history:     &capacityHistory{size: 10},
+		events:      newChannelEventLog(),
+		broadcaster: newStatsBroadcaster(),
+		ready:       make(chan struct{}),
+		quit:        make(chan struct{}),
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// featuredPeerConfig is the configuration of a peer the hub recommends to
+// its visitors.
+type featuredPeerConfig struct {
+	Addr        *lnrpc.LightningAddress
+	Description string
+}
+
+// featuredPeersConfig holds the options of the featured peers, which are set
+// in the [featured_peer] section of the config file, or with the
+// --featured_peer.* flags. The descriptions are optional, but when given,
+// there must be one per peer, in the same order, possibly empty:
+//
+//	[featured_peer]
+//	featured_peer.uri=02aa...@node.example.com:9735
+//	featured_peer.description=Well connected <b>routing</b> node
+//	featured_peer.uri=03bb...@198.51.100.7:9735
+//	featured_peer.description=
+type featuredPeersConfig struct {
+	URIs         []string `long:"uri" env:"DCRLNHUB_FEATURED_PEER_URI" env-delim:";" description:"URI of a peer recommended to visitors on the home page, as pubkey@host, may be specified multiple times"`
+	Descriptions []string `long:"description" env:"DCRLNHUB_FEATURED_PEER_DESCRIPTION" env-delim:";" description:"description of the featured peer, which may contain HTML, once per featured peer URI if any"`
+}
+
+// parseFeaturedPeers zips the configured options of the featured peers into
+// one config per peer, validating their URIs.
+func parseFeaturedPeers(peers featuredPeersConfig) ([]featuredPeerConfig,
+	error) {
+
+	if len(peers.Descriptions) != 0 &&
+		len(peers.Descriptions) != len(peers.URIs) {
+
+		return nil, fmt.Errorf("got %d featured peer URIs and %d "+
+			"descriptions, every featured peer needs one if any is "+
+			"set", len(peers.URIs), len(peers.Descriptions))
+	}
+
+	configs := make([]featuredPeerConfig, 0, len(peers.URIs))
+	pubkeys := make(map[string]bool)
+	for i, uri := range peers.URIs {
+		addr, err := parseNodeAddr(strings.TrimSpace(uri))
+		if err != nil {
+			return nil, fmt.Errorf("invalid featured peer %q: %v",
+				uri, err)
+		}
+		if pubkeys[addr.Pubkey] {
+			return nil, fmt.Errorf("duplicate featured peer %v",
+				addr.Pubkey)
+		}
+		pubkeys[addr.Pubkey] = true
+
+		var description string
+		if len(peers.Descriptions) != 0 {
+			description = strings.TrimSpace(peers.Descriptions[i])
+		}
+		configs = append(configs, featuredPeerConfig{
+			Addr:        addr,
+			Description: description,
+		})
+	}
+
+	return configs, nil
+}
+
+// featuredPeer is a peer recommended to the visitors on the home page.
+type featuredPeer struct {
+	// URI is the address of the peer, in the pubkey@host format.
+	URI string
+
+	// Description is the operator provided description of the peer,
+	// sanitized so it's safe to render.
+	Description template.HTML
+
+	// HasChannel is true if the hub already has a channel with the peer.
+	HasChannel bool
+}
+
+// featuredPeers returns the configured featured peers to show along with the
+// passed channels of the hub.
+func (h *lightningHub) featuredPeers(channels []*lnrpc.Channel) []featuredPeer {
+	if len(h.cfg.featuredPeers) == 0 {
+		return nil
+	}
+
+	withChannel := make(map[string]bool)
+	for _, channel := range channels {
+		withChannel[channel.RemotePubkey] = true
+	}
+
+	peers := make([]featuredPeer, 0, len(h.cfg.featuredPeers))
+	for _, peer := range h.cfg.featuredPeers {
+		peers = append(peers, featuredPeer{
+			URI:         peer.Addr.Pubkey + "@" + peer.Addr.Host,
+			Description: h.sanitizeHTML(peer.Description),
+			HasChannel:  withChannel[peer.Addr.Pubkey],
+		})
+	}
+
+	return peers
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	flags "github.com/jessevdk/go-flags"
+)
+
+const (
+	// featuredPubkey1 and featuredPubkey2 are valid node pubkeys, the
+	// ones of the secp256k1 generator and of its double.
+	featuredPubkey1 = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28" +
+		"d959f2815b16f81798"
+	featuredPubkey2 = "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3c" +
+		"a7abac09b95c709ee5"
+)
+
+// TestFeaturedPeersConfigIni checks that the featured peers are read from
+// the [featured_peer] section of the config file.
+func TestFeaturedPeersConfigIni(t *testing.T) {
+	ini := `
+[featured_peer]
+featured_peer.uri=` + featuredPubkey1 + `@node.example.com:9735
+featured_peer.description=Well connected <b>routing</b> node, in Europe
+featured_peer.uri=` + featuredPubkey2 + `@198.51.100.7:9735
+featured_peer.description=
+`
+
+	var cfg config
+	parser := flags.NewParser(&cfg, flags.Default)
+	err := flags.NewIniParser(parser).Parse(strings.NewReader(ini))
+	if err != nil {
+		t.Fatalf("unable to parse config: %v", err)
+	}
+
+	peers, err := parseFeaturedPeers(cfg.FeaturedPeers)
+	if err != nil {
+		t.Fatalf("unable to parse featured peers: %v", err)
+	}
+	want := []featuredPeerConfig{{
+		Addr: &lnrpc.LightningAddress{
+			Pubkey: featuredPubkey1,
+			Host:   "node.example.com:9735",
+		},
+		Description: "Well connected <b>routing</b> node, in Europe",
+	}, {
+		Addr: &lnrpc.LightningAddress{
+			Pubkey: featuredPubkey2,
+			Host:   "198.51.100.7:9735",
+		},
+	}}
+	if !reflect.DeepEqual(peers, want) {
+		t.Fatalf("got featured peers %+v, want %+v", peers, want)
+	}
+}
+
+// TestParseFeaturedPeers checks that the featured peers are validated.
+func TestParseFeaturedPeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		peers   featuredPeersConfig
+		want    int
+		wantErr bool
+	}{{
+		name: "none",
+	}, {
+		name: "without descriptions",
+		peers: featuredPeersConfig{
+			URIs: []string{
+				featuredPubkey1 + "@node.example.com:9735",
+				featuredPubkey2 + "@198.51.100.7:9735",
+			},
+		},
+		want: 2,
+	}, {
+		name: "duplicate",
+		peers: featuredPeersConfig{
+			URIs: []string{
+				featuredPubkey1 + "@node.example.com:9735",
+				featuredPubkey1 + "@198.51.100.7:9735",
+			},
+		},
+		wantErr: true,
+	}, {
+		name: "missing host",
+		peers: featuredPeersConfig{
+			URIs: []string{featuredPubkey1},
+		},
+		wantErr: true,
+	}, {
+		name: "invalid pubkey",
+		peers: featuredPeersConfig{
+			URIs: []string{"02aa@node.example.com:9735"},
+		},
+		wantErr: true,
+	}, {
+		name: "missing description",
+		peers: featuredPeersConfig{
+			URIs: []string{
+				featuredPubkey1 + "@node.example.com:9735",
+				featuredPubkey2 + "@198.51.100.7:9735",
+			},
+			Descriptions: []string{"routing node"},
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		peers, err := parseFeaturedPeers(test.peers)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if len(peers) != test.want {
+			t.Errorf("%s: got %d featured peers, want %d",
+				test.name, len(peers), test.want)
+		}
+	}
+}
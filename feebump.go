@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/walletrpc"
+)
+
+// bumpFeeResponse is the response of the fee bump endpoint.
+type bumpFeeResponse struct {
+	ChannelPoint string `json:"channel_point"`
+	Outpoint     string `json:"outpoint"`
+}
+
+// parseOutpoint parses an outpoint in the txid:index format.
+func parseOutpoint(s string) (*lnrpc.OutPoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || len(parts[0]) != 64 {
+		return nil, fmt.Errorf("invalid outpoint %q, expected "+
+			"txid:index", s)
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid outpoint index %q", parts[1])
+	}
+
+	return &lnrpc.OutPoint{
+		TxidStr:     parts[0],
+		OutputIndex: uint32(index),
+	}, nil
+}
+
+// pendingOpenChannels returns the channels of the hub's node waiting for
+// their funding transaction to confirm.
+func (h *lightningHub) pendingOpenChannels() (
+	[]*lnrpc.PendingChannelsResponse_PendingOpenChannel, error) {
+
+	pendingReq := &lnrpc.PendingChannelsRequest{}
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	pendingRes, err := h.client().PendingChannels(ctx, pendingReq)
+	if err != nil {
+		return nil, fmt.Errorf("rpc PendingChannels() failed: %w", err)
+	}
+
+	return pendingRes.PendingOpenChannels, nil
+}
+
+// pendingOpenChannel returns the pending open channel funded by the
+// transaction with the passed txid, if any.
+func (h *lightningHub) pendingOpenChannel(txid string) (
+	*lnrpc.PendingChannelsResponse_PendingChannel, error) {
+
+	opening, err := h.pendingOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pending := range opening {
+		if strings.HasPrefix(pending.Channel.ChannelPoint, txid+":") {
+			return pending.Channel, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// bumpFundingFee bumps the fee of the funding transaction of the pending
+// channel with the passed channel point as requested.
+func (h *lightningHub) bumpFundingFee(chanPoint string,
+	req *walletrpc.BumpFeeRequest) error {
+
+	log.Infof("Bumping fee of funding tx of channel %v by spending %v:%d "+
+		"(atoms_per_byte=%d, target_conf=%d)", chanPoint,
+		req.Outpoint.TxidStr, req.Outpoint.OutputIndex,
+		req.AtomsPerByte, req.TargetConf)
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	if _, err := h.walletKit().BumpFee(ctx, req); err != nil {
+		return fmt.Errorf("rpc BumpFee() failed: %w", err)
+	}
+
+	return nil
+}
+
+// BumpFee bumps the fee of a stuck channel funding transaction by spending
+// one of its outputs we control (usually the change) with a higher fee
+// (CPFP). The outpoint form value selects the output to spend and must
+// belong to the funding transaction of a pending open channel, while either
+// atoms_per_byte or target_conf selects the new fee rate.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) BumpFee(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	if !h.cfg.EnableFeeBump {
+		http.Error(w, "fee bumping is disabled", http.StatusForbidden)
+		return
+	}
+
+	outpoint, err := parseOutpoint(r.FormValue("outpoint"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &walletrpc.BumpFeeRequest{Outpoint: outpoint}
+	if v := r.FormValue("atoms_per_byte"); v != "" {
+		rate, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || rate == 0 {
+			http.Error(w, "invalid atoms_per_byte", http.StatusBadRequest)
+			return
+		}
+		req.AtomsPerByte = uint32(rate)
+	}
+	if v := r.FormValue("target_conf"); v != "" {
+		conf, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || conf == 0 {
+			http.Error(w, "invalid target_conf", http.StatusBadRequest)
+			return
+		}
+		req.TargetConf = uint32(conf)
+	}
+	if (req.AtomsPerByte == 0) == (req.TargetConf == 0) {
+		http.Error(w, "exactly one of atoms_per_byte or target_conf "+
+			"must be specified", http.StatusBadRequest)
+		return
+	}
+
+	// Only funding transactions of channels still being opened can be
+	// bumped through the hub.
+	pending, err := h.pendingOpenChannel(outpoint.TxidStr)
+	if err != nil {
+		log.Errorf("unable to fetch pending channels: %v", err)
+		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		return
+	}
+	if pending == nil {
+		http.Error(w, "outpoint doesn't belong to the funding "+
+			"transaction of a pending channel", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.bumpFundingFee(pending.ChannelPoint, req); err != nil {
+		log.Errorf("unable to bump fee of %v: %v",
+			r.FormValue("outpoint"), err)
+		http.Error(w, fmt.Sprintf("unable to bump fee: %v", err),
+			http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, &bumpFeeResponse{
+		ChannelPoint: pending.ChannelPoint,
+		Outpoint:     r.FormValue("outpoint"),
+	})
+}
+
+// stuckOpens returns the channel points of the passed pending opens which
+// have been waiting for longer than the passed duration as of now. since
+// holds when each pending open was first seen waiting: it's updated with the
+// new ones, forgets the ones not pending anymore and restarts the wait of the
+// returned ones, so each of them is bumped again only if it's still stuck
+// after another full wait.
+func stuckOpens(since map[string]time.Time,
+	opening []*lnrpc.PendingChannelsResponse_PendingOpenChannel,
+	wait time.Duration, now time.Time) []string {
+
+	pending := make(map[string]struct{}, len(opening))
+	var stuck []string
+	for _, open := range opening {
+		chanPoint := open.Channel.ChannelPoint
+		pending[chanPoint] = struct{}{}
+
+		seen, ok := since[chanPoint]
+		switch {
+		case !ok:
+			since[chanPoint] = now
+		case now.Sub(seen) >= wait:
+			stuck = append(stuck, chanPoint)
+			since[chanPoint] = now
+		}
+	}
+	for chanPoint := range since {
+		if _, ok := pending[chanPoint]; !ok {
+			delete(since, chanPoint)
+		}
+	}
+
+	return stuck
+}
+
+// fundingChange returns an unconfirmed output of the transaction with the
+// passed txid controlled by the node's wallet, which is the change of the
+// funding transactions it published. It returns nil if there's none, e.g.
+// for the channels opened by the remote node.
+func (h *lightningHub) fundingChange(txid string) (*lnrpc.OutPoint, error) {
+	// Both bounds at 0 lists the unconfirmed outputs only.
+	unspentReq := &lnrpc.ListUnspentRequest{MinConfs: 0, MaxConfs: 0}
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	unspentRes, err := h.client().ListUnspent(ctx, unspentReq)
+	if err != nil {
+		return nil, fmt.Errorf("rpc ListUnspent() failed: %w", err)
+	}
+
+	for _, utxo := range unspentRes.Utxos {
+		if utxo.Outpoint != nil && utxo.Outpoint.TxidStr == txid {
+			return utxo.Outpoint, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// bumpStuckOpens bumps the fee of the funding transactions of the channels
+// being opened by the hub's node, targeting fee_bump_target_conf, once they
+// have been waiting for fee_bump_after without confirming. The wait is
+// measured from when the hub first saw the channel pending, so it restarts
+// with the hub.
+func (h *lightningHub) bumpStuckOpens(since map[string]time.Time,
+	now time.Time) {
+
+	opening, err := h.pendingOpenChannels()
+	if err != nil {
+		log.Warnf("Unable to check for stuck channel opens: %v", err)
+		return
+	}
+
+	for _, chanPoint := range stuckOpens(since, opening,
+		h.cfg.FeeBumpAfter, now) {
+
+		txid := strings.Split(chanPoint, ":")[0]
+		outpoint, err := h.fundingChange(txid)
+		if err != nil {
+			log.Warnf("Unable to find the change of the funding tx "+
+				"of channel %v: %v", chanPoint, err)
+			continue
+		}
+		if outpoint == nil {
+			log.Debugf("Funding tx of channel %v has no output of "+
+				"ours to bump its fee with", chanPoint)
+			continue
+		}
+
+		req := &walletrpc.BumpFeeRequest{
+			Outpoint:   outpoint,
+			TargetConf: h.cfg.FeeBumpTargetConf,
+		}
+		if err := h.bumpFundingFee(chanPoint, req); err != nil {
+			log.Errorf("Unable to bump fee of funding tx of "+
+				"channel %v: %v", chanPoint, err)
+		}
+	}
+}
+
+// autoBumpFees checks for stuck channel opens to bump the fee of on every
+// refresh interval until the hub is stopped.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) autoBumpFees() {
+	ticker := time.NewTicker(h.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	since := make(map[string]time.Time)
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.quit:
+			return
+		}
+
+		h.bumpStuckOpens(since, time.Now())
+	}
+}
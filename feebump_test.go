@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/walletrpc"
+	"google.golang.org/grpc"
+)
+
+var (
+	testFundingTxid = strings.Repeat("11", 32)
+	testOtherTxid   = strings.Repeat("22", 32)
+)
+
+// useFakeWalletKit makes the hub reach the WalletKit sub-server through the
+// passed fake node, until the returned function is called.
+func useFakeWalletKit(lnd *fakeLnd) func() {
+	origNewWalletKitClient := newWalletKitClient
+	newWalletKitClient = func(conn *grpc.ClientConn) walletKitClient {
+		return lnd
+	}
+	return func() {
+		newWalletKitClient = origNewWalletKitClient
+	}
+}
+
+// pendingOpen returns the pending channels of a node opening a channel with
+// the passed channel point.
+func pendingOpen(chanPoint string) *lnrpc.PendingChannelsResponse {
+	return &lnrpc.PendingChannelsResponse{
+		PendingOpenChannels: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{{
+			Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+				ChannelPoint: chanPoint,
+			},
+		}},
+	}
+}
+
+// TestBumpFee checks that the fee bump endpoint only bumps the funding
+// transactions of pending opens.
+func TestBumpFee(t *testing.T) {
+	tests := []struct {
+		name     string
+		outpoint string
+		code     int
+		bumped   bool
+	}{{
+		name:     "pending open",
+		outpoint: testFundingTxid + ":1",
+		code:     http.StatusOK,
+		bumped:   true,
+	}, {
+		name:     "not a pending open",
+		outpoint: testOtherTxid + ":1",
+		code:     http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lnd := &fakeLnd{pending: pendingOpen(testFundingTxid + ":0")}
+			defer useFakeWalletKit(lnd)()
+			cfg := newTestConfig()
+			cfg.AdminToken = "token"
+			cfg.EnableFeeBump = true
+			h := &lightningHub{cfg: cfg, lnd: lnd}
+
+			form := url.Values{
+				"outpoint":    {test.outpoint},
+				"target_conf": {"2"},
+			}
+			r := httptest.NewRequest("POST", "/admin/bumpfee",
+				strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type",
+				"application/x-www-form-urlencoded")
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			h.BumpFee(w, r)
+
+			if w.Code != test.code {
+				t.Fatalf("expected %d, got %d: %s", test.code,
+					w.Code, w.Body)
+			}
+			if !test.bumped {
+				if n := lnd.callCount("BumpFee"); n != 0 {
+					t.Fatalf("expected no fee bump, got %d", n)
+				}
+				return
+			}
+
+			req := lnd.lastRequest("BumpFee").(*walletrpc.BumpFeeRequest)
+			if req.Outpoint.TxidStr != testFundingTxid ||
+				req.Outpoint.OutputIndex != 1 || req.TargetConf != 2 {
+
+				t.Fatalf("unexpected fee bump %+v", req)
+			}
+		})
+	}
+}
+
+// TestBumpStuckOpens checks that the funding transaction of a pending open is
+// bumped once it waited for fee_bump_after, by spending its change.
+func TestBumpStuckOpens(t *testing.T) {
+	lnd := &fakeLnd{
+		pending: pendingOpen(testFundingTxid + ":0"),
+		utxos: &lnrpc.ListUnspentResponse{
+			Utxos: []*lnrpc.Utxo{{
+				Outpoint: &lnrpc.OutPoint{TxidStr: testOtherTxid},
+			}, {
+				Outpoint: &lnrpc.OutPoint{
+					TxidStr:     testFundingTxid,
+					OutputIndex: 1,
+				},
+			}},
+		},
+	}
+	defer useFakeWalletKit(lnd)()
+	cfg := newTestConfig()
+	cfg.EnableFeeBump = true
+	cfg.FeeBumpAfter = time.Hour
+	cfg.FeeBumpTargetConf = 3
+	h := &lightningHub{cfg: cfg, lnd: lnd}
+
+	since := make(map[string]time.Time)
+	now := time.Now()
+	h.bumpStuckOpens(since, now)
+	h.bumpStuckOpens(since, now.Add(time.Hour/2))
+	if n := lnd.callCount("BumpFee"); n != 0 {
+		t.Fatalf("expected no fee bump before fee_bump_after, got %d",
+			n)
+	}
+
+	h.bumpStuckOpens(since, now.Add(time.Hour))
+	if n := lnd.callCount("BumpFee"); n != 1 {
+		t.Fatalf("expected 1 fee bump, got %d", n)
+	}
+	req := lnd.lastRequest("BumpFee").(*walletrpc.BumpFeeRequest)
+	if req.Outpoint.TxidStr != testFundingTxid ||
+		req.Outpoint.OutputIndex != 1 || req.TargetConf != 3 {
+
+		t.Fatalf("unexpected fee bump %+v", req)
+	}
+
+	// The wait restarts after a bump.
+	h.bumpStuckOpens(since, now.Add(3*time.Hour/2))
+	if n := lnd.callCount("BumpFee"); n != 1 {
+		t.Fatalf("expected no new fee bump, got %d", n-1)
+	}
+
+	// Confirmed channels are forgotten.
+	lnd.pending = nil
+	h.bumpStuckOpens(since, now.Add(2*time.Hour))
+	if len(since) != 0 {
+		t.Fatalf("expected confirmed opens to be forgotten, got %v",
+			since)
+	}
+}
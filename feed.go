@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+const (
+	// feedCacheDuration is how long a generated feed is served before
+	// being generated again.
+	feedCacheDuration = 5 * time.Minute
+
+	// atomNamespace is the XML namespace of Atom feeds.
+	atomNamespace = "http://www.w3.org/2005/Atom"
+)
+
+// atomFeed is an Atom feed, as defined by RFC 4287.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomLink is a link of an Atom feed.
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// atomAuthor is the author of an Atom feed.
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// atomEntry is an entry of an Atom feed.
+type atomEntry struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+	Summary string `xml:"summary"`
+}
+
+// feedCache holds the last generated feed.
+type feedCache struct {
+	mtx       sync.Mutex
+	feed      []byte
+	generated time.Time
+}
+
+// closeTypeDescription returns a human readable description of how a channel
+// was closed.
+func closeTypeDescription(closeType lnrpc.ChannelCloseSummary_ClosureType) string {
+	switch closeType {
+	case lnrpc.ChannelCloseSummary_COOPERATIVE_CLOSE:
+		return "cooperatively closed"
+	case lnrpc.ChannelCloseSummary_LOCAL_FORCE_CLOSE,
+		lnrpc.ChannelCloseSummary_REMOTE_FORCE_CLOSE:
+		return "force closed"
+	case lnrpc.ChannelCloseSummary_BREACH_CLOSE:
+		return "closed by a breach"
+	default:
+		return "closed"
+	}
+}
+
+// buildFeed builds the Atom feed of the recent channel opens and closes. The
+// opens come from the channel event log, while the closes are matched
+// against ClosedChannels to describe how each channel was closed. Closes that
+// happened before the hub started aren't part of the feed since we don't know
+// when they happened. Private channels are never part of the feed.
+func (h *lightningHub) buildFeed(r *http.Request) ([]byte, error) {
+	closedReq := &lnrpc.ClosedChannelsRequest{}
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	closedRes, err := h.client().ClosedChannels(ctx, closedReq)
+	if err != nil {
+		return nil, fmt.Errorf("rpc ClosedChannels() failed: %w", err)
+	}
+	closeTypes := make(map[string]lnrpc.ChannelCloseSummary_ClosureType)
+	for _, summary := range closedRes.Channels {
+		closeTypes[summary.ChannelPoint] = summary.CloseType
+	}
+
+	feedURL := h.absoluteURL(r, "/feed.xml")
+	feed := &atomFeed{
+		Xmlns:   atomNamespace,
+		ID:      feedURL,
+		Title:   "dcrlnhub channel activity",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: feedURL, Rel: "self"},
+		Author:  atomAuthor{Name: "dcrlnhub"},
+	}
+
+	events := h.events.recent()
+	for _, event := range events {
+		if event.Private {
+			continue
+		}
+
+		action := "opened"
+		if event.Type == channelEventClose {
+			closeType, ok := closeTypes[event.ChannelPoint]
+			if !ok {
+				// The channel is still closing, so it'll only
+				// show up once the close is confirmed.
+				continue
+			}
+			action = closeTypeDescription(closeType)
+		}
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID: fmt.Sprintf("urn:dcrlnhub:%s:%s", event.Type,
+				strings.Replace(event.ChannelPoint, ":", "-", 1)),
+			Title: fmt.Sprintf("Channel %s with %v capacity",
+				action, event.Capacity),
+			Updated: event.Time.UTC().Format(time.RFC3339),
+			Summary: fmt.Sprintf("Channel %s with %s was %s.",
+				event.ChannelPoint, event.RemotePubkey, action),
+		})
+	}
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	}
+
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Feed serves an Atom feed of the hub's recent channel opens and closes. The
+// feed is cached for a few minutes since it requires querying dcrlnd.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Feed(w http.ResponseWriter, r *http.Request) {
+	h.feed.mtx.Lock()
+	if time.Since(h.feed.generated) > feedCacheDuration {
+		feed, err := h.buildFeed(r)
+		if err != nil {
+			h.feed.mtx.Unlock()
+			log.Errorf("unable to build feed: %v", err)
+			http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+			return
+		}
+		h.feed.feed = feed
+		h.feed.generated = time.Now()
+	}
+	feed, generated := h.feed.feed, h.feed.generated
+	h.feed.mtx.Unlock()
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d",
+		int(feedCacheDuration.Seconds())))
+	w.Header().Set("Last-Modified", generated.UTC().Format(http.TimeFormat))
+	w.Write(feed)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// feeReportCache caches the fee policies of the hub's channels, which are
+// only needed by the channels page.
+type feeReportCache struct {
+	mtx     sync.Mutex
+	fetched time.Time
+	fees    map[string]*lnrpc.ChannelFeeReport
+}
+
+// channelFees returns the hub's fee policy of each of its channels, keyed by
+// channel point. The policies are fetched with a single FeeReport call and
+// reused for a refresh interval. When they can't be fetched, the last known
+// policies are returned, which may be none.
+func (h *lightningHub) channelFees() map[string]*lnrpc.ChannelFeeReport {
+	h.feeReport.mtx.Lock()
+	defer h.feeReport.mtx.Unlock()
+
+	if time.Since(h.feeReport.fetched) < h.cfg.RefreshInterval {
+		return h.feeReport.fees
+	}
+
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	res, err := h.client().FeeReport(ctx, &lnrpc.FeeReportRequest{})
+	if err != nil {
+		log.Warnf("rpc FeeReport() failed: %v", err)
+		return h.feeReport.fees
+	}
+
+	fees := make(map[string]*lnrpc.ChannelFeeReport, len(res.ChannelFees))
+	for _, fee := range res.ChannelFees {
+		fees[fee.ChanPoint] = fee
+	}
+	h.feeReport.fees = fees
+	h.feeReport.fetched = time.Now()
+
+	return fees
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestChannelFees checks that the fee policies are fetched once per refresh
+// interval, and that the last known policies are kept when dcrlnd fails.
+func TestChannelFees(t *testing.T) {
+	lnd := &fakeLnd{
+		feeReport: &lnrpc.FeeReportResponse{
+			ChannelFees: []*lnrpc.ChannelFeeReport{{
+				ChanPoint:     "aa:0",
+				BaseFeeMAtoms: 1000,
+				FeePerMil:     10,
+			}},
+		},
+		errs: map[string][]error{
+			"FeeReport": {nil, errors.New("dcrlnd is down")},
+		},
+	}
+	cfg := newTestConfig()
+	cfg.RefreshInterval = time.Hour
+	hub := newTestHub(t, cfg, lnd)
+
+	fees := hub.channelFees()
+	if fee := fees["aa:0"]; fee == nil || fee.FeePerMil != 10 {
+		t.Fatalf("got fee policy %v, want 10 ppm", fee)
+	}
+	if fee := fees["bb:0"]; fee != nil {
+		t.Fatalf("got fee policy %v for a channel without one", fee)
+	}
+
+	hub.channelFees()
+	if n := lnd.callCount("FeeReport"); n != 1 {
+		t.Fatalf("got %d FeeReport calls within the refresh interval, "+
+			"want 1", n)
+	}
+
+	// Once the policies are stale, they're fetched again, the last known
+	// ones being used when that fails.
+	hub.feeReport.fetched = time.Now().Add(-2 * time.Hour)
+	fees = hub.channelFees()
+	if n := lnd.callCount("FeeReport"); n != 2 {
+		t.Fatalf("got %d FeeReport calls, want 2", n)
+	}
+	if fee := fees["aa:0"]; fee == nil || fee.FeePerMil != 10 {
+		t.Fatalf("got fee policy %v after a failure, want the last "+
+			"known one", fee)
+	}
+}
+
+// TestChannelsFees checks that the channels page shows the fee policy of
+// each channel, and handles the channels without a known policy.
+func TestChannelsFees(t *testing.T) {
+	lnd := &fakeLnd{
+		feeReport: &lnrpc.FeeReportResponse{
+			ChannelFees: []*lnrpc.ChannelFeeReport{{
+				ChanPoint:     "aa:0",
+				BaseFeeMAtoms: 1000,
+				FeePerMil:     10,
+			}},
+		},
+	}
+	hub := newTestHub(t, newTestConfig(), lnd)
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		ActiveChannels: []*lnrpc.Channel{{
+			RemotePubkey: "02aaaa",
+			ChannelPoint: "aa:0",
+		}, {
+			RemotePubkey: "02bbbb",
+			ChannelPoint: "bb:0",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	hub.Channels(w, httptest.NewRequest("GET", "/channels", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, "1000 matoms + 10 ppm") {
+		t.Fatalf("channels page doesn't show the fee policy: %s", body)
+	}
+	if !strings.Contains(body, "unknown") {
+		t.Fatalf("channels page doesn't show the missing fee policy: "+
+			"%s", body)
+	}
+}
@@ -3,15 +3,21 @@ module github.com/fguisso/dcrlnhub
 go 1.14
 
 require (
-	github.com/decred/dcrd/dcrec/secp256k1/v3 v3.0.0-20200801222518-7b0968b7e15b // indirect
+	github.com/decred/dcrd/chaincfg/chainhash v1.0.2
+	github.com/decred/dcrd/dcrec/secp256k1/v3 v3.0.0-20200801222518-7b0968b7e15b
 	github.com/decred/dcrd/dcrutil v1.4.0
 	github.com/decred/dcrd/dcrutil/v3 v3.0.0-20200801222518-7b0968b7e15b
 	github.com/decred/dcrlnd v0.2.1
 	github.com/decred/slog v1.0.0
 	github.com/gorilla/mux v1.7.4
+	github.com/gorilla/websocket v1.4.2
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/jrick/logrotate v1.0.0
+	github.com/microcosm-cc/bluemonday v1.0.4
+	github.com/prometheus/client_golang v1.1.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	golang.org/x/crypto v0.0.0-20190829043050-9756ffdc2472
+	golang.org/x/net v0.0.0-20190827160401-ba9fcec4b297
 	google.golang.org/grpc v1.22.0
 	gopkg.in/macaroon.v2 v2.0.0
 )
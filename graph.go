@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// graphStats are the stats of the hub's node in the public channel graph,
+// which include the channels opened by other nodes to it.
+type graphStats struct {
+	Channels uint32
+	Capacity int64
+}
+
+// graphStatsCache holds the last fetched graph stats. They're fetched on
+// their own, slower, interval since querying the graph is heavier than the
+// rest of the data shown by the hub.
+type graphStatsCache struct {
+	mtx   sync.RWMutex
+	stats *graphStats
+}
+
+// get returns the last fetched graph stats, or nil if they weren't fetched
+// yet.
+func (c *graphStatsCache) get() *graphStats {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.stats
+}
+
+// set replaces the last fetched graph stats.
+func (c *graphStatsCache) set(stats *graphStats) {
+	c.mtx.Lock()
+	c.stats = stats
+	c.mtx.Unlock()
+}
+
+// fetchGraphStats fetches the stats of the node with the passed pubkey from
+// the channel graph.
+func (h *lightningHub) fetchGraphStats(pubkey string) (*graphStats, error) {
+	nodeInfoReq := &lnrpc.NodeInfoRequest{PubKey: pubkey}
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	nodeInfo, err := h.client().GetNodeInfo(ctx, nodeInfoReq)
+	if err != nil {
+		return nil, fmt.Errorf("rpc GetNodeInfo() failed: %w", err)
+	}
+
+	return &graphStats{
+		Channels: nodeInfo.NumChannels,
+		Capacity: nodeInfo.TotalCapacity,
+	}, nil
+}
+
+// updateGraphStats fetches the graph stats of the hub's node on every graph
+// interval until the hub is stopped. They're picked up by the next refresh
+// of the template context.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) updateGraphStats() {
+	// We need to know our own pubkey, so wait until dcrlnd was reached at
+	// least once, which may only happen once a degraded hub reaches it.
+	select {
+	case <-h.ready:
+	case <-h.quit:
+		return
+	}
+
+	ticker := time.NewTicker(h.cfg.GraphInterval)
+	defer ticker.Stop()
+
+	for {
+		pubkey := h.cachedContext().NodePubkey
+		stats, err := h.fetchGraphStats(pubkey)
+		if err != nil {
+			log.Warnf("Unable to fetch graph stats: %v", err)
+		} else {
+			h.graphStats.set(stats)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-h.quit:
+			return
+		}
+	}
+}
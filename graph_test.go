@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestUpdateGraphStats checks that the graph stats of the hub's node are
+// fetched as soon as its pubkey is known, and picked up by the home page.
+func TestUpdateGraphStats(t *testing.T) {
+	lnd := &fakeLnd{
+		info: testnetInfo(),
+		nodeInfo: &lnrpc.NodeInfo{
+			NumChannels:   12,
+			TotalCapacity: 3000000,
+		},
+	}
+	cfg := newTestConfig()
+	cfg.GraphInterval = time.Hour
+	hub := newTestHub(t, cfg, lnd)
+	defer close(hub.quit)
+
+	go hub.updateGraphStats()
+
+	// A hub started in degraded mode doesn't know its pubkey yet.
+	time.Sleep(50 * time.Millisecond)
+	if n := lnd.callCount("GetNodeInfo"); n != 0 {
+		t.Fatalf("got %d GetNodeInfo calls before the first context", n)
+	}
+
+	err := hub.setContext(&templateContext{
+		NodePubkey:    "02aa",
+		DcrlndVersion: "0.2.1",
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.graphStats.get() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("graph stats not fetched after the first context")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	req := lnd.lastRequest("GetNodeInfo").(*lnrpc.NodeInfoRequest)
+	if req.PubKey != "02aa" {
+		t.Fatalf("got graph stats of %q, want 02aa", req.PubKey)
+	}
+
+	homeCtx, err := hub.fetchHomePage()
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if homeCtx.GraphChannels != 12 || homeCtx.GraphCapacity != 3000000 {
+		t.Fatalf("got %d graph channels with %d atoms, want 12 with "+
+			"3000000", homeCtx.GraphChannels, homeCtx.GraphCapacity)
+	}
+}
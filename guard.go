@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+)
+
+// writesAllowed returns true if the endpoints that spend the node's funds or
+// otherwise change its state may be served on the configured network.
+func (c *config) writesAllowed() bool {
+	return c.Network != "mainnet" || c.EnableMainnetWrites
+}
+
+// readOnly returns true if the hub authenticates to dcrlnd with the read-only
+// macaroon, which is only the case when writes aren't allowed anyway. The hub
+// then doesn't create anything on the node, donation invoices and addresses
+// included.
+func (c *config) readOnly() bool {
+	return c.ReadOnlyMacaroonPath != "" && !c.writesAllowed()
+}
+
+// writesAllowed returns true if the endpoints that spend the node's funds or
+// otherwise change its state may be served. As a safety rail they're disabled
+// on mainnet unless explicitly enabled, so a deployment meant for testing
+// can't accidentally dispense mainnet funds.
+func (h *lightningHub) writesAllowed() bool {
+	return h.cfg.writesAllowed()
+}
+
+// requireWritable wraps a handler that spends the node's funds or changes its
+// state, rejecting every request when writes aren't allowed on the current
+// network.
+func (h *lightningHub) requireWritable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.writesAllowed() {
+			http.Error(w, "this feature is disabled on mainnet",
+				http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
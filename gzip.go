@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// compressibleTypes are the media types worth compressing. Images other than
+// SVG are already compressed, so they're served as is.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/manifest+json",
+	"application/xml",
+	"application/javascript",
+	"application/rss+xml",
+	"image/svg+xml",
+}
+
+// compressible returns true if a response of the passed content type is worth
+// compressing.
+func compressible(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	mediaType = strings.ToLower(mediaType)
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsGzip returns true if the client of the passed request accepts gzip
+// encoded responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.TrimSpace(strings.Split(coding, ";")[0])
+			if strings.EqualFold(coding, "gzip") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// gzipResponseWriter wraps an http.ResponseWriter to gzip the body of the
+// response. Whether the response is compressed is only decided once its
+// headers are written, since it depends on its content type.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+
+	// gz is the writer compressing the body, nil until the response is
+	// known to be compressed.
+	gz *gzip.Writer
+
+	wroteHeader bool
+}
+
+// WriteHeader decides whether the response is compressed, then writes its
+// headers.
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+
+	header := g.ResponseWriter.Header()
+
+	// Responses without a body, partial content and already encoded
+	// responses, such as pre-compressed static files, are left alone.
+	compress := status != http.StatusNoContent &&
+		status != http.StatusNotModified &&
+		status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" &&
+		compressible(header.Get("Content-Type"))
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+
+	g.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body of the response, compressing it if needed.
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		// Like net/http, sniff the content type if none was set.
+		header := g.ResponseWriter.Header()
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(p))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if g.gz == nil {
+		return g.ResponseWriter.Write(p)
+	}
+	return g.gz.Write(p)
+}
+
+// Flush flushes the compressed data written so far, then the wrapped writer
+// if it supports it.
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, which the WebSocket
+// upgrade requires.
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := g.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer can't be hijacked")
+	}
+	return hj.Hijack()
+}
+
+// close terminates the compressed stream, if the response was compressed.
+func (g *gzipResponseWriter) close() error {
+	if g.gz == nil {
+		return nil
+	}
+	return g.gz.Close()
+}
+
+// compressResponses gzips the responses of the passed handler for the
+// clients accepting it. Only compressible content types are compressed.
+func compressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on the accepted encodings, so caches
+		// must not share it across them.
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// WebSocket upgrades take over the connection, and HEAD
+		// requests have no body to compress.
+		if !acceptsGzip(r) || r.Method == http.MethodHead ||
+			r.Header.Get("Upgrade") != "" {
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer func() {
+			if err := gw.close(); err != nil {
+				log.Debugf("unable to finish compressed response "+
+					"to %v: %v", r.URL.Path, err)
+			}
+		}()
+		next.ServeHTTP(gw, r)
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCompressResponses checks that the responses are gzipped for the
+// clients accepting it, and served as is otherwise.
+func TestCompressResponses(t *testing.T) {
+	body := strings.Repeat("<p>The hub of all ln channels!</p>\n", 100)
+	handler := compressResponses(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+		},
+	))
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{"gzip", "gzip, deflate, br", true},
+		{"weighted gzip", "br;q=1.0, gzip;q=0.8", true},
+		{"identity", "identity", false},
+		{"none", "", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("%s: got Vary %q, want Accept-Encoding",
+				test.name, vary)
+		}
+
+		encoding := w.Header().Get("Content-Encoding")
+		if (encoding == "gzip") != test.wantGzip {
+			t.Errorf("%s: got Content-Encoding %q", test.name,
+				encoding)
+			continue
+		}
+
+		got := w.Body.Bytes()
+		if test.wantGzip {
+			if len(got) >= len(body) {
+				t.Errorf("%s: compressed body of %d bytes isn't "+
+					"smaller than %d", test.name, len(got),
+					len(body))
+			}
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Errorf("%s: invalid gzip body: %v", test.name,
+					err)
+				continue
+			}
+			got, err = ioutil.ReadAll(gz)
+			if err != nil {
+				t.Errorf("%s: unable to decompress body: %v",
+					test.name, err)
+				continue
+			}
+		}
+		if string(got) != body {
+			t.Errorf("%s: got body %q, want %q", test.name, got,
+				body)
+		}
+	}
+}
+
+// TestCompressResponsesSkipped checks that already compressed content types
+// and encoded responses aren't compressed again.
+func TestCompressResponsesSkipped(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		wantGzip bool
+	}{{
+		name:   "png",
+		header: http.Header{"Content-Type": {"image/png"}},
+	}, {
+		name: "pre-compressed",
+		header: http.Header{
+			"Content-Type":     {"text/css"},
+			"Content-Encoding": {"gzip"},
+		},
+	}, {
+		name:     "svg",
+		header:   http.Header{"Content-Type": {"image/svg+xml"}},
+		wantGzip: true,
+	}}
+
+	for _, test := range tests {
+		handler := compressResponses(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				for key, values := range test.header {
+					w.Header()[key] = values
+				}
+				w.Write([]byte("data"))
+			},
+		))
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		gzipped := w.Body.String() != "data"
+		if gzipped != test.wantGzip {
+			t.Errorf("%s: got compressed %v, want %v", test.name,
+				gzipped, test.wantGzip)
+		}
+	}
+}
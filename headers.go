@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// securityHeaders is a middleware setting the security headers on every
+// response. HSTS is only sent when the hub serves HTTPS itself, since
+// browsers ignore it over plain http anyway and a proxy terminating TLS in
+// front of the hub is expected to set its own.
+func (h *lightningHub) securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "SAMEORIGIN")
+		header.Set("Referrer-Policy", "same-origin")
+		if h.cfg.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy",
+				h.cfg.ContentSecurityPolicy)
+		}
+		if h.cfg.UseLeHTTPS && h.cfg.HSTSMaxAge > 0 {
+			header.Set("Strict-Transport-Security",
+				fmt.Sprintf("max-age=%d; includeSubDomains",
+					int64(h.cfg.HSTSMaxAge.Seconds())))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// frameableCSP returns the passed content security policy, allowing the page
+// to be framed by any site.
+func frameableCSP(policy string) string {
+	directives := []string{}
+	for _, directive := range strings.Split(policy, ";") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" ||
+			strings.HasPrefix(directive, "frame-ancestors") {
+
+			continue
+		}
+		directives = append(directives, directive)
+	}
+	directives = append(directives, "frame-ancestors *")
+
+	return strings.Join(directives, "; ")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSecurityHeaders checks that the security headers are set on every
+// response, with HSTS only when the hub serves HTTPS itself.
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		https    bool
+		csp      string
+		wantHSTS string
+	}{{
+		name:     "https",
+		https:    true,
+		csp:      defaultContentSecurityPolicy,
+		wantHSTS: "max-age=31536000; includeSubDomains",
+	}, {
+		name: "plain http",
+		csp:  defaultContentSecurityPolicy,
+	}, {
+		name:     "no csp",
+		https:    true,
+		wantHSTS: "max-age=31536000; includeSubDomains",
+	}}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.UseLeHTTPS = test.https
+		cfg.HSTSMaxAge = 365 * 24 * time.Hour
+		cfg.ContentSecurityPolicy = test.csp
+		hub := newTestHub(t, cfg, &fakeLnd{})
+
+		w := httptest.NewRecorder()
+		hub.securityHeaders(ok).ServeHTTP(w,
+			httptest.NewRequest("GET", "/", nil))
+
+		want := map[string]string{
+			"X-Content-Type-Options":    "nosniff",
+			"X-Frame-Options":           "SAMEORIGIN",
+			"Referrer-Policy":           "same-origin",
+			"Content-Security-Policy":   test.csp,
+			"Strict-Transport-Security": test.wantHSTS,
+		}
+		for name, value := range want {
+			if got := w.Header().Get(name); got != value {
+				t.Errorf("%s: got %v %q, want %q", test.name,
+					name, got, value)
+			}
+		}
+	}
+}
+
+// TestFrameableCSP checks that framing by any site is allowed in place of
+// the frame-ancestors directive of the policy.
+func TestFrameableCSP(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{"", "frame-ancestors *"},
+		{"default-src 'self'", "default-src 'self'; frame-ancestors *"},
+		{
+			"default-src 'self'; frame-ancestors 'none'; img-src data:;",
+			"default-src 'self'; img-src data:; frame-ancestors *",
+		},
+	}
+
+	for _, test := range tests {
+		if got := frameableCSP(test.policy); got != test.want {
+			t.Errorf("frameableCSP(%q) = %q, want %q", test.policy,
+				got, test.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+const (
+	// healthOK is reported when the hub is fully operational.
+	healthOK = "ok"
+
+	// healthDegraded is reported when dcrlnd responds but too many of
+	// its channels are inactive for it to route effectively.
+	healthDegraded = "degraded"
+
+	// healthUnavailable is reported when dcrlnd can't be reached.
+	healthUnavailable = "unavailable"
+
+	// lndCheckCacheDuration is how long the result of a check of the
+	// connection to dcrlnd is reused, so frequent probes don't flood the
+	// node with requests.
+	lndCheckCacheDuration = 5 * time.Second
+
+	// lndCheckTimeout is the maximum time to wait for dcrlnd to respond to
+	// a check of the connection.
+	lndCheckTimeout = 5 * time.Second
+)
+
+// lndCheck caches the result of the last check of the connection to dcrlnd.
+type lndCheck struct {
+	mtx     sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// checkLnd checks that dcrlnd responds through a GetInfo call, reusing the
+// result of the last check if it's recent enough.
+func (h *lightningHub) checkLnd() error {
+	h.lndCheck.mtx.Lock()
+	defer h.lndCheck.mtx.Unlock()
+
+	if time.Since(h.lndCheck.checked) < lndCheckCacheDuration {
+		return h.lndCheck.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctxb, lndCheckTimeout)
+	defer cancel()
+
+	_, err := h.client().GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	h.lndCheck.checked = time.Now()
+	h.lndCheck.err = err
+
+	return err
+}
+
+// inactiveRatio returns the ratio of inactive channels to all channels.
+func (c *templateContext) inactiveRatio() float64 {
+	total := len(c.ActiveChannels)
+	if total == 0 {
+		return 0
+	}
+
+	return float64(c.InactiveCount) / float64(total)
+}
+
+// healthStatus returns the health of the hub given its current context,
+// along with a human readable reason when it isn't ok. The connection to
+// dcrlnd is checked as well, since the context may be served from the cache
+// while dcrlnd is down.
+func (h *lightningHub) healthStatus(homeInfo *templateContext) (string, string) {
+	if homeInfo == nil {
+		return healthUnavailable, "dcrlnd hasn't been reached"
+	}
+
+	if err := h.checkLnd(); err != nil {
+		return healthUnavailable, fmt.Sprintf("unable to reach "+
+			"dcrlnd: %v", err)
+	}
+
+	if homeInfo.inactiveRatio() > h.cfg.MaxInactiveRatio {
+		return healthDegraded, "too many inactive channels"
+	}
+
+	return healthOK, ""
+}
+
+// healthResponse is the response of the health endpoint.
+type healthResponse struct {
+	Status        string  `json:"status"`
+	Reason        string  `json:"reason,omitempty"`
+	InactiveRatio float64 `json:"inactive_ratio"`
+	HubUptime     string  `json:"hub_uptime"`
+	UptimeSeconds int64   `json:"uptime_seconds"`
+}
+
+// Healthz reports the health of the hub as JSON. It responds with 503 if
+// dcrlnd can't be reached, and reports a degraded status when the ratio of
+// inactive channels exceeds the configured maximum.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Healthz(w http.ResponseWriter, r *http.Request) {
+	homeInfo := h.freshContext()
+	status, reason := h.healthStatus(homeInfo)
+
+	now := time.Now()
+	resp := &healthResponse{
+		Status:        status,
+		Reason:        reason,
+		HubUptime:     hubUptime(now),
+		UptimeSeconds: int64(now.Sub(hubStarted).Seconds()),
+	}
+	if homeInfo != nil {
+		resp.InactiveRatio = homeInfo.inactiveRatio()
+	}
+	if status == healthUnavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, resp)
+}
+
+// statusPage is the context used to render the status page.
+type statusPage struct {
+	*baseContext
+	*templateContext
+
+	// Status and Reason are the health of the hub.
+	Status string
+	Reason string
+
+	// InactivePercent and MaxInactivePercent are the current and maximum
+	// acceptable percentage of inactive channels.
+	InactivePercent    float64
+	MaxInactivePercent float64
+
+	// Reachability is the result of the last reachability check of each
+	// of the node's advertised addresses.
+	Reachability []addrReachability
+}
+
+// Status renders the operational status page of the hub.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Status(w http.ResponseWriter, r *http.Request) {
+	statusTemplate := h.lookupTemplate("status.html")
+	if statusTemplate == nil {
+		log.Error("unable to lookup status")
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		h.renderUnavailable(w)
+		return
+	}
+
+	status, reason := h.healthStatus(homeInfo)
+	statusTemplate.Execute(w, &statusPage{
+		baseContext:        h.baseContext("Status"),
+		templateContext:    homeInfo,
+		Status:             status,
+		Reason:             reason,
+		InactivePercent:    100 * homeInfo.inactiveRatio(),
+		MaxInactivePercent: 100 * h.cfg.MaxInactiveRatio,
+		Reachability:       h.reachability.get(),
+	})
+}
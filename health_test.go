@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestHealthz checks that the health endpoint responds with 503 while dcrlnd
+// can't be reached, even with a cached context, and with 200 once it's back.
+func TestHealthz(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   int
+		wantStatus string
+	}{{
+		name:       "unavailable",
+		err:        status.Error(codes.Unavailable, "connection refused"),
+		wantCode:   http.StatusServiceUnavailable,
+		wantStatus: healthUnavailable,
+	}, {
+		name:       "reachable",
+		wantCode:   http.StatusOK,
+		wantStatus: healthOK,
+	}}
+
+	lnd := &fakeLnd{info: testnetInfo()}
+	hub := newTestHub(t, newTestConfig(), lnd)
+	err := hub.setContext(&templateContext{
+		DcrlndVersion:  "0.2.1",
+		ActiveChannels: []*lnrpc.Channel{{Active: true}},
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	for _, test := range tests {
+		lnd.errs = nil
+		if test.err != nil {
+			lnd.errs = map[string][]error{"GetInfo": {test.err}}
+		}
+		// Forget the result of the previous check instead of waiting
+		// for it to expire.
+		hub.lndCheck.checked = time.Time{}
+
+		w := httptest.NewRecorder()
+		hub.Healthz(w, httptest.NewRequest("GET", "/healthz", nil))
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name, w.Code,
+				test.wantCode)
+		}
+		var res healthResponse
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			t.Fatalf("%s: unable to decode response: %v", test.name,
+				err)
+		}
+		if res.Status != test.wantStatus {
+			t.Errorf("%s: got health %q (%s), want %q", test.name,
+				res.Status, res.Reason, test.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// historySaveInterval is how often the history is persisted when it
+	// changed. Points are recorded on every refresh, so they're saved in
+	// batches rather than rewriting the whole file every time.
+	historySaveInterval = 10 * time.Minute
+)
+
+// historyPoint is a snapshot of the node's channels taken on a refresh.
+type historyPoint struct {
+	Time          time.Time `json:"time"`
+	ChannelsCount uint32    `json:"channels_count"`
+	Capacity      int64     `json:"capacity"`
+}
+
+// capacityHistory records the number of channels and capacity of the node on
+// every refresh, keeping up to a fixed number of points. It's persisted to
+// disk so the history survives restarts.
+type capacityHistory struct {
+	mtx    sync.Mutex
+	path   string
+	size   int
+	points []historyPoint
+
+	// dirty is true if points were recorded since the history was last
+	// persisted.
+	dirty bool
+}
+
+// newCapacityHistory creates a history keeping up to size points and
+// persisting them at path, loading any previously saved points. An empty path
+// disables persistence.
+func newCapacityHistory(path string, size int) (*capacityHistory, error) {
+	c := &capacityHistory{path: path, size: size}
+	if path == "" {
+		return c, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return c, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.points); err != nil {
+		return nil, fmt.Errorf("unable to parse %v: %v", path, err)
+	}
+
+	// The history may have been saved with a larger size.
+	if len(c.points) > size {
+		c.points = c.points[len(c.points)-size:]
+	}
+
+	return c, nil
+}
+
+// record adds a point for the passed context, dropping the oldest point once
+// the history is full. The point is persisted by the next flush.
+func (c *capacityHistory) record(homeCtx *templateContext) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.points = append(c.points, historyPoint{
+		Time:          homeCtx.LastUpdated,
+		ChannelsCount: homeCtx.ChannelsCount,
+		Capacity:      homeCtx.Capacity,
+	})
+	if len(c.points) > c.size {
+		// Copy the points we keep so the backing array doesn't keep
+		// growing.
+		c.points = append(
+			[]historyPoint(nil), c.points[len(c.points)-c.size:]...,
+		)
+	}
+	c.dirty = true
+}
+
+// flush persists the history if points were recorded since it was last
+// persisted.
+func (c *capacityHistory) flush() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	if err := c.save(); err != nil {
+		return err
+	}
+	c.dirty = false
+
+	return nil
+}
+
+// saveHistory persists the history on every history save interval until the
+// hub is stopped, which persists it one last time.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) saveHistory() {
+	ticker := time.NewTicker(historySaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.quit:
+			return
+		}
+
+		if err := h.history.flush(); err != nil {
+			log.Warnf("Unable to save history: %v", err)
+		}
+	}
+}
+
+// snapshot returns a copy of the recorded points, oldest first.
+func (c *capacityHistory) snapshot() []historyPoint {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]historyPoint{}, c.points...)
+}
+
+// save writes the history to disk. The caller must hold the mutex.
+func (c *capacityHistory) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(c.points)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash can't leave us with a
+	// truncated history.
+	tmpPath := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.path)
+}
+
+// historyResponse is the response of the history API.
+type historyResponse struct {
+	Count  int            `json:"count"`
+	Points []historyPoint `json:"points"`
+}
+
+// APIHistory returns the number of channels and capacity of the node over
+// time as JSON, oldest point first, for charting.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) APIHistory(w http.ResponseWriter, r *http.Request) {
+	points := h.history.snapshot()
+
+	h.setCacheHeaders(w)
+	writeJSON(w, &historyResponse{
+		Count:  len(points),
+		Points: points,
+	})
+}
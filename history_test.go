@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCapacityHistory checks that a point is recorded on every refresh, up
+// to the configured number of points, and served oldest first.
+func TestCapacityHistory(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	hub.history = &capacityHistory{size: 3}
+
+	for i := 1; i <= 5; i++ {
+		err := hub.setContext(&templateContext{
+			DcrlndVersion: "0.2.1",
+			ChannelsCount: uint32(i),
+			Capacity:      int64(i) * 100000,
+		})
+		if err != nil {
+			t.Fatalf("unable to set context: %v", err)
+		}
+
+		want := i
+		if want > 3 {
+			want = 3
+		}
+		if n := len(hub.history.snapshot()); n != want {
+			t.Fatalf("after %d refreshes, got %d points, want %d",
+				i, n, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	hub.APIHistory(w, httptest.NewRequest("GET", "/api/v1/history", nil))
+	var resp historyResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("unable to decode history: %v", err)
+	}
+	if resp.Count != 3 {
+		t.Fatalf("got %d points, want 3", resp.Count)
+	}
+	for i, point := range resp.Points {
+		if point.ChannelsCount != uint32(i+3) ||
+			point.Capacity != int64(i+3)*100000 {
+
+			t.Fatalf("point %d: got %d channels with %d atoms", i,
+				point.ChannelsCount, point.Capacity)
+		}
+		if i > 0 && point.Time.Before(resp.Points[i-1].Time) {
+			t.Fatalf("point %d is older than the previous one", i)
+		}
+	}
+}
+
+// TestCapacityHistoryFlush checks that the recorded points are only written
+// to disk when flushed, and loaded back on restart.
+func TestCapacityHistoryFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.json")
+
+	history, err := newCapacityHistory(path, 10)
+	if err != nil {
+		t.Fatalf("unable to create history: %v", err)
+	}
+	history.record(&templateContext{ChannelsCount: 1, Capacity: 1000})
+	history.record(&templateContext{ChannelsCount: 2, Capacity: 3000})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("history written before being flushed: %v", err)
+	}
+
+	if err := history.flush(); err != nil {
+		t.Fatalf("unable to flush history: %v", err)
+	}
+	loaded, err := newCapacityHistory(path, 10)
+	if err != nil {
+		t.Fatalf("unable to load history: %v", err)
+	}
+	points := loaded.snapshot()
+	if len(points) != 2 || points[1].Capacity != 3000 {
+		t.Fatalf("got points %+v after a restart", points)
+	}
+
+	// Nothing is written again until a new point is recorded.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("unable to remove history: %v", err)
+	}
+	if err := history.flush(); err != nil {
+		t.Fatalf("unable to flush history: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged history written again: %v", err)
+	}
+}
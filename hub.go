@@ -3,115 +3,544 @@ package main
 import (
 	"fmt"
 	"html/template"
-	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrlnd/lnrpc"
-	"github.com/decred/dcrlnd/macaroons"
+	"github.com/microcosm-cc/bluemonday"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	macaroon "gopkg.in/macaroon.v2"
 )
 
 // lightningHub is a Decred Channel Hub. The main action for the hub is open
 // more channels and help to increase the Decred's Lightning Network. The hub
 // required a connection to a local lnd node in order to operate properly.
 type lightningHub struct {
-	lnd      lnrpc.LightningClient
-	template *template.Template
-	cfg      *config
-	context  *templateContext
+	cfg *config
+
+	// templateMtx guards template, which is replaced on every request in
+	// development mode.
+	templateMtx sync.RWMutex
+	template    *template.Template
+
+	// connMtx guards conn and lnd, which are replaced whenever the hub
+	// needs to reconnect to dcrlnd.
+	connMtx sync.RWMutex
+	conn    *grpc.ClientConn
+	lnd     lndClient
+
+	// nodes are the additional dcrlnd nodes whose stats are aggregated
+	// with the ones of the hub's node.
+	nodes []*backendNode
+
+	// stats caches the last successfully fetched template context, which
+	// every page, API and WebSocket client is served from.
+	stats statsCache
+
+	// ready is closed once the first template context is cached, so the
+	// background checks needing it don't start before dcrlnd is reached.
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// openMtx serializes the channel opens, so each of them is checked
+	// against the balance left by the previous ones.
+	openMtx sync.Mutex
+
+	// refreshMtx guards refreshing, which is non-nil while a refresh
+	// triggered by a stale read or an admin is in flight.
+	refreshMtx sync.Mutex
+	refreshing *pendingRefresh
+
+	// quit is closed when the hub is stopped.
+	quit chan struct{}
+
+	// donations keeps the running total of donations received.
+	donations *donationTracker
+
+	// history records the node's channels and capacity over time.
+	history *capacityHistory
+
+	// feeReport caches the hub's fee policy of each channel.
+	feeReport feeReportCache
+
+	// qrCache caches the QR codes rendered by the QR endpoint.
+	qrCache qrCache
+
+	// sanitizer sanitizes all HTML provided by the operator before it's
+	// added to a template context.
+	sanitizer *bluemonday.Policy
+
+	// events records the channels opened and closed by the node, and feed
+	// caches the Atom feed built from them.
+	events *channelEventLog
+	feed   feedCache
+
+	// reachability holds the result of the last reachability check of
+	// each of the node's advertised addresses.
+	reachability reachabilityChecker
+
+	// lndCheck caches the result of the last check of the connection to
+	// dcrlnd done by the health endpoint.
+	lndCheck lndCheck
+
+	// metrics holds the exported Prometheus metrics, it's nil unless
+	// metrics are enabled.
+	metrics *hubMetrics
+
+	// broadcaster pushes every new context to the WebSocket clients.
+	broadcaster *statsBroadcaster
+
+	// graphStats holds the stats of the node in the public channel graph.
+	graphStats graphStatsCache
+
+	// limiter limits how often each visitor can use the endpoints
+	// changing the node's state, it's nil when rate limiting is disabled.
+	limiter *rateLimiter
+
+	// basicAuthCache caches the verified basic auth credentials.
+	basicAuthCache basicAuthCache
 }
 
 // templateContext defines the inital context required to rendering dcrlnhub.
 type templateContext struct {
-	NodeAddr        string
-	Network         string
-	ChannelsCount   uint32
-	Capacity        int64
-	Balance         dcrutil.Amount
-	ActiveChannels  []*lnrpc.Channel
-	DonationAddr    string
-	DonationInvoice string
+	NodePubkey           string
+	NodeAddr             string
+	NodeURIs             []string
+	Alias                string
+	Color                string
+	Network              string
+	DcrlndVersion        string
+	SyncedToChain        bool
+	BlockHeight          uint32
+	LastUpdated          time.Time
+	ChannelsCount        uint32
+	InactiveCount        uint32
+	PeerCount            int
+	ChannelPeerCount     int
+	Capacity             int64
+	GraphChannels        uint32
+	GraphCapacity        int64
+	Balance              dcrutil.Amount
+	UnconfirmedBalance   dcrutil.Amount
+	TotalBalance         dcrutil.Amount
+	InboundCapacity      dcrutil.Amount
+	TotalLocalBalance    dcrutil.Amount
+	TotalRemoteBalance   dcrutil.Amount
+	TotalDonated         dcrutil.Amount
+	ActiveChannels       []*lnrpc.Channel
+	Nodes                []*nodeStats
+	PendingChannels      *pendingChannels
+	SuggestedChannelSize dcrutil.Amount
+	DonationAddr         string
+	DonationAddrURI      template.URL
+	DonationInvoice      string
+	DonationURI          template.URL
+	DonationExpiry       time.Time
+	FeaturedPeers        []featuredPeer
+
+	// HubBalance is the confirmed balance of the hub's own wallet, which
+	// funds its channels, while Balance also includes the wallets of the
+	// additional nodes. Likewise, HubInboundCapacity is what the hub's own
+	// node can receive, e.g. as donations.
+	HubBalance         dcrutil.Amount
+	HubInboundCapacity dcrutil.Amount
+}
+
+// nodeColorPattern matches the colors advertised by nodes, in the #rrggbb
+// format.
+var nodeColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// nodeColor returns the passed node color if it's well formed, so it's safe
+// to use in a style, or an empty string otherwise.
+func nodeColor(color string) string {
+	if !nodeColorPattern.MatchString(color) {
+		return ""
+	}
+
+	return color
+}
+
+// LocalPercent returns the percentage of the balance of the active channels
+// held by the hub, i.e. its share of the outbound liquidity.
+func (c *templateContext) LocalPercent() float64 {
+	total := c.TotalLocalBalance + c.TotalRemoteBalance
+	if total == 0 {
+		return 0
+	}
+
+	return 100 * float64(c.TotalLocalBalance) / float64(total)
+}
+
+// formatDCR formats the passed amount of atoms in DCR, with as many decimals
+// as needed, e.g. 100000000 atoms as "1 DCR".
+func formatDCR(atoms int64) string {
+	return dcrutil.Amount(atoms).String()
+}
+
+// CapacityDCR returns the capacity of the node's channels formatted in DCR.
+func (c *templateContext) CapacityDCR() string {
+	return formatDCR(c.Capacity)
+}
+
+// GraphCapacityDCR returns the capacity of the node's channels known to the
+// network graph formatted in DCR.
+func (c *templateContext) GraphCapacityDCR() string {
+	return formatDCR(c.GraphCapacity)
+}
+
+// HubUptime returns how long the hub process has been running, computed when
+// the page is rendered rather than when the context was fetched.
+func (c *templateContext) HubUptime() string {
+	return hubUptime(time.Now())
+}
+
+// UpdatedAgo returns how long ago the context was fetched from dcrlnd,
+// rounded to the second.
+func (c *templateContext) UpdatedAgo() time.Duration {
+	return time.Since(c.LastUpdated).Round(time.Second)
+}
+
+// DisplayAlias returns the alias of the node, falling back to the beginning
+// of its pubkey when the node has no alias.
+func (c *templateContext) DisplayAlias() string {
+	if c.Alias != "" {
+		return c.Alias
+	}
+
+	const shortPubkeyLen = 16
+	if len(c.NodePubkey) <= shortPubkeyLen {
+		return c.NodePubkey
+	}
+	return c.NodePubkey[:shortPubkeyLen] + "…"
+}
+
+// pendingChannels are the channels of the node which are still being opened
+// or closed.
+type pendingChannels struct {
+	// Opening are the channels waiting for their funding transaction to
+	// confirm.
+	Opening []*lnrpc.PendingChannelsResponse_PendingOpenChannel
+
+	// WaitingClose are the channels waiting for their closing transaction
+	// to confirm.
+	WaitingClose []*lnrpc.PendingChannelsResponse_WaitingCloseChannel
+
+	// Capacity is the total capacity of the pending channels, which isn't
+	// part of the node's capacity yet, or anymore.
+	Capacity int64
+}
+
+// baseContext is the context shared by every rendered page, used by the
+// shared header and footer templates.
+type baseContext struct {
+	// SiteName and Tagline brand the hub.
+	SiteName string
+	Tagline  template.HTML
+
+	// Title is the title of the current page.
+	Title string
+
+	// Version is the version of dcrlnhub.
+	Version string
+
+	// ThemeColor is the color of the browser UI around the hub.
+	ThemeColor string
+}
+
+// baseContext returns the base context of a page with the passed title.
+// Every page's context embeds it, so its fields are merged with the page
+// specific ones.
+func (h *lightningHub) baseContext(title string) *baseContext {
+	return &baseContext{
+		SiteName:   h.cfg.SiteName,
+		Tagline:    h.sanitizeHTML(h.cfg.Tagline),
+		Title:      title,
+		Version:    version(),
+		ThemeColor: h.cfg.ThemeColor,
+	}
+}
+
+// homePage is the context used to render the home page. It extends the
+// template context with the sections the current visitor is allowed to see.
+type homePage struct {
+	*baseContext
+	*templateContext
+
+	// ShowChannels is true if the per-channel details should be rendered
+	// along with the aggregate stats.
+	ShowChannels bool
+
+	// Channels are the channels the visitor is allowed to see. Private
+	// channels are only listed to admins.
+	Channels []*lnrpc.Channel
+
+	// MinChannelSize and MaxChannelSize bound the size of the channels
+	// visitors can request.
+	MinChannelSize dcrutil.Amount
+	MaxChannelSize dcrutil.Amount
+
+	// MaxFundable is the largest channel the hub can currently fund out
+	// of its confirmed on-chain balance.
+	MaxFundable dcrutil.Amount
+
+	// FundingTxid and OpenChannelError hold the outcome of the channel
+	// open requested through the form, if any.
+	FundingTxid      string
+	OpenChannelError string
+
+	// ConnectMessage and ConnectError hold the outcome of the peer
+	// connection requested through the form, if any.
+	ConnectMessage string
+	ConnectError   string
+
+	// DonationsEnabled is true if visitors can donate to the hub, which
+	// isn't the case when it runs with a read-only macaroon.
+	DonationsEnabled bool
+}
+
+// homePage returns the context used to render the home page for the passed
+// request.
+func (h *lightningHub) homePage(r *http.Request,
+	homeInfo *templateContext) *homePage {
+
+	// Per-channel data is only shown to everyone when the hub isn't
+	// configured to keep it behind the admin auth.
+	isAdmin := h.isAdmin(r)
+	visibility := visibilityPublic
+	if isAdmin {
+		visibility = visibilityAll
+	}
+
+	return &homePage{
+		baseContext:     h.baseContext("The hub of all ln channels!"),
+		templateContext: homeInfo,
+		ShowChannels:    !h.cfg.PublicAggregateOnly || isAdmin,
+		Channels:        filterChannels(homeInfo.ActiveChannels, visibility),
+		MinChannelSize:  dcrutil.Amount(h.cfg.MinChannelSize),
+		MaxChannelSize:  dcrutil.Amount(h.cfg.MaxChannelSize),
+		MaxFundable:     fundableAmount(homeInfo.HubBalance, h.cfg),
+
+		DonationsEnabled: !h.cfg.readOnly(),
+	}
+}
+
+// widgetPage is the context used to render the embeddable stats widget.
+type widgetPage struct {
+	*baseContext
+	*templateContext
+
+	// HubURL is the absolute URL of the hub's home page.
+	HubURL string
 }
 
 func newLightningHub(cfg *config, template *template.Template) (
 	*lightningHub, error) {
 
 	// First attempt to establish a connection to dcrlnd's RPC sever.
-	tlsCertPath := cleanAndExpandPath(cfg.TLSCertPath)
-	creds, err := credentials.NewClientTLSFromFile(tlsCertPath, "")
+	conn, err := dialLnd(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read cert file: %v", err)
+		return nil, err
 	}
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
 
-	// Load the specified macaroon file.
-	macPath := cleanAndExpandPath(cfg.MacaroonPath)
-	macBytes, err := ioutil.ReadFile(macPath)
-	if err != nil {
-		return nil, err
+	// If we're able to connect out to the dcrlnd node, then we can start up
+	// the hub safely.
+	lnd := newLndClient(conn)
+
+	// Load the donations received so far, we'll keep them up to date
+	// from the invoices settled by dcrlnd. They're only persisted when
+	// we're allowed to use the data directory.
+	var donationsPath string
+	if !cfg.NoDataDir {
+		donationsPath = filepath.Join(
+			defaultDataDir, "data", cfg.Network, donationsFilename,
+		)
 	}
-	mac := &macaroon.Macaroon{}
-	if err = mac.UnmarshalBinary(macBytes); err != nil {
-		return nil, err
+	donations, err := newDonationTracker(donationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load donations: %v", err)
 	}
 
-	// Now we append the macaroon credentials to the dial options.
-	opts = append(
-		opts,
-		grpc.WithPerRPCCredentials(macaroons.NewMacaroonCredential(mac)),
-	)
-	conn, err := grpc.Dial(cfg.RPCHost, opts...)
+	history, err := newCapacityHistory(cfg.HistoryFile, cfg.HistorySize)
 	if err != nil {
-		return nil, fmt.Errorf("unable to dial to dcrlnd's gRPC server: %v", err)
+		return nil, fmt.Errorf("unable to load history: %v", err)
 	}
 
-	// If we're able to connect out to the dcrlnd node, then we can start up
-	// the hub safely.
-	lnd := lnrpc.NewLightningClient(conn)
+	// Connect to the additional nodes as well. Like for the hub's node,
+	// the connections are established lazily unless a dial timeout is
+	// set.
+	var nodes []*backendNode
+	for _, nodeCfg := range cfg.nodes {
+		node, err := dialNode(cfg, nodeCfg)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	hub := &lightningHub{
+		conn:        conn,
+		lnd:         lnd,
+		nodes:       nodes,
+		template:    template,
+		cfg:         cfg,
+		donations:   donations,
+		history:     history,
+		sanitizer:   newSanitizer(cfg.SanitizeTags, cfg.SanitizeAttrs),
+		events:      newChannelEventLog(),
+		broadcaster: newStatsBroadcaster(),
+		ready:       make(chan struct{}),
+		quit:        make(chan struct{}),
+	}
+	if cfg.EnableMetrics {
+		hub.metrics = newHubMetrics()
+	}
+	if cfg.RateLimit > 0 {
+		hub.limiter = newRateLimiter(cfg.RateLimit)
+	}
+	go hub.trackDonations()
+
+	if cfg.ReachabilityInterval > 0 {
+		go hub.checkReachability()
+	}
+
+	if !hub.writesAllowed() {
+		log.Infof("Running on mainnet, features spending funds are " +
+			"disabled unless enable_mainnet_writes is set")
+	}
 
 	// Get chain info to stop creation if the dcrlnd and dcrlnfaucet
 	// are set in different networks.
-	homeCtx, err := fetchHomePage(lnd, cfg)
+	homeCtx, err := hub.fetchInitialContext()
 	if err != nil {
 		log.Errorf("%v", err)
-		return nil, fmt.Errorf("unable to get initial info: %v", err)
+		if !cfg.StartDegraded {
+			return nil, fmt.Errorf("unable to get initial info: %v", err)
+		}
+
+		// If we were asked to start regardless, we'll serve the
+		// unavailable page until the refresher reaches dcrlnd.
+		log.Warnf("Starting in degraded mode, retrying dcrlnd every %v",
+			cfg.RefreshInterval)
+	} else if err := hub.setContext(homeCtx); err != nil {
+		return nil, err
+	}
+
+	// Keep the context up to date in the background so requests don't
+	// have to wait on dcrlnd.
+	go hub.refreshLoop()
+
+	if cfg.GraphInterval > 0 {
+		go hub.updateGraphStats()
+	}
+
+	if cfg.HistoryFile != "" {
+		go hub.saveHistory()
+	}
+
+	if cfg.FeeBumpAfter > 0 && hub.writesAllowed() {
+		go hub.autoBumpFees()
 	}
 
-	return &lightningHub{
-		lnd:      lnd,
-		template: template,
-		cfg:      cfg,
-		context:  homeCtx,
-	}, nil
+	return hub, nil
+}
+
+// setContext replaces the cached template context, recording any channel
+// opened or closed since the previous one. The version of dcrlnd is checked
+// on the first context, whether it was fetched on startup or once a degraded
+// hub reached dcrlnd, and an error is returned without caching the context
+// if it's too old and strict_version is set.
+func (h *lightningHub) setContext(homeCtx *templateContext) error {
+	prevCtx := h.cachedContext()
+	if prevCtx == nil {
+		// Some of the RPCs we rely on may be missing from older
+		// versions of dcrlnd, which only fail once they're used.
+		err := checkDcrlndVersion(homeCtx.DcrlndVersion, h.cfg)
+		switch {
+		case err != nil && h.cfg.StrictVersion:
+			return err
+		case err != nil:
+			log.Warnf("%v, some features may not work", err)
+		}
+	}
+
+	h.events.observe(homeCtx.ActiveChannels)
+	h.metrics.update(homeCtx)
+
+	h.stats.set(homeCtx)
+	h.readyOnce.Do(func() { close(h.ready) })
+
+	h.history.record(homeCtx)
+
+	h.broadcaster.publish(homeCtx)
+
+	// Render the QR code of a new donation invoice as soon as it's
+	// created, so the kiosks showing it get it instantly.
+	if h.cfg.PrefetchDonationInvoice && homeCtx.DonationInvoice != "" &&
+		(prevCtx == nil ||
+			prevCtx.DonationInvoice != homeCtx.DonationInvoice) {
+
+		go h.prefetchInvoice(homeCtx.DonationInvoice)
+	}
+
+	return nil
+}
+
+// cachedContext returns the last successfully fetched template context, or
+// nil if dcrlnd hasn't been reached yet.
+func (h *lightningHub) cachedContext() *templateContext {
+	return h.stats.get()
+}
+
+// checkNodeNetwork returns an error unless the passed GetInfo response is of
+// a node on the configured network.
+func checkNodeNetwork(nodeInfo *lnrpc.GetInfoResponse, cfg *config) error {
+	// dcrlnd may not report its chain yet while it's starting up.
+	if len(nodeInfo.Chains) == 0 {
+		return fmt.Errorf("dcrlnd returned no chain info")
+	}
+
+	activeNetwork := nodeInfo.Chains[0].Network
+	if activeNetwork != cfg.Network {
+		return fmt.Errorf("dcrlnd and dcrlnhub are set in different "+
+			"networks <dcrlnd: %v / dcrlnhub: %v>", activeNetwork,
+			cfg.Network)
+	}
+
+	return nil
 }
 
 // fetchHomePage query the information required and pass to the template context
 // to be present in the Hub's home page.
-func fetchHomePage(lnd lnrpc.LightningClient, cfg *config) (
+func fetchHomePage(lnd lndClient, cfg *config) (
 	*templateContext, error) {
 
 	// First query for the general information from the dcrlnd node, this'll
 	// be used to populate the number of active channel as well as the
 	// identity of the node.
 	infoReq := &lnrpc.GetInfoRequest{}
-	nodeInfo, err := lnd.GetInfo(ctxb, infoReq)
+	var nodeInfo *lnrpc.GetInfoResponse
+	err := retryRPC(cfg, "GetInfo", func() (err error) {
+		ctx, cancel := cfg.rpcContext()
+		defer cancel()
+		nodeInfo, err = lnd.GetInfo(ctx, infoReq)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("rpc GetInfo() failed: %v", err)
+		return nil, fmt.Errorf("rpc GetInfo() failed: %w", err)
 	}
 
 	// Stop creation if the dcrlnd and dcrlnhub are set in different networks.
-	activeNetwork := nodeInfo.Chains[0].Network
-	if activeNetwork != cfg.Network {
-		return nil, fmt.Errorf(
-			"dcrlnd and dcrlnhub are set in different "+
-				"networks <dcrlnd: %v / dcrlnhub: %v>",
-			activeNetwork, cfg.Network)
+	if err := checkNodeNetwork(nodeInfo, cfg); err != nil {
+		return nil, err
 	}
+	activeNetwork := nodeInfo.Chains[0].Network
 
-	// Get the dcrlnd's node uri.
+	// Get the dcrlnd's node uri. Nodes reachable over both clearnet and
+	// Tor advertise several URIs, all of which are shown so visitors can
+	// pick the one that suits them, the first being the primary one.
 	nodeAddr := ""
 	if len(nodeInfo.Uris) == 0 {
 		log.Warn("nodeInfo did not include a URI. external_ip config of dcrlnd is probably not set")
@@ -121,63 +550,203 @@ func fetchHomePage(lnd lnrpc.LightningClient, cfg *config) (
 
 	// Get active channels list.
 	listChanReq := &lnrpc.ListChannelsRequest{}
-	listChanRes, err := lnd.ListChannels(ctxb, listChanReq)
+	var listChanRes *lnrpc.ListChannelsResponse
+	err = retryRPC(cfg, "ListChannels", func() (err error) {
+		ctx, cancel := cfg.rpcContext()
+		defer cancel()
+		listChanRes, err = lnd.ListChannels(ctx, listChanReq)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("rpc ListChannels() failed: %v", err)
+		return nil, fmt.Errorf("rpc ListChannels() failed: %w", err)
 	}
 
-	// With channels list now we'll calculete the total capacity in atoms.
+	// With channels list now we'll calculete the total capacity in atoms,
+	// as well as how much we're able to receive through the active
+	// channels. The remote party must keep its reserve, so that part of
+	// its balance can't be pushed to us. The local and remote balances of
+	// the active channels are summed as reported, reserves included, so
+	// they add up to their capacity minus the commitment fee.
 	var totalCapacity int64
+	var inboundCapacity int64
+	var localBalance, remoteBalance int64
+	var inactiveCount uint32
+	channelPeers := make(map[string]struct{})
 	for _, channel := range listChanRes.Channels {
 		totalCapacity += channel.Capacity
+		channelPeers[channel.RemotePubkey] = struct{}{}
+
+		if !channel.Active {
+			inactiveCount++
+			continue
+		}
+		localBalance += channel.LocalBalance
+		remoteBalance += channel.RemoteBalance
+
+		inbound := channel.RemoteBalance - channel.RemoteChanReserveAtoms
+		if inbound > 0 {
+			inboundCapacity += inbound
+		}
+	}
+
+	// The peers we're connected to don't necessarily have a channel with
+	// us, nor are the peers we have channels with always online, so both
+	// counts are kept. The peer count is only informative, so failing to
+	// fetch it doesn't take the whole page down.
+	listPeersReq := &lnrpc.ListPeersRequest{}
+	var listPeersRes *lnrpc.ListPeersResponse
+	err = retryRPC(cfg, "ListPeers", func() (err error) {
+		ctx, cancel := cfg.rpcContext()
+		defer cancel()
+		listPeersRes, err = lnd.ListPeers(ctx, listPeersReq)
+		return err
+	})
+	var peerCount int
+	if err != nil {
+		log.Warnf("rpc ListPeers() failed: %v", err)
+	} else {
+		peerCount = len(listPeersRes.Peers)
+	}
+
+	// Channels being opened or closed aren't listed by ListChannels, so
+	// they're fetched separately. Their capacity is kept apart from the
+	// capacity of the open channels.
+	pendingReq := &lnrpc.PendingChannelsRequest{}
+	var pendingRes *lnrpc.PendingChannelsResponse
+	err = retryRPC(cfg, "PendingChannels", func() (err error) {
+		ctx, cancel := cfg.rpcContext()
+		defer cancel()
+		pendingRes, err = lnd.PendingChannels(ctx, pendingReq)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rpc PendingChannels() failed: %w", err)
+	}
+	pending := &pendingChannels{
+		Opening:      pendingRes.PendingOpenChannels,
+		WaitingClose: pendingRes.WaitingCloseChannels,
+	}
+	for _, channel := range pending.Opening {
+		pending.Capacity += channel.Channel.Capacity
+	}
+	for _, channel := range pending.WaitingClose {
+		pending.Capacity += channel.Channel.Capacity
 	}
 
 	// Get the on-chain wallet balance.
 	walletBalanceReq := &lnrpc.WalletBalanceRequest{}
-	walletBalanceRes, err := lnd.WalletBalance(ctxb, walletBalanceReq)
+	var walletBalanceRes *lnrpc.WalletBalanceResponse
+	err = retryRPC(cfg, "WalletBalance", func() (err error) {
+		ctx, cancel := cfg.rpcContext()
+		defer cancel()
+		walletBalanceRes, err = lnd.WalletBalance(ctx, walletBalanceReq)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rpc WalletBalance() failed: %w", err)
+	}
+
+	homeCtx := &templateContext{
+		NodePubkey:         nodeInfo.IdentityPubkey,
+		NodeAddr:           nodeAddr,
+		NodeURIs:           nodeInfo.Uris,
+		Alias:              nodeInfo.Alias,
+		Color:              nodeColor(nodeInfo.Color),
+		Network:            activeNetwork,
+		DcrlndVersion:      nodeInfo.Version,
+		SyncedToChain:      nodeInfo.SyncedToChain,
+		BlockHeight:        nodeInfo.BlockHeight,
+		ChannelsCount:      nodeInfo.NumActiveChannels,
+		InactiveCount:      inactiveCount,
+		PeerCount:          peerCount,
+		ChannelPeerCount:   len(channelPeers),
+		Capacity:           totalCapacity,
+		Balance:            dcrutil.Amount(walletBalanceRes.ConfirmedBalance),
+		HubBalance:         dcrutil.Amount(walletBalanceRes.ConfirmedBalance),
+		UnconfirmedBalance: dcrutil.Amount(walletBalanceRes.UnconfirmedBalance),
+		TotalBalance: dcrutil.Amount(
+			walletBalanceRes.ConfirmedBalance +
+				walletBalanceRes.UnconfirmedBalance,
+		),
+		InboundCapacity:    dcrutil.Amount(inboundCapacity),
+		HubInboundCapacity: dcrutil.Amount(inboundCapacity),
+		TotalLocalBalance:  dcrutil.Amount(localBalance),
+		TotalRemoteBalance: dcrutil.Amount(remoteBalance),
+		ActiveChannels:     listChanRes.Channels,
+		PendingChannels:    pending,
+		SuggestedChannelSize: suggestChannelSize(
+			listChanRes.Channels, cfg,
+		),
+	}
+
+	// Creating invoices and addresses isn't allowed by a read-only
+	// macaroon, so there's nothing to donate to.
+	if cfg.readOnly() {
+		return homeCtx, nil
+	}
+
+	// Invoices expire, so a fresh donation invoice is created on every
+	// refresh instead of being cached indefinitely. Failing to create one
+	// only hides the off-chain donation option.
+	homeCtx.DonationInvoice, homeCtx.DonationExpiry, err = fetchDonationInvoice(
+		lnd, cfg, dcrutil.Amount(inboundCapacity),
+	)
+	if err != nil {
+		log.Warnf("Unable to create donation invoice: %v", err)
+	}
+
+	// Get the on-chain address for donations.
+	donationAddr, err := donationAddress(lnd, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("rpc WalletBalance() failed: %v", err)
+		return nil, err
 	}
-	log.Warn(nodeInfo.NumActiveChannels)
-	return &templateContext{
-		NodeAddr:       nodeAddr,
-		Network:        activeNetwork,
-		ChannelsCount:  nodeInfo.NumActiveChannels,
-		Capacity:       totalCapacity,
-		Balance:        dcrutil.Amount(walletBalanceRes.ConfirmedBalance),
-		ActiveChannels: listChanRes.Channels,
-	}, nil
+	homeCtx.DonationAddr = donationAddr
+	homeCtx.DonationAddrURI = decredURI(donationAddr, 0)
+	homeCtx.DonationURI = lightningURI(homeCtx.DonationInvoice)
+
+	return homeCtx, nil
 }
 
 // HomePage renders the home page for the hub.
 //
 // NOTE: This method implements the http.Handler interface.
 func (h *lightningHub) HomePage(w http.ResponseWriter, r *http.Request) {
+	defer h.metrics.observeHomePage(time.Now())
 
 	// First obtain the home template from our cache of pre-compiled
 	// templates.
-	homeTemplate := h.template.Lookup("index.html")
+	homeTemplate := h.lookupTemplate("index.html")
 	if homeTemplate == nil {
 		log.Error("unable to lookup index")
-		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		h.renderError(w, http.StatusInternalServerError)
 		return
 	}
 
 	// In order to render the home template we'll need the necessary
-	// context, so we'll grab that from the lnd daemon now in order to get
-	// the most up to date state.
-	homeInfo, err := fetchHomePage(h.lnd, h.cfg)
-	if err != nil {
+	// context, which is refreshed from the lnd daemon if what we have is
+	// too old.
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
 		log.Error("unable to fetch home state")
+
+		// When running degraded we let the visitor know the node is
+		// unavailable rather than showing a bare error.
+		if h.cfg.StartDegraded {
+			h.renderUnavailable(w)
+			return
+		}
+
 		http.Error(w, "unable to render home page", http.StatusInternalServerError)
 		return
 	}
 
+	page := h.homePage(r, homeInfo)
+
 	// If the method is GET, then we'll render the home page with the form
 	// itself.
 	switch {
 	case r.Method == http.MethodGet:
-		homeTemplate.Execute(w, homeInfo)
+		homeTemplate.Execute(w, page)
 
 		// If the method isn't either of those, then this is an error as we
 	// only support the two methods above.
@@ -185,3 +754,82 @@ func (h *lightningHub) HomePage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed!", http.StatusMethodNotAllowed)
 	}
 }
+
+// renderUnavailable renders the "node unavailable" page used while the hub
+// can't reach dcrlnd.
+func (h *lightningHub) renderUnavailable(w http.ResponseWriter) {
+	unavailableTemplate := h.lookupTemplate("unavailable.html")
+	if unavailableTemplate == nil {
+		log.Error("unable to lookup unavailable")
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusServiceUnavailable)
+	unavailableTemplate.Execute(w, h.baseContext("Node unavailable"))
+}
+
+// errorTitles are the titles of the templated error pages, keyed by their
+// status code.
+var errorTitles = map[int]string{
+	http.StatusNotFound:            "Page not found",
+	http.StatusInternalServerError: "Internal error",
+}
+
+// renderError renders the templated error page of the passed status code,
+// falling back to a plain text error if there's no template for it.
+func (h *lightningHub) renderError(w http.ResponseWriter, status int) {
+	errorTemplate := h.lookupTemplate(fmt.Sprintf("%d.html", status))
+	if errorTemplate == nil {
+		http.Error(w, fmt.Sprintf("%d %s.", status,
+			http.StatusText(status)), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	errorTemplate.Execute(w, h.baseContext(errorTitles[status]))
+}
+
+// NotFound renders the templated 404 page for the paths not served by the
+// hub.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) NotFound(w http.ResponseWriter, r *http.Request) {
+	h.renderError(w, http.StatusNotFound)
+}
+
+// Widget renders a compact stats card meant to be embedded on other websites
+// through an iframe. It's served from the cached context so embedding the
+// widget doesn't add load on dcrlnd beyond keeping the context fresh.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Widget(w http.ResponseWriter, r *http.Request) {
+	widgetTemplate := h.lookupTemplate("widget.html")
+	if widgetTemplate == nil {
+		log.Error("unable to lookup widget")
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The widget links back to the hub, which must be an absolute URL
+	// since it's rendered within another site.
+	page := &widgetPage{
+		baseContext:     h.baseContext("Widget"),
+		templateContext: homeInfo,
+		HubURL:          h.absoluteURL(r, "/"),
+	}
+
+	// Unlike the rest of the hub, the widget is meant to be framed by any
+	// site that wants to show our stats.
+	w.Header().Del("X-Frame-Options")
+	w.Header().Set("Content-Security-Policy",
+		frameableCSP(h.cfg.ContentSecurityPolicy))
+	widgetTemplate.Execute(w, page)
+}
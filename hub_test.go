@@ -0,0 +1,405 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestFetchHomePage checks that the home page context is built from the
+// responses of dcrlnd.
+func TestFetchHomePage(t *testing.T) {
+	lnd := &fakeLnd{
+		info: testnetInfo(),
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				RemotePubkey:           "02bb",
+				Active:                 true,
+				Capacity:               100000,
+				LocalBalance:           60000,
+				RemoteBalance:          40000,
+				RemoteChanReserveAtoms: 1000,
+			}, {
+				RemotePubkey: "02cc",
+				Capacity:     50000,
+			}},
+		},
+		balance: &lnrpc.WalletBalanceResponse{
+			ConfirmedBalance:   300000,
+			UnconfirmedBalance: 20000,
+		},
+		invoice: &lnrpc.AddInvoiceResponse{PaymentRequest: "lntdcr1"},
+		newAddr: &lnrpc.NewAddressResponse{Address: "TsAddr"},
+	}
+
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+
+	if homeCtx.Alias != "hub" || homeCtx.Network != "testnet" {
+		t.Fatalf("unexpected node %v on %v", homeCtx.Alias,
+			homeCtx.Network)
+	}
+	if homeCtx.Capacity != 150000 {
+		t.Fatalf("expected capacity 150000, got %v", homeCtx.Capacity)
+	}
+	if homeCtx.InactiveCount != 1 {
+		t.Fatalf("expected 1 inactive channel, got %v",
+			homeCtx.InactiveCount)
+	}
+	if homeCtx.InboundCapacity != 39000 {
+		t.Fatalf("expected inbound capacity 39000, got %v",
+			int64(homeCtx.InboundCapacity))
+	}
+	if homeCtx.TotalBalance != dcrutil.Amount(320000) {
+		t.Fatalf("expected total balance 320000, got %v",
+			int64(homeCtx.TotalBalance))
+	}
+	if homeCtx.DonationInvoice != "lntdcr1" {
+		t.Fatalf("unexpected donation invoice %q",
+			homeCtx.DonationInvoice)
+	}
+	if homeCtx.DonationAddr != "TsAddr" {
+		t.Fatalf("unexpected donation address %q", homeCtx.DonationAddr)
+	}
+}
+
+// TestFetchHomePageWrongNetwork checks that a node on another network than
+// the hub's is rejected.
+func TestFetchHomePageWrongNetwork(t *testing.T) {
+	info := testnetInfo()
+	info.Chains[0].Network = "mainnet"
+	lnd := &fakeLnd{info: info}
+
+	if _, err := fetchHomePage(lnd, newTestConfig()); err == nil {
+		t.Fatal("expected an error for a node on another network")
+	}
+	if n := lnd.callCount("ListChannels"); n != 0 {
+		t.Fatalf("expected no ListChannels call, got %d", n)
+	}
+}
+
+// TestFetchHomePageNoChains checks that a node which doesn't report its
+// chain yet, e.g. while it's starting up, is reported as an error rather than
+// crashing the hub.
+func TestFetchHomePageNoChains(t *testing.T) {
+	info := testnetInfo()
+	info.Chains = nil
+	lnd := &fakeLnd{info: info}
+
+	if _, err := fetchHomePage(lnd, newTestConfig()); err == nil {
+		t.Fatal("expected an error for a node without chain info")
+	}
+}
+
+// TestFetchHomePageBalances checks that the local and remote balances of the
+// active channels are summed, leaving the inactive ones out.
+func TestFetchHomePageBalances(t *testing.T) {
+	lnd := &fakeLnd{
+		info: testnetInfo(),
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				RemotePubkey:  "02bb",
+				Active:        true,
+				Capacity:      100000,
+				LocalBalance:  59000,
+				RemoteBalance: 40000,
+				CommitFee:     1000,
+			}, {
+				RemotePubkey:  "02cc",
+				Active:        true,
+				Capacity:      50000,
+				LocalBalance:  10000,
+				RemoteBalance: 39000,
+				CommitFee:     1000,
+			}, {
+				RemotePubkey:  "02dd",
+				Capacity:      70000,
+				LocalBalance:  69000,
+				RemoteBalance: 0,
+				CommitFee:     1000,
+			}},
+		},
+	}
+
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if homeCtx.TotalLocalBalance != 69000 {
+		t.Errorf("got local balance %d, want 69000",
+			int64(homeCtx.TotalLocalBalance))
+	}
+	if homeCtx.TotalRemoteBalance != 79000 {
+		t.Errorf("got remote balance %d, want 79000",
+			int64(homeCtx.TotalRemoteBalance))
+	}
+	want := 100 * 69000 / 148000.0
+	if percent := homeCtx.LocalPercent(); percent != want {
+		t.Errorf("got local percent %v, want %v", percent, want)
+	}
+}
+
+// TestFetchHomePageSync checks that a node out of sync with the chain is
+// reported with its block height and the time the stats were fetched.
+func TestFetchHomePageSync(t *testing.T) {
+	info := testnetInfo()
+	info.SyncedToChain = false
+	info.BlockHeight = 123456
+	lnd := &fakeLnd{info: info}
+
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if homeCtx.SyncedToChain || homeCtx.BlockHeight != 123456 {
+		t.Fatalf("got synced %v at height %d, want out of sync at "+
+			"123456", homeCtx.SyncedToChain, homeCtx.BlockHeight)
+	}
+
+	hub := newTestHub(t, newTestConfig(), lnd)
+	before := time.Now()
+	if err := hub.setContext(homeCtx); err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+	if homeCtx.LastUpdated.Before(before) {
+		t.Fatalf("got last updated %v, want at least %v",
+			homeCtx.LastUpdated, before)
+	}
+
+	w := httptest.NewRecorder()
+	hub.HomePage(w, httptest.NewRequest("GET", "/", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "still syncing to the chain") {
+		t.Error("out of sync warning not rendered")
+	}
+	if !strings.Contains(body, "Last updated 0s ago, at block 123456") {
+		t.Error("last update not rendered")
+	}
+}
+
+// TestFormatDCR checks that amounts of atoms are formatted in DCR, with as
+// many decimals as needed.
+func TestFormatDCR(t *testing.T) {
+	tests := []struct {
+		atoms int64
+		want  string
+	}{
+		{100000000, "1 DCR"},
+		{150000000, "1.5 DCR"},
+		{1, "0.00000001 DCR"},
+		{0, "0 DCR"},
+	}
+
+	for _, test := range tests {
+		if got := formatDCR(test.atoms); got != test.want {
+			t.Errorf("formatDCR(%d) = %q, want %q", test.atoms, got,
+				test.want)
+		}
+	}
+
+	homeCtx := &templateContext{Capacity: 100000000}
+	if got := homeCtx.CapacityDCR(); got != "1 DCR" {
+		t.Errorf("got capacity %q, want 1 DCR", got)
+	}
+}
+
+// TestFetchHomePagePeers checks that the connected peers are counted apart
+// from the peers the hub has channels with, and that failing to list them
+// doesn't fail the whole page.
+func TestFetchHomePagePeers(t *testing.T) {
+	lnd := &fakeLnd{
+		info: testnetInfo(),
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{
+				{RemotePubkey: "02bb", Active: true},
+				{RemotePubkey: "02bb"},
+				{RemotePubkey: "02cc", Active: true},
+			},
+		},
+		peers: &lnrpc.ListPeersResponse{
+			Peers: []*lnrpc.Peer{
+				{PubKey: "02bb"}, {PubKey: "02dd"}, {PubKey: "02ee"},
+			},
+		},
+	}
+
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if homeCtx.PeerCount != 3 || homeCtx.ChannelPeerCount != 2 {
+		t.Fatalf("expected 3 peers and 2 channel peers, got %d and %d",
+			homeCtx.PeerCount, homeCtx.ChannelPeerCount)
+	}
+
+	lnd.errs = map[string][]error{
+		"ListPeers": {status.Error(codes.Internal, "peers unavailable")},
+	}
+	homeCtx, err = fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("failing ListPeers failed the home page: %v", err)
+	}
+	if homeCtx.PeerCount != 0 || homeCtx.ChannelPeerCount != 2 {
+		t.Fatalf("expected 0 peers and 2 channel peers, got %d and %d",
+			homeCtx.PeerCount, homeCtx.ChannelPeerCount)
+	}
+}
+
+// TestFetchHomePageURIs checks that every URI advertised by the node is kept
+// and rendered with its copy button, the first one being the primary one.
+func TestFetchHomePageURIs(t *testing.T) {
+	info := testnetInfo()
+	info.Uris = []string{
+		"02aa@203.0.113.5:9735",
+		"02aa@hubhubhubhubhubh.onion:9735",
+	}
+	lnd := &fakeLnd{info: info}
+
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if homeCtx.NodeAddr != info.Uris[0] {
+		t.Fatalf("expected primary URI %v, got %v", info.Uris[0],
+			homeCtx.NodeAddr)
+	}
+	if !reflect.DeepEqual(homeCtx.NodeURIs, info.Uris) {
+		t.Fatalf("expected URIs %v, got %v", info.Uris,
+			homeCtx.NodeURIs)
+	}
+
+	hub := newTestHub(t, newTestConfig(), lnd)
+	if err := hub.setContext(homeCtx); err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+	w := httptest.NewRecorder()
+	hub.HomePage(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, uri := range info.Uris {
+		if !strings.Contains(body, `data-copy="`+uri+`"`) {
+			t.Fatalf("no copy button for %v", uri)
+		}
+	}
+	if !strings.Contains(body, `src="/static/copy.js"`) {
+		t.Fatal("copy script not included")
+	}
+}
+
+// TestFetchHomePageAlias checks that the alias and color of the node flow
+// through to the home page, falling back to the beginning of the pubkey when
+// the node has no alias.
+func TestFetchHomePageAlias(t *testing.T) {
+	tests := []struct {
+		name        string
+		alias       string
+		color       string
+		wantDisplay string
+		wantColor   string
+	}{{
+		name:        "alias and color",
+		alias:       "hub",
+		color:       "#3d8bff",
+		wantDisplay: "hub",
+		wantColor:   "#3d8bff",
+	}, {
+		name:        "no alias",
+		color:       "#3d8bff",
+		wantDisplay: featuredPubkey1[:16] + "…",
+		wantColor:   "#3d8bff",
+	}, {
+		name:        "malformed color",
+		alias:       "hub",
+		color:       "red;}",
+		wantDisplay: "hub",
+	}}
+
+	for _, test := range tests {
+		info := testnetInfo()
+		info.IdentityPubkey = featuredPubkey1
+		info.Alias = test.alias
+		info.Color = test.color
+		lnd := &fakeLnd{info: info}
+
+		homeCtx, err := fetchHomePage(lnd, newTestConfig())
+		if err != nil {
+			t.Errorf("%s: unable to fetch home page: %v", test.name,
+				err)
+			continue
+		}
+		if homeCtx.Alias != test.alias {
+			t.Errorf("%s: got alias %q, want %q", test.name,
+				homeCtx.Alias, test.alias)
+		}
+		if display := homeCtx.DisplayAlias(); display != test.wantDisplay {
+			t.Errorf("%s: got display alias %q, want %q", test.name,
+				display, test.wantDisplay)
+		}
+		if homeCtx.Color != test.wantColor {
+			t.Errorf("%s: got color %q, want %q", test.name,
+				homeCtx.Color, test.wantColor)
+		}
+	}
+}
+
+// TestFetchHomePagePending checks that the pending channels are listed apart
+// from the active ones, with their capacity kept out of the node's capacity.
+func TestFetchHomePagePending(t *testing.T) {
+	lnd := &fakeLnd{
+		info: testnetInfo(),
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				RemotePubkey: "02bb",
+				Active:       true,
+				Capacity:     100000,
+			}},
+		},
+		pending: &lnrpc.PendingChannelsResponse{
+			PendingOpenChannels: []*lnrpc.PendingChannelsResponse_PendingOpenChannel{{
+				Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+					RemoteNodePub: "02cc",
+					Capacity:      200000,
+				},
+			}},
+			WaitingCloseChannels: []*lnrpc.PendingChannelsResponse_WaitingCloseChannel{{
+				Channel: &lnrpc.PendingChannelsResponse_PendingChannel{
+					RemoteNodePub: "02dd",
+					Capacity:      30000,
+				},
+			}},
+		},
+	}
+
+	homeCtx, err := fetchHomePage(lnd, newTestConfig())
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+
+	if homeCtx.Capacity != 100000 || len(homeCtx.ActiveChannels) != 1 {
+		t.Fatalf("expected 1 active channel with capacity 100000, got "+
+			"%d with %d", len(homeCtx.ActiveChannels),
+			homeCtx.Capacity)
+	}
+	pending := homeCtx.PendingChannels
+	if len(pending.Opening) != 1 || len(pending.WaitingClose) != 1 {
+		t.Fatalf("expected 1 opening and 1 closing channel, got %d "+
+			"and %d", len(pending.Opening), len(pending.WaitingClose))
+	}
+	if pending.Capacity != 230000 {
+		t.Fatalf("expected pending capacity 230000, got %d",
+			pending.Capacity)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/gorilla/mux"
+)
+
+// invoiceResponse is the response of the invoice lookup endpoint.
+type invoiceResponse struct {
+	RHash      string         `json:"r_hash"`
+	Settled    bool           `json:"settled"`
+	Amount     dcrutil.Amount `json:"amount"`
+	AmountPaid dcrutil.Amount `json:"amount_paid"`
+	SettleTime *time.Time     `json:"settle_time,omitempty"`
+}
+
+// parseRHash parses the hex encoded payment hash of an invoice.
+func parseRHash(s string) ([]byte, error) {
+	rHash, err := hex.DecodeString(s)
+	if err != nil || len(rHash) != chainhash.HashSize {
+		return nil, fmt.Errorf("invalid payment hash, expected %d hex "+
+			"encoded bytes", chainhash.HashSize)
+	}
+
+	return rHash, nil
+}
+
+// isInvoiceNotFound returns true if the passed error was returned by
+// LookupInvoice because dcrlnd doesn't know the invoice.
+func isInvoiceNotFound(err error) bool {
+	return strings.Contains(err.Error(), "unable to locate invoice")
+}
+
+// Invoice lets donors check whether their donation invoice was settled. Only
+// donation invoices can be looked up, the node's other invoices are reported
+// as not found.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Invoice(w http.ResponseWriter, r *http.Request) {
+	rHash, err := parseRHash(mux.Vars(r)["rhash"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &lnrpc.PaymentHash{RHash: rHash}
+	ctx, cancel := h.cfg.rpcContext()
+	defer cancel()
+	invoice, err := h.client().LookupInvoice(ctx, req)
+	switch {
+	case err != nil && isInvoiceNotFound(err):
+		http.Error(w, "404 Not Found.", http.StatusNotFound)
+		return
+
+	case err != nil:
+		log.Errorf("rpc LookupInvoice() failed: %v", err)
+		http.Error(w, "unable to look up the invoice",
+			http.StatusBadGateway)
+		return
+
+	case !isDonationMemo(invoice.Memo):
+		http.Error(w, "404 Not Found.", http.StatusNotFound)
+		return
+	}
+
+	res := &invoiceResponse{
+		RHash:      hex.EncodeToString(rHash),
+		Settled:    invoice.State == lnrpc.Invoice_SETTLED,
+		Amount:     dcrutil.Amount(invoice.Value),
+		AmountPaid: dcrutil.Amount(invoice.AmtPaidAtoms),
+	}
+	if res.Settled && invoice.SettleDate != 0 {
+		settleTime := time.Unix(invoice.SettleDate, 0).UTC()
+		res.SettleTime = &settleTime
+	}
+
+	writeJSON(w, res)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/gorilla/mux"
+)
+
+// TestInvoice checks that donors can look up whether their donation invoice
+// was settled, and only donation invoices.
+func TestInvoice(t *testing.T) {
+	rHash := strings.Repeat("ab", 32)
+	settleDate := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		rHash       string
+		lookup      *lnrpc.Invoice
+		lookupErr   error
+		wantCode    int
+		wantSettled bool
+		wantPaid    int64
+	}{{
+		name:  "settled",
+		rHash: rHash,
+		lookup: &lnrpc.Invoice{
+			Memo:         donationInvoiceMemo(""),
+			Value:        1000,
+			AmtPaidAtoms: 1000,
+			State:        lnrpc.Invoice_SETTLED,
+			SettleDate:   settleDate.Unix(),
+		},
+		wantCode:    http.StatusOK,
+		wantSettled: true,
+		wantPaid:    1000,
+	}, {
+		name:  "unsettled",
+		rHash: rHash,
+		lookup: &lnrpc.Invoice{
+			Memo:  donationInvoiceMemo(""),
+			Value: 1000,
+			State: lnrpc.Invoice_OPEN,
+		},
+		wantCode: http.StatusOK,
+	}, {
+		name:  "not a donation",
+		rHash: rHash,
+		lookup: &lnrpc.Invoice{
+			Memo:  "payment",
+			Value: 1000,
+			State: lnrpc.Invoice_SETTLED,
+		},
+		wantCode: http.StatusNotFound,
+	}, {
+		name:      "not found",
+		rHash:     rHash,
+		lookupErr: fmt.Errorf("unable to locate invoice"),
+		wantCode:  http.StatusNotFound,
+	}, {
+		name:     "invalid hash",
+		rHash:    "abcd",
+		wantCode: http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		lnd := &fakeLnd{lookup: test.lookup}
+		if test.lookupErr != nil {
+			lnd.errs = map[string][]error{
+				"LookupInvoice": {test.lookupErr},
+			}
+		}
+		hub := newTestHub(t, newTestConfig(), lnd)
+
+		r := mux.NewRouter()
+		r.HandleFunc("/invoice/{rhash}", hub.Invoice)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET",
+			"/invoice/"+test.rHash, nil))
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var res invoiceResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Errorf("%s: unable to parse response: %v", test.name,
+				err)
+			continue
+		}
+		if res.RHash != rHash || res.Amount != 1000 {
+			t.Errorf("%s: got invoice %s of %d atoms, want %s of "+
+				"1000", test.name, res.RHash, int64(res.Amount),
+				rHash)
+		}
+		if res.Settled != test.wantSettled ||
+			int64(res.AmountPaid) != test.wantPaid {
+
+			t.Errorf("%s: got settled %v with %d atoms paid, want "+
+				"%v with %d", test.name, res.Settled,
+				int64(res.AmountPaid), test.wantSettled,
+				test.wantPaid)
+		}
+		switch {
+		case test.wantSettled && (res.SettleTime == nil ||
+			!res.SettleTime.Equal(settleDate)):
+
+			t.Errorf("%s: got settle time %v, want %v", test.name,
+				res.SettleTime, settleDate)
+
+		case !test.wantSettled && res.SettleTime != nil:
+			t.Errorf("%s: got settle time %v, want none",
+				test.name, res.SettleTime)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/walletrpc"
+	"github.com/decred/dcrlnd/macaroons"
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+// The startup backoffs are variables so tests can shorten them.
+var (
+	// startupInitialBackoff is how long to wait before retrying to reach
+	// dcrlnd after the first failed attempt at startup. It's doubled after
+	// every failed attempt, up to startupMaxBackoff.
+	startupInitialBackoff = time.Second
+
+	// startupMaxBackoff is the maximum time to wait between two attempts
+	// to reach dcrlnd at startup.
+	startupMaxBackoff = 30 * time.Second
+)
+
+// lndClient is the subset of dcrlnd's Lightning service used by the hub. The
+// hub depends on it rather than on the full generated client so it can be
+// served by any implementation, e.g. a fake node in tests.
+type lndClient interface {
+	GetInfo(ctx context.Context, in *lnrpc.GetInfoRequest,
+		opts ...grpc.CallOption) (*lnrpc.GetInfoResponse, error)
+
+	GetNodeInfo(ctx context.Context, in *lnrpc.NodeInfoRequest,
+		opts ...grpc.CallOption) (*lnrpc.NodeInfo, error)
+
+	ListChannels(ctx context.Context, in *lnrpc.ListChannelsRequest,
+		opts ...grpc.CallOption) (*lnrpc.ListChannelsResponse, error)
+
+	PendingChannels(ctx context.Context, in *lnrpc.PendingChannelsRequest,
+		opts ...grpc.CallOption) (*lnrpc.PendingChannelsResponse, error)
+
+	ClosedChannels(ctx context.Context, in *lnrpc.ClosedChannelsRequest,
+		opts ...grpc.CallOption) (*lnrpc.ClosedChannelsResponse, error)
+
+	FeeReport(ctx context.Context, in *lnrpc.FeeReportRequest,
+		opts ...grpc.CallOption) (*lnrpc.FeeReportResponse, error)
+
+	WalletBalance(ctx context.Context, in *lnrpc.WalletBalanceRequest,
+		opts ...grpc.CallOption) (*lnrpc.WalletBalanceResponse, error)
+
+	ListUnspent(ctx context.Context, in *lnrpc.ListUnspentRequest,
+		opts ...grpc.CallOption) (*lnrpc.ListUnspentResponse, error)
+
+	NewAddress(ctx context.Context, in *lnrpc.NewAddressRequest,
+		opts ...grpc.CallOption) (*lnrpc.NewAddressResponse, error)
+
+	AddInvoice(ctx context.Context, in *lnrpc.Invoice,
+		opts ...grpc.CallOption) (*lnrpc.AddInvoiceResponse, error)
+
+	LookupInvoice(ctx context.Context, in *lnrpc.PaymentHash,
+		opts ...grpc.CallOption) (*lnrpc.Invoice, error)
+
+	ListInvoices(ctx context.Context, in *lnrpc.ListInvoiceRequest,
+		opts ...grpc.CallOption) (*lnrpc.ListInvoiceResponse, error)
+
+	SubscribeInvoices(ctx context.Context, in *lnrpc.InvoiceSubscription,
+		opts ...grpc.CallOption) (lnrpc.Lightning_SubscribeInvoicesClient,
+		error)
+
+	ListPeers(ctx context.Context, in *lnrpc.ListPeersRequest,
+		opts ...grpc.CallOption) (*lnrpc.ListPeersResponse, error)
+
+	ConnectPeer(ctx context.Context, in *lnrpc.ConnectPeerRequest,
+		opts ...grpc.CallOption) (*lnrpc.ConnectPeerResponse, error)
+
+	OpenChannelSync(ctx context.Context, in *lnrpc.OpenChannelRequest,
+		opts ...grpc.CallOption) (*lnrpc.ChannelPoint, error)
+
+	QueryRoutes(ctx context.Context, in *lnrpc.QueryRoutesRequest,
+		opts ...grpc.CallOption) (*lnrpc.QueryRoutesResponse, error)
+
+	SendToRouteSync(ctx context.Context, in *lnrpc.SendToRouteRequest,
+		opts ...grpc.CallOption) (*lnrpc.SendResponse, error)
+}
+
+// newLndClient returns a client of dcrlnd's Lightning service over the passed
+// connection. It's a variable so tests can substitute a fake node.
+var newLndClient = func(conn *grpc.ClientConn) lndClient {
+	return lnrpc.NewLightningClient(conn)
+}
+
+// walletKitClient is the subset of dcrlnd's WalletKit sub-server used by the
+// hub.
+type walletKitClient interface {
+	BumpFee(ctx context.Context, in *walletrpc.BumpFeeRequest,
+		opts ...grpc.CallOption) (*walletrpc.BumpFeeResponse, error)
+}
+
+// newWalletKitClient returns a client of dcrlnd's WalletKit sub-server over
+// the passed connection. It's a variable so tests can substitute a fake node.
+var newWalletKitClient = func(conn *grpc.ClientConn) walletKitClient {
+	return walletrpc.NewWalletKitClient(conn)
+}
+
+// tlsCertPEM returns the inline TLS certificate of dcrlnd. Config files
+// can't hold multi-line values, so escaped newlines are unescaped.
+func (c *config) tlsCertPEM() []byte {
+	return []byte(strings.ReplaceAll(c.TLSCertPEM, `\n`, "\n"))
+}
+
+// parseCertPEM returns a certificate pool of the certificates of the passed
+// PEM data, which must hold at least one.
+func parseCertPEM(pemData []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	return pool, nil
+}
+
+// transportCredentials returns the TLS credentials used to reach dcrlnd,
+// trusting its inline certificate if set, or else its certificate file.
+func transportCredentials(cfg *config) (credentials.TransportCredentials,
+	error) {
+
+	if cfg.TLSCertPEM == "" {
+		tlsCertPath := cleanAndExpandPath(cfg.TLSCertPath)
+		creds, err := credentials.NewClientTLSFromFile(tlsCertPath, "")
+		if err != nil {
+			return nil, fmt.Errorf("unable to read cert file: %v", err)
+		}
+		return creds, nil
+	}
+
+	pool, err := parseCertPEM(cfg.tlsCertPEM())
+	if err != nil {
+		return nil, fmt.Errorf("invalid tlscert_pem: %v", err)
+	}
+	return credentials.NewClientTLSFromCert(pool, ""), nil
+}
+
+// dialLnd establishes a new gRPC connection to dcrlnd using the TLS
+// certificate and macaroon from the passed config.
+func dialLnd(cfg *config) (*grpc.ClientConn, error) {
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+
+		// Identify ourselves to dcrlnd so node operators can tell
+		// which client (and which version of it) is connecting when
+		// reviewing its logs.
+		grpc.WithUserAgent(userAgent()),
+	}
+
+	// Reach dcrlnd through the SOCKS5 proxy when one is configured, e.g.
+	// when it's only reachable over Tor.
+	if cfg.SOCKSProxy != "" {
+		dialer, err := proxy.SOCKS5("tcp", cfg.SOCKSProxy, nil, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create SOCKS5 dialer: %v",
+				err)
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("SOCKS5 dialer doesn't support " +
+				"contexts")
+		}
+		opts = append(opts, grpc.WithContextDialer(
+			func(ctx context.Context, addr string) (net.Conn, error) {
+				return contextDialer.DialContext(ctx, "tcp", addr)
+			},
+		))
+	}
+
+	mac, err := loadMacaroon(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Now we append the macaroon credentials to the dial options.
+	opts = append(
+		opts,
+		grpc.WithPerRPCCredentials(macaroons.NewMacaroonCredential(mac)),
+	)
+	// By default the dial doesn't block, so connection failures only
+	// surface on the first RPC. When a dial timeout is configured, we
+	// instead wait for the connection to be established so failures are
+	// reported right away, with the actual cause when it's not transient.
+	if cfg.DialTimeout <= 0 {
+		conn, err := grpc.Dial(cfg.RPCHost, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to dial to dcrlnd's gRPC "+
+				"server: %v", err)
+		}
+		return conn, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctxb, cfg.DialTimeout)
+	defer cancel()
+	opts = append(opts, grpc.WithBlock(), grpc.FailOnNonTempDialError(true))
+	conn, err := grpc.DialContext(ctx, cfg.RPCHost, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to dcrlnd's gRPC "+
+			"server at %v within %v: %v", cfg.RPCHost,
+			cfg.DialTimeout, err)
+	}
+
+	return conn, nil
+}
+
+// checkFile returns an error describing the passed file, and what to do about
+// it, if it can't be accessed.
+func checkFile(path, what, hint string) error {
+	_, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("%s not found at %s, %s", what, path, hint)
+	case err != nil:
+		return fmt.Errorf("unable to access %s at %s: %v", what, path,
+			err)
+	default:
+		return nil
+	}
+}
+
+// checkDcrlndFiles checks that the TLS certificate and macaroon used to reach
+// dcrlnd exist. macaroonDerived is true when the macaroon path was derived
+// from the selected network rather than set by the user, in which case a
+// missing macaroon usually means the wrong network was selected.
+func checkDcrlndFiles(cfg *config, macaroonDerived bool) error {
+	// There's no file to check when the certificate is passed inline.
+	if cfg.TLSCertPEM == "" {
+		err := checkFile(
+			cleanAndExpandPath(cfg.TLSCertPath),
+			"dcrlnd's TLS certificate", "set certpath to its location",
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch {
+	case cfg.readOnly():
+		err = checkFile(
+			cleanAndExpandPath(cfg.ReadOnlyMacaroonPath),
+			"the read-only macaroon", "check readonly_macpath",
+		)
+
+	case cfg.MacaroonHex != "":
+		// The macaroon is passed inline, there's no file to check.
+
+	case macaroonDerived:
+		err = checkFile(
+			cleanAndExpandPath(cfg.MacaroonPath), "dcrlnd's macaroon",
+			fmt.Sprintf("the path was derived from the %s network, "+
+				"select the network dcrlnd runs on or set "+
+				"macpath to its location", cfg.Network),
+		)
+
+	default:
+		err = checkFile(
+			cleanAndExpandPath(cfg.MacaroonPath), "dcrlnd's macaroon",
+			"set macpath to its location",
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, node := range cfg.nodes {
+		err := checkFile(
+			cleanAndExpandPath(node.TLSCertPath),
+			fmt.Sprintf("the TLS certificate of node %s", node.Name),
+			"check its node option",
+		)
+		if err != nil {
+			return err
+		}
+
+		err = checkFile(
+			cleanAndExpandPath(node.MacaroonPath),
+			fmt.Sprintf("the macaroon of node %s", node.Name),
+			"check its node option",
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadMacaroon loads the macaroon used to authenticate to dcrlnd. The
+// read-only macaroon is used when the hub runs read-only, otherwise it's
+// loaded from the configured hex string or, when it's not set, from the
+// macaroon file.
+func loadMacaroon(cfg *config) (*macaroon.Macaroon, error) {
+	var (
+		macBytes []byte
+		err      error
+	)
+	switch {
+	case cfg.readOnly():
+		macPath := cleanAndExpandPath(cfg.ReadOnlyMacaroonPath)
+		macBytes, err = ioutil.ReadFile(macPath)
+		if err != nil {
+			return nil, err
+		}
+
+	case cfg.MacaroonHex != "":
+		macBytes, err = hex.DecodeString(cfg.MacaroonHex)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode macaroon hex: %v",
+				err)
+		}
+
+	default:
+		macPath := cleanAndExpandPath(cfg.MacaroonPath)
+		macBytes, err = ioutil.ReadFile(macPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(macBytes); err != nil {
+		return nil, fmt.Errorf("invalid macaroon: %v", err)
+	}
+
+	return mac, nil
+}
+
+// rpcContext returns the context of an RPC to dcrlnd, which times out after
+// the configured RPC timeout so a hung dcrlnd can't block the hub forever.
+// The returned cancel function must be called once the RPC returns.
+func (c *config) rpcContext() (context.Context, context.CancelFunc) {
+	if c.RPCTimeout == 0 {
+		return context.WithCancel(ctxb)
+	}
+
+	return context.WithTimeout(ctxb, c.RPCTimeout)
+}
+
+// grpcCode returns the gRPC status code of the passed error, or any error it
+// wraps, or codes.Unknown if it isn't a gRPC error.
+func grpcCode(err error) codes.Code {
+	var grpcErr interface {
+		GRPCStatus() *status.Status
+	}
+	if !errors.As(err, &grpcErr) {
+		return codes.Unknown
+	}
+
+	return grpcErr.GRPCStatus().Code()
+}
+
+// isUnauthenticated returns true if the passed error, or any error it wraps,
+// is a gRPC Unauthenticated error.
+func isUnauthenticated(err error) bool {
+	return grpcCode(err) == codes.Unauthenticated
+}
+
+// isUnavailable returns true if the passed error, or any error it wraps, is a
+// gRPC Unavailable error, which is what we get while dcrlnd is down.
+func isUnavailable(err error) bool {
+	return grpcCode(err) == codes.Unavailable
+}
+
+// client returns the current client to dcrlnd.
+func (h *lightningHub) client() lndClient {
+	h.connMtx.RLock()
+	defer h.connMtx.RUnlock()
+	return h.lnd
+}
+
+// walletKit returns a client to dcrlnd's WalletKit sub-server over the
+// current connection.
+func (h *lightningHub) walletKit() walletKitClient {
+	h.connMtx.RLock()
+	defer h.connMtx.RUnlock()
+	return newWalletKitClient(h.conn)
+}
+
+// Stop stops the background refresher, persists the history and closes the
+// connection to dcrlnd.
+func (h *lightningHub) Stop() {
+	close(h.quit)
+
+	if err := h.history.flush(); err != nil {
+		log.Errorf("Unable to save history: %v", err)
+	}
+
+	h.connMtx.Lock()
+	defer h.connMtx.Unlock()
+
+	if err := h.conn.Close(); err != nil {
+		log.Errorf("Unable to close connection to dcrlnd: %v", err)
+	}
+	for _, node := range h.nodes {
+		if err := node.conn.Close(); err != nil {
+			log.Errorf("Unable to close connection to node %v: %v",
+				node.name, err)
+		}
+	}
+}
+
+// reconnect dials dcrlnd again, re-reading the TLS certificate and macaroon
+// from disk, and replaces the current connection with the new one.
+func (h *lightningHub) reconnect() error {
+	conn, err := dialLnd(h.cfg)
+	if err != nil {
+		return err
+	}
+
+	h.connMtx.Lock()
+	oldConn := h.conn
+	h.conn = conn
+	h.lnd = newLndClient(conn)
+	h.connMtx.Unlock()
+
+	if oldConn != nil {
+		oldConn.Close()
+	}
+
+	return nil
+}
+
+// refresh fetches a new home page context from dcrlnd.
+//
+// If dcrlnd's macaroon root key was rotated, the loaded macaroon is no longer
+// valid and every RPC fails as Unauthenticated. In that case the macaroon is
+// re-read from disk, since dcrlnd may have rewritten it, and the fetch is
+// attempted once more over a fresh connection before giving up.
+//
+// Likewise, if dcrlnd is unavailable, e.g. because it was restarted, the
+// connection is dialed again rather than waiting for gRPC's reconnect backoff,
+// which may grow up to minutes, so the hub recovers as soon as dcrlnd is back.
+func (h *lightningHub) refresh() (*templateContext, error) {
+	homeCtx, err := h.fetchHomePage()
+	switch {
+	case err == nil:
+		return homeCtx, nil
+
+	// A macaroon passed as hex can't have been rewritten, so there's no
+	// point in trying again with it.
+	case isUnauthenticated(err) && h.cfg.MacaroonHex != "":
+		return nil, err
+
+	case isUnauthenticated(err):
+		log.Warnf("dcrlnd rejected our macaroon, re-reading it from "+
+			"%v: %v", h.cfg.MacaroonPath, err)
+
+	case isUnavailable(err):
+		log.Warnf("dcrlnd is unavailable, reconnecting: %v", err)
+
+	default:
+		return nil, err
+	}
+
+	if err := h.reconnect(); err != nil {
+		return nil, fmt.Errorf("unable to reconnect to dcrlnd: %v", err)
+	}
+
+	return h.fetchHomePage()
+}
+
+// fetchInitialContext fetches the first home page context when the hub
+// starts. As dcrlnd may still be starting up itself, failed fetches are
+// retried with an exponential backoff, up to the configured number of
+// attempts and startup timeout.
+func (h *lightningHub) fetchInitialContext() (*templateContext, error) {
+	var deadline time.Time
+	if h.cfg.StartupTimeout > 0 {
+		deadline = time.Now().Add(h.cfg.StartupTimeout)
+	}
+
+	backoff := startupInitialBackoff
+	for attempt := 1; ; attempt++ {
+		homeCtx, err := h.fetchHomePage()
+		if err == nil {
+			return homeCtx, nil
+		}
+
+		log.Warnf("Unable to reach dcrlnd (attempt %d/%d): %v",
+			attempt, h.cfg.StartupAttempts, err)
+		if attempt >= h.cfg.StartupAttempts {
+			return nil, err
+		}
+		if !deadline.IsZero() && time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("gave up after %v: %w",
+				h.cfg.StartupTimeout, err)
+		}
+
+		log.Infof("Retrying in %v", backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > startupMaxBackoff {
+			backoff = startupMaxBackoff
+		}
+	}
+}
+
+// fetchHomePage fetches the home page context from dcrlnd and complements it
+// with the state kept by the hub itself.
+func (h *lightningHub) fetchHomePage() (*templateContext, error) {
+	homeCtx, err := fetchHomePage(h.client(), h.cfg)
+	if err != nil {
+		return nil, err
+	}
+	homeCtx.TotalDonated = h.donations.total()
+	homeCtx.FeaturedPeers = h.featuredPeers(homeCtx.ActiveChannels)
+	h.aggregateNodes(homeCtx)
+	if stats := h.graphStats.get(); stats != nil {
+		homeCtx.GraphChannels = stats.Channels
+		homeCtx.GraphCapacity = stats.Capacity
+	}
+
+	return homeCtx, nil
+}
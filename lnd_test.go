@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/macaroon.v2"
+)
+
+// TestRPCContext checks that the RPCs give up once the configured timeout
+// is reached, reporting it as a deadline exceeded error.
+func TestRPCContext(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.RPCTimeout = 20 * time.Millisecond
+	lnd := &fakeLnd{info: testnetInfo(), delay: time.Second}
+
+	start := time.Now()
+	_, err := fetchHomePage(lnd, cfg)
+	if err == nil {
+		t.Fatal("expected the RPC to time out")
+	}
+	if elapsed := time.Since(start); elapsed >= lnd.delay {
+		t.Fatalf("RPC not cancelled after the timeout, took %v",
+			elapsed)
+	}
+	if code := grpcCode(err); code != codes.DeadlineExceeded {
+		t.Fatalf("got error %v, want code %v", err,
+			codes.DeadlineExceeded)
+	}
+
+	// Without a timeout, the RPCs have no deadline.
+	cfg.RPCTimeout = 0
+	ctx, cancel := cfg.rpcContext()
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("got a deadline without an RPC timeout")
+	}
+}
+
+// newTestMacaroon returns a serialized macaroon with the passed id.
+func newTestMacaroon(t *testing.T, id string) []byte {
+	t.Helper()
+
+	mac, err := macaroon.New(
+		[]byte("root key"), []byte(id), "lnd", macaroon.LatestVersion,
+	)
+	if err != nil {
+		t.Fatalf("unable to create macaroon: %v", err)
+	}
+	macBytes, err := mac.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to serialize macaroon: %v", err)
+	}
+
+	return macBytes
+}
+
+// TestCheckDcrlndFiles checks that a missing certificate or macaroon is
+// reported with the missing file, and the network a derived path came from.
+func TestCheckDcrlndFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "tls.cert")
+	macPath := filepath.Join(dir, "admin.macaroon")
+	for _, path := range []string{certPath, macPath} {
+		if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+	missingPath := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name     string
+		certPath string
+		macPath  string
+		derived  bool
+		wantErr  []string
+	}{{
+		name:     "both present",
+		certPath: certPath,
+		macPath:  macPath,
+	}, {
+		name:     "missing cert",
+		certPath: missingPath,
+		macPath:  macPath,
+		wantErr:  []string{"TLS certificate", missingPath},
+	}, {
+		name:     "missing macaroon",
+		certPath: certPath,
+		macPath:  missingPath,
+		wantErr:  []string{"macaroon", missingPath, "macpath"},
+	}, {
+		name:     "missing derived macaroon",
+		certPath: certPath,
+		macPath:  missingPath,
+		derived:  true,
+		wantErr:  []string{"macaroon", missingPath, "testnet network"},
+	}}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.TLSCertPath = test.certPath
+		cfg.MacaroonPath = test.macPath
+
+		err := checkDcrlndFiles(cfg, test.derived)
+		if (err != nil) != (test.wantErr != nil) {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr != nil)
+			continue
+		}
+		for _, want := range test.wantErr {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: got error %q, want it to mention %q",
+					test.name, err, want)
+			}
+		}
+	}
+}
+
+// TestLoadMacaroon checks that the macaroon is loaded from the configured
+// hex string when set, and from the macaroon file otherwise.
+func TestLoadMacaroon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	macPath := filepath.Join(dir, "admin.macaroon")
+	err = ioutil.WriteFile(macPath, newTestMacaroon(t, "file"), 0600)
+	if err != nil {
+		t.Fatalf("unable to write macaroon: %v", err)
+	}
+	readOnlyPath := filepath.Join(dir, "readonly.macaroon")
+	err = ioutil.WriteFile(readOnlyPath, newTestMacaroon(t, "readonly"),
+		0600)
+	if err != nil {
+		t.Fatalf("unable to write macaroon: %v", err)
+	}
+	macHex := hex.EncodeToString(newTestMacaroon(t, "hex"))
+
+	tests := []struct {
+		name         string
+		network      string
+		macaroonHex  string
+		readOnlyPath string
+		wantID       string
+		wantErr      bool
+	}{{
+		name:   "file",
+		wantID: "file",
+	}, {
+		name:        "hex",
+		macaroonHex: macHex,
+		wantID:      "hex",
+	}, {
+		name:         "read-only",
+		network:      "mainnet",
+		macaroonHex:  macHex,
+		readOnlyPath: readOnlyPath,
+		wantID:       "readonly",
+	}, {
+		name:        "invalid hex",
+		macaroonHex: "not hex",
+		wantErr:     true,
+	}, {
+		name:        "invalid macaroon",
+		macaroonHex: "0badc0de",
+		wantErr:     true,
+	}}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		if test.network != "" {
+			cfg.Network = test.network
+		}
+		cfg.MacaroonPath = macPath
+		cfg.MacaroonHex = test.macaroonHex
+		cfg.ReadOnlyMacaroonPath = test.readOnlyPath
+
+		mac, err := loadMacaroon(cfg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if err == nil && string(mac.Id()) != test.wantID {
+			t.Errorf("%s: got macaroon %q, want %q", test.name,
+				mac.Id(), test.wantID)
+		}
+	}
+}
+
+// TestTransportCredentials checks that the credentials built from the inline
+// PEM certificate and from the certificate file both complete a TLS
+// handshake with dcrlnd, and only with it.
+func TestTransportCredentials(t *testing.T) {
+	serverCert := newTestCert(t, "dcrlnd", true, nil)
+	otherCert := newTestCert(t, "other", true, nil)
+	certPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: serverCert.Certificate[0],
+	})
+	otherPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: otherCert.Certificate[0],
+	})
+
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certPath := filepath.Join(dir, "tls.cert")
+	if err := ioutil.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatalf("unable to write cert: %v", err)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+	})
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name          string
+		certPath      string
+		certPEM       string
+		wantErr       bool
+		wantHandshake bool
+	}{{
+		name:          "file",
+		certPath:      certPath,
+		wantHandshake: true,
+	}, {
+		name:          "inline PEM",
+		certPath:      filepath.Join(dir, "missing.cert"),
+		certPEM:       string(certPEM),
+		wantHandshake: true,
+	}, {
+		name:     "escaped inline PEM",
+		certPath: filepath.Join(dir, "missing.cert"),
+		certPEM: strings.ReplaceAll(
+			string(certPEM), "\n", `\n`,
+		),
+		wantHandshake: true,
+	}, {
+		name:     "other inline PEM",
+		certPath: certPath,
+		certPEM:  string(otherPEM),
+	}, {
+		name:     "missing file",
+		certPath: filepath.Join(dir, "missing.cert"),
+		wantErr:  true,
+	}, {
+		name:     "invalid inline PEM",
+		certPath: certPath,
+		certPEM:  "not a certificate",
+		wantErr:  true,
+	}}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.TLSCertPath = test.certPath
+		cfg.TLSCertPEM = test.certPEM
+
+		creds, err := transportCredentials(cfg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("%s: unable to dial: %v", test.name, err)
+		}
+		ctx, cancel := context.WithTimeout(
+			context.Background(), 5*time.Second,
+		)
+		tlsConn, _, err := creds.ClientHandshake(
+			ctx, l.Addr().String(), conn,
+		)
+		cancel()
+		if err == nil {
+			tlsConn.Close()
+		} else {
+			conn.Close()
+		}
+
+		if (err == nil) != test.wantHandshake {
+			t.Errorf("%s: got handshake error %v, want handshake %v",
+				test.name, err, test.wantHandshake)
+		}
+	}
+}
+
+// TestDialLndSOCKSProxy checks that dcrlnd is reached through the SOCKS5
+// proxy when one is configured.
+func TestDialLndSOCKSProxy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+
+	// The proxy reports the address it's asked to connect to, then
+	// refuses the connection.
+	targets := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			// Greeting: version, number of methods and methods.
+			greeting := make([]byte, 2)
+			if _, err := io.ReadFull(conn, greeting); err != nil {
+				conn.Close()
+				continue
+			}
+			methods := make([]byte, greeting[1])
+			io.ReadFull(conn, methods)
+			conn.Write([]byte{5, 0})
+
+			// Request: version, command, reserved, IPv4 address
+			// type, address and port.
+			req := make([]byte, 10)
+			if _, err := io.ReadFull(conn, req); err == nil {
+				port := int(req[8])<<8 | int(req[9])
+				targets <- fmt.Sprintf("%v:%d", net.IP(req[4:8]),
+					port)
+			}
+			conn.Close()
+		}
+	}()
+
+	cert := newTestCert(t, "dcrlnd", true, nil)
+	cfg := newTestConfig()
+	cfg.TLSCertPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Certificate[0],
+	}))
+	cfg.MacaroonHex = hex.EncodeToString(newTestMacaroon(t, "admin"))
+	cfg.RPCHost = "127.0.0.1:10009"
+	cfg.SOCKSProxy = l.Addr().String()
+	cfg.DialTimeout = time.Second
+
+	// The proxy refuses the connection, so only the attempt matters.
+	if conn, err := dialLnd(cfg); err == nil {
+		conn.Close()
+	}
+
+	select {
+	case target := <-targets:
+		if target != cfg.RPCHost {
+			t.Fatalf("got proxied connection to %v, want %v",
+				target, cfg.RPCHost)
+		}
+	default:
+		t.Fatal("dcrlnd wasn't dialed through the proxy")
+	}
+}
+
+// TestFetchInitialContext checks that reaching dcrlnd at startup is retried
+// until it succeeds, up to the configured number of attempts.
+func TestFetchInitialContext(t *testing.T) {
+	origBackoff := startupInitialBackoff
+	startupInitialBackoff = time.Millisecond
+	defer func() {
+		startupInitialBackoff = origBackoff
+	}()
+
+	starting := fmt.Errorf("dcrlnd is starting")
+	tests := []struct {
+		name      string
+		errs      []error
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{{
+		name:      "fails twice then succeeds",
+		errs:      []error{starting, starting, nil},
+		attempts:  5,
+		wantCalls: 3,
+	}, {
+		name:      "attempts exhausted",
+		errs:      []error{starting},
+		attempts:  2,
+		wantCalls: 2,
+		wantErr:   true,
+	}}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.StartupAttempts = test.attempts
+		lnd := &fakeLnd{
+			info: testnetInfo(),
+			errs: map[string][]error{"GetInfo": test.errs},
+		}
+		hub := newTestHub(t, cfg, lnd)
+
+		homeCtx, err := hub.fetchInitialContext()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if err == nil && homeCtx.Alias != "hub" {
+			t.Errorf("%s: got alias %q, want hub", test.name,
+				homeCtx.Alias)
+		}
+		if n := lnd.callCount("GetInfo"); n != test.wantCalls {
+			t.Errorf("%s: got %d GetInfo calls, want %d", test.name,
+				n, test.wantCalls)
+		}
+	}
+}
+
+// TestRefreshReconnect checks that a refresh failing because dcrlnd is
+// unavailable dials dcrlnd again and retries over the new connection, so the
+// hub recovers as soon as dcrlnd is back.
+func TestRefreshReconnect(t *testing.T) {
+	cert := newTestCert(t, "dcrlnd", true, nil)
+	cfg := newTestConfig()
+	cfg.TLSCertPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Certificate[0],
+	}))
+	cfg.MacaroonHex = hex.EncodeToString(newTestMacaroon(t, "admin"))
+	cfg.RPCHost = "127.0.0.1:10009"
+
+	// dcrlnd is down on the first call, and back once reconnected.
+	lnd := &fakeLnd{
+		info: testnetInfo(),
+		errs: map[string][]error{
+			"GetInfo": {status.Error(codes.Unavailable, "down"), nil},
+		},
+	}
+	origNewLndClient := newLndClient
+	var reconnects int
+	newLndClient = func(conn *grpc.ClientConn) lndClient {
+		reconnects++
+		return lnd
+	}
+	defer func() {
+		newLndClient = origNewLndClient
+	}()
+
+	hub := newTestHub(t, cfg, lnd)
+	refresh := hub.triggerRefresh()
+	<-refresh.done
+	if hub.conn != nil {
+		defer hub.conn.Close()
+	}
+
+	if refresh.err != nil {
+		t.Fatalf("unable to refresh: %v", refresh.err)
+	}
+	if reconnects != 1 {
+		t.Fatalf("expected 1 reconnect, got %d", reconnects)
+	}
+	if n := lnd.callCount("GetInfo"); n != 2 {
+		t.Fatalf("expected 2 GetInfo calls, got %d", n)
+	}
+	if hub.cachedContext() == nil {
+		t.Fatal("refreshed context not cached")
+	}
+}
@@ -5,22 +5,103 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/decred/slog"
 	"github.com/jrick/logrotate/rotator"
 )
 
 // logWriter implements an io.Writer that outputs to both standard output and
-// the write-end pipe of an initialized log rotator.
+// the write-end pipe of an initialized log rotator.  When no log rotator was
+// initialized, because the hub runs without a data directory, it outputs to
+// standard error only.
 type logWriter struct{}
 
 func (logWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+	if logJSON {
+		p = jsonLogLine(p)
+	}
+
+	if logRotator == nil {
+		os.Stderr.Write(p)
+		return n, nil
+	}
+
 	os.Stdout.Write(p)
 	logRotator.Write(p)
-	return len(p), nil
+	return n, nil
+}
+
+const (
+	// logFormatText writes the log lines as formatted by slog.
+	logFormatText = "text"
+
+	// logFormatJSON writes each log line as a JSON object.
+	logFormatJSON = "json"
+)
+
+// slogLinePattern matches a line written by the slog backend, capturing its
+// timestamp, level, subsystem and message.
+var slogLinePattern = regexp.MustCompile(
+	`(?s)^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}) \[(\w{3})\] (\w+): (.*?)\n?$`,
+)
+
+// slogTimeLayout is the layout of the timestamps written by the slog backend.
+const slogTimeLayout = "2006-01-02 15:04:05.000"
+
+// logLevels maps the levels written by the slog backend to the ones used in
+// JSON log lines.
+var logLevels = map[string]string{
+	"TRC": "trace",
+	"DBG": "debug",
+	"INF": "info",
+	"WRN": "warn",
+	"ERR": "error",
+	"CRT": "critical",
+	"OFF": "off",
+}
+
+// jsonLogEntry is a log line written in the JSON format.
+type jsonLogEntry struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Subsystem string `json:"subsystem"`
+	Message   string `json:"message"`
+}
+
+// jsonLogLine converts a line written by the slog backend to a JSON object.
+// A line that can't be parsed is kept as the message of the entry, so nothing
+// is lost.
+func jsonLogLine(p []byte) []byte {
+	var entry jsonLogEntry
+	match := slogLinePattern.FindSubmatch(p)
+	if match == nil {
+		entry.Time = time.Now().Format(time.RFC3339Nano)
+		entry.Message = strings.TrimSuffix(string(p), "\n")
+	} else {
+		t, err := time.ParseInLocation(slogTimeLayout, string(match[1]),
+			time.Local)
+		if err != nil {
+			t = time.Now()
+		}
+		entry.Time = t.Format(time.RFC3339Nano)
+		entry.Level = logLevels[string(match[2])]
+		entry.Subsystem = string(match[3])
+		entry.Message = string(match[4])
+	}
+
+	line, err := json.Marshal(&entry)
+	if err != nil {
+		return p
+	}
+	return append(line, '\n')
 }
 
 // Loggers per subsystem.  A single backend logger is created and all subsytem
@@ -42,6 +123,10 @@ var (
 	// application shutdown.
 	logRotator *rotator.Rotator
 
+	// logJSON is true if the log lines are written as JSON objects.  It
+	// must be set before the loggers are used.
+	logJSON bool
+
 	log = backendLog.Logger("DHUB")
 )
 
@@ -73,6 +158,12 @@ func initLogRotator(logFile string) {
 	logRotator = r
 }
 
+// setLogFormat sets the format of the log lines, either logFormatText or
+// logFormatJSON.  It must be called before the loggers are used.
+func setLogFormat(format string) {
+	logJSON = format == logFormatJSON
+}
+
 // setLogLevel sets the logging level for provided subsystem.  Invalid
 // subsystems are ignored.  Uninitialized subsystems are dynamically created as
 // needed.
@@ -88,6 +179,12 @@ func setLogLevel(subsystemID string, logLevel string) {
 	logger.SetLevel(level)
 }
 
+// validLogLevel returns true if the passed log level is known.
+func validLogLevel(logLevel string) bool {
+	_, ok := slog.LevelFromString(logLevel)
+	return ok
+}
+
 // setLogLevels sets the log level for all subsystem loggers to the passed
 // level.  It also dynamically creates the subsystem loggers as needed, so it
 // can be used to initialize the logging system.
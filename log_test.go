@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+// TestJSONLogLine checks that a message logged through the slog backend
+// round-trips through JSON parsing with the expected fields, and that a line
+// which can't be parsed is kept as the message.
+func TestJSONLogLine(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.NewBackend(&buf).Logger("DHUB")
+	logger.Warnf("dcrlnd unreachable: %v", "connection refused")
+
+	var entry jsonLogEntry
+	if err := json.Unmarshal(jsonLogLine(buf.Bytes()), &entry); err != nil {
+		t.Fatalf("unable to parse log line: %v", err)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry.Time); err != nil {
+		t.Errorf("got time %q: %v", entry.Time, err)
+	}
+	want := jsonLogEntry{
+		Time:      entry.Time,
+		Level:     "warn",
+		Subsystem: "DHUB",
+		Message:   "dcrlnd unreachable: connection refused",
+	}
+	if entry != want {
+		t.Errorf("got entry %+v, want %+v", entry, want)
+	}
+
+	entry = jsonLogEntry{}
+	err := json.Unmarshal(jsonLogLine([]byte("panic: boom\n")), &entry)
+	if err != nil {
+		t.Fatalf("unable to parse unformatted log line: %v", err)
+	}
+	if entry.Message != "panic: boom" || entry.Level != "" {
+		t.Errorf("got unformatted entry %+v, want message %q",
+			entry, "panic: boom")
+	}
+}
@@ -3,19 +3,25 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"html/template"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	flags "github.com/jessevdk/go-flags"
 	"golang.org/x/crypto/acme/autocert"
 )
 
 var (
-	// ctxb is a global context with no timeouts that's used within the
-	// gRPC requests to lnd.
+	// ctxb is a global context with no timeouts that the contexts of the
+	// gRPC requests to lnd derive from. Only the invoice subscription,
+	// which is long-lived, uses it directly.
 	ctxb = context.Background()
 )
 
@@ -24,13 +30,32 @@ func main() {
 	// initializes logging and configures it accordingly.
 	cfg, _, err := loadConfig()
 	if err != nil {
-		return
+		// Asking for the help isn't a failure.
+		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
+			return
+		}
+		os.Exit(1)
 	}
 
 	// Pre-compile the template so we'll catch any errors in the
 	// templates as soon as the binary is running.
-	hubTemplate := template.Must(template.New("dcrlnhub").
-		ParseGlob("static/*.html"))
+	hubTemplate, err := loadTemplates(cfg.StaticDir)
+	if err != nil {
+		log.Criticalf("unable to load templates: %v", err)
+		os.Exit(1)
+		return
+	}
+
+	// In check mode, we're done once dcrlnd could be reached on the
+	// configured network, nothing is served. The hub isn't created, as
+	// it would create the donation invoice and address.
+	if cfg.Check {
+		if err := runCheck(cfg); err != nil {
+			log.Criticalf("Check failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// With the templates loaded, create the hub itself.
 	hub, err := newLightningHub(cfg, hubTemplate)
@@ -43,23 +68,119 @@ func main() {
 	// Create a new mux in order to route a request based on its path to a
 	// dedicated http.Handler.
 	r := mux.NewRouter()
-	r.HandleFunc("/", hub.HomePage).Methods("POST", "GET")
+	r.HandleFunc("/", hub.HomePage).
+		Methods("POST", "GET").Name("home")
+	r.HandleFunc("/openchannel", hub.rateLimit(hub.requireWritable(hub.OpenChannel))).
+		Methods("POST").Name("openchannel")
+	r.HandleFunc("/connect", hub.rateLimit(hub.requireWritable(hub.Connect))).
+		Methods("POST").Name("connect")
+	r.HandleFunc("/donate", hub.rateLimit(hub.Donate)).
+		Methods("GET").Name("donate")
+	r.HandleFunc("/invoice/{rhash}", hub.Invoice).
+		Methods("GET").Name("invoice")
+	r.HandleFunc("/qr", hub.QRCode).
+		Methods("GET").Name("qr")
+	r.HandleFunc("/widget", hub.requireDomainHost(hub.Widget)).
+		Methods("GET").Name("widget")
+	r.HandleFunc("/badge/{name:channels|capacity}.svg", hub.Badge).
+		Methods("GET").Name("badge")
+	r.HandleFunc("/channels", hub.Channels).
+		Methods("GET").Name("channels")
+	r.HandleFunc("/channels.csv", hub.ChannelsCSV).
+		Methods("GET").Name("channels_csv")
+	r.HandleFunc("/channels.json", hub.ChannelsJSON).
+		Methods("GET").Name("channels_json")
+	r.HandleFunc("/admin/channels", hub.AdminChannels).
+		Methods("GET").Name("admin_channels")
+	r.HandleFunc("/api/channels", hub.cors(hub.APIChannels)).
+		Methods("GET", "OPTIONS").Name("api_channels")
+	r.HandleFunc("/api/v1/info", hub.cors(hub.APIInfo)).
+		Methods("GET", "OPTIONS").Name("api_info")
+	r.HandleFunc("/api/v1/history", hub.cors(hub.APIHistory)).
+		Methods("GET", "OPTIONS").Name("api_history")
+	r.HandleFunc("/node.json", hub.NodeInfoJSON).
+		Methods("GET").Name("node_json")
+	r.HandleFunc("/node.txt", hub.NodeInfoText).
+		Methods("GET").Name("node_txt")
+	r.HandleFunc("/ws", hub.LiveStats).
+		Methods("GET").Name("ws")
+	r.HandleFunc("/robots.txt", hub.RobotsTxt).
+		Methods("GET").Name("robots")
+	r.HandleFunc("/favicon.ico", hub.Favicon).
+		Methods("GET").Name("favicon")
+	r.HandleFunc("/manifest.webmanifest", hub.WebManifest).
+		Methods("GET").Name("webmanifest")
+	r.HandleFunc("/icon-{size:192|512}.png", hub.Icon).
+		Methods("GET").Name("icon")
+	r.HandleFunc("/healthz", hub.Healthz).
+		Methods("GET").Name("healthz")
+	r.HandleFunc("/status", hub.Status).
+		Methods("GET").Name("status")
+	r.HandleFunc("/feed.xml", hub.requireDomainHost(hub.Feed)).
+		Methods("GET").Name("feed")
+	r.HandleFunc("/.well-known/lightning-node.json", hub.NodeManifest).
+		Methods("GET").Name("manifest")
+	r.HandleFunc("/admin/bumpfee", hub.requireWritable(hub.BumpFee)).
+		Methods("POST").Name("admin_bumpfee")
+	r.HandleFunc("/admin/refresh", hub.AdminRefresh).
+		Methods("POST").Name("admin_refresh")
+	r.HandleFunc("/admin/selftest", hub.requireWritable(hub.SelfTest)).
+		Methods("POST").Name("admin_selftest")
+
+	r.NotFoundHandler = http.HandlerFunc(hub.NotFound)
+
+	if cfg.EnableMetrics {
+		r.Handle("/metrics", hub.metrics.handler()).
+			Methods("GET").Name("metrics")
+	}
 
 	// Next create a static file server which will dispatch our static
 	// files. We rap the file sever http.Handler is a handler that strips
 	// out the absolute file path since it'll dispatch based on solely the
 	// file name.
-	staticFileServer := http.FileServer(http.Dir("static"))
+	staticFileServer := http.FileServer(http.Dir(cfg.StaticDir))
 	staticHandler := http.StripPrefix("/static/", staticFileServer)
-	r.PathPrefix("/static/").Handler(staticHandler)
+	r.PathPrefix("/static/").Handler(staticHandler).Name("static")
+
+	// Keep the operational endpoints out of search indexes.
+	r.Use(hub.robotsTag)
+
+	// Require a client certificate on the admin endpoints, if configured.
+	r.Use(hub.requireAdminClientCert)
+
+	// Pick up the changes to the templates right away in development
+	// mode.
+	if cfg.DevMode {
+		log.Infof("Development mode, reloading the templates on " +
+			"every request")
+		r.Use(hub.reloadTemplates)
+	}
+
+	// Now that every route is registered, bound how long each of them may
+	// take to respond.
+	if err := applyRouteTimeouts(r, cfg); err != nil {
+		log.Criticalf("unable to apply route timeouts: %v", err)
+		os.Exit(1)
+		return
+	}
 
-	// With all of our paths registered we'll register our mux as part of
-	// the global http handler.
-	http.Handle("/", r)
+	// Set the security headers and compress the responses for the
+	// clients accepting it, and log every request, whichever route ends
+	// up serving it. The site's credentials, when set, are required
+	// before any route is reached.
+	handler := hub.logRequests(compressResponses(
+		hub.securityHeaders(hub.basicAuth(r)),
+	))
 
+	// servers holds every http server we start, so they can all be shut
+	// down gracefully.
+	var servers []*http.Server
 	if !cfg.UseLeHTTPS {
+		httpServer := newPlainServer(cfg, handler)
+		servers = append(servers, httpServer)
+
 		log.Infof("Listening on %s", cfg.BindAddr)
-		go http.ListenAndServe(cfg.BindAddr, r)
+		go serve(httpServer, httpServer.ListenAndServe)
 	} else {
 		// Create a directory cache so the certs we get from Let's
 		// Encrypt are cached locally. This avoids running into their
@@ -71,40 +192,188 @@ func main() {
 		m := autocert.Manager{
 			Prompt:     autocert.AcceptTOS,
 			Cache:      certCache,
-			HostPolicy: autocert.HostWhitelist(cfg.Domain),
+			HostPolicy: autocert.HostWhitelist(cfg.domains...),
 		}
 
 		// As we'd like all requests to default to https, redirect all regular
 		// http requests to the https version of the faucet.
+		redirectServer := newPlainServer(cfg, m.HTTPHandler(nil))
+		servers = append(servers, redirectServer)
+
 		log.Infof("Listening on %s", cfg.BindAddr)
-		go http.ListenAndServe(cfg.BindAddr, m.HTTPHandler(nil))
+		go serve(redirectServer, redirectServer.ListenAndServe)
 
 		// Finally, create the http server, passing in our TLS configuration.
-		httpServer := &http.Server{
-			Handler:      r,
-			WriteTimeout: 30 * time.Second,
-			ReadTimeout:  30 * time.Second,
-			Addr:         ":https",
-			TLSConfig: &tls.Config{
-				GetCertificate: m.GetCertificate,
-				MinVersion:     tls.VersionTLS12,
-				CipherSuites: []uint16{
-					tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-					tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-					tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-					tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-				},
-			},
+		httpServer := newTLSServer(cfg, handler, m.GetCertificate)
+		servers = append(servers, httpServer)
+
+		log.Infof("Listening on %s", httpServer.Addr)
+		go serve(httpServer, func() error {
+			return httpServer.ListenAndServeTLS("", "")
+		})
+	}
+
+	// Block until we're asked to stop, then stop accepting new
+	// connections and let the in-flight requests finish before closing
+	// our connection to dcrlnd. SIGHUP reloads the config instead.
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	sig := <-c
+	for sig == syscall.SIGHUP {
+		if err := reloadConfig(cfg); err != nil {
+			log.Errorf("Unable to reload config: %v", err)
 		}
-		if err := httpServer.ListenAndServeTLS("", ""); err != nil {
-			log.Critical(err)
-			os.Exit(1)
+		sig = <-c
+	}
+	log.Infof("Received %v, shutting down", sig)
+
+	shutdown(servers, cfg.ShutdownTimeout)
+	hub.Stop()
+	log.Infof("Shutdown complete")
+}
+
+// requiredTemplates are the templates every page of the hub relies on, which
+// must be found in the static directory. The error pages are optional.
+var requiredTemplates = []string{
+	"header", "footer", "index.html", "channels.html", "donate.html",
+	"status.html", "unavailable.html", "widget.html",
+}
+
+// loadTemplates parses the HTML templates of the passed static directory,
+// making sure none of the required templates is missing.
+func loadTemplates(dir string) (*template.Template, error) {
+	hubTemplate, err := template.New("dcrlnhub").
+		ParseGlob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range requiredTemplates {
+		if hubTemplate.Lookup(name) == nil {
+			return nil, fmt.Errorf("template %q not found in %v",
+				name, dir)
 		}
 	}
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+	return hubTemplate, nil
+}
+
+// lookupTemplate returns the template with the passed name, or nil if there's
+// none.
+func (h *lightningHub) lookupTemplate(name string) *template.Template {
+	h.templateMtx.RLock()
+	defer h.templateMtx.RUnlock()
+	return h.template.Lookup(name)
+}
+
+// reloadTemplates is a middleware parsing the templates again before serving
+// every request, so they can be edited without restarting the hub. Parse
+// errors are rendered as the response instead of stopping the hub.
+func (h *lightningHub) reloadTemplates(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hubTemplate, err := loadTemplates(h.cfg.StaticDir)
+		if err != nil {
+			log.Errorf("unable to reload templates: %v", err)
+			http.Error(w, fmt.Sprintf("unable to reload templates: "+
+				"%v", err), http.StatusInternalServerError)
+			return
+		}
+
+		h.templateMtx.Lock()
+		h.template = hubTemplate
+		h.templateMtx.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientAuth returns the policy of the TLS server regarding client
+// certificates, which are only asked for when an admin CA is configured.
+func clientAuth(cfg *config) tls.ClientAuthType {
+	if cfg.adminClientCAs == nil {
+		return tls.NoClientCert
+	}
+
+	return tls.VerifyClientCertIfGiven
+}
+
+// newPlainServer creates the plain http server listening on the configured
+// bind address, with the configured timeouts so slow clients can't hold its
+// connections open forever.
+func newPlainServer(cfg *config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:        handler,
+		Addr:           cfg.BindAddr,
+		ReadTimeout:    cfg.HTTPReadTimeout,
+		WriteTimeout:   cfg.HTTPWriteTimeout,
+		IdleTimeout:    cfg.HTTPIdleTimeout,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+}
+
+// newTLSServer creates the https server listening on the configured https
+// bind address, getting its certificates from the passed function.
+func newTLSServer(cfg *config, handler http.Handler,
+	getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate,
+		error)) *http.Server {
+
+	return &http.Server{
+		Handler:      handler,
+		WriteTimeout: 30 * time.Second,
+		ReadTimeout:  30 * time.Second,
+		Addr:         cfg.HTTPSBindAddr,
+		TLSConfig: &tls.Config{
+			GetCertificate: getCertificate,
+			MinVersion:     tls.VersionTLS12,
+
+			// Client certificates are only required on the admin
+			// endpoints, which is enforced by
+			// requireAdminClientCert, so they're only verified
+			// when presented.
+			ClientCAs:  cfg.adminClientCAs,
+			ClientAuth: clientAuth(cfg),
+			CipherSuites: []uint16{
+				tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			},
+		},
+	}
+}
+
+// serve runs the passed listen function of an http server, which blocks
+// until the server fails or is shut down. A failure other than the server
+// being shut down is fatal.
+func serve(srv *http.Server, listen func() error) {
+	err := listen()
+	if err == nil || err == http.ErrServerClosed {
+		return
+	}
+
+	log.Criticalf("Unable to serve on %s: %v", srv.Addr, err)
+	os.Exit(1)
+}
+
+// shutdown gracefully shuts down the passed servers, waiting up to timeout
+// for their in-flight requests to finish.
+func shutdown(servers []*http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctxb, timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+
+			if err := srv.Shutdown(ctx); err != nil {
+				log.Errorf("Unable to gracefully shut down "+
+					"server on %s: %v", srv.Addr, err)
+			}
+		}(srv)
+	}
+	wg.Wait()
 }
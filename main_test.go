@@ -0,0 +1,274 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestReloadTemplates checks that the edits to the templates are picked up
+// on the next request in development mode, and that a broken template is
+// reported in the response.
+func TestReloadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := filepath.Glob("static/*.html")
+	if err != nil {
+		t.Fatalf("unable to list templates: %v", err)
+	}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unable to read template: %v", err)
+		}
+		dest := filepath.Join(dir, filepath.Base(path))
+		if err := ioutil.WriteFile(dest, data, 0600); err != nil {
+			t.Fatalf("unable to copy template: %v", err)
+		}
+	}
+
+	cfg := newTestConfig()
+	cfg.StaticDir = dir
+	hub := newTestHub(t, cfg, &fakeLnd{})
+	hub.template, err = loadTemplates(dir)
+	if err != nil {
+		t.Fatalf("unable to load templates: %v", err)
+	}
+
+	notFoundPath := filepath.Join(dir, "404.html")
+	editNotFound := func(content string) {
+		t.Helper()
+		err := ioutil.WriteFile(notFoundPath, []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("unable to edit template: %v", err)
+		}
+	}
+	devHandler := hub.reloadTemplates(http.HandlerFunc(hub.NotFound))
+	request := func(handler http.Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/missing", nil)
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	editNotFound(`{{ template "header" . }}Edited 404` +
+		`{{ template "footer" . }}`)
+
+	// Without development mode, the templates parsed at startup are kept.
+	w := request(http.HandlerFunc(hub.NotFound))
+	if strings.Contains(w.Body.String(), "Edited 404") {
+		t.Fatalf("template edit picked up outside of development mode")
+	}
+
+	w = request(devHandler)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want 404", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Edited 404") {
+		t.Fatalf("template edit not picked up: %s", w.Body.String())
+	}
+
+	editNotFound(`{{ template "header" . }}{{ if }}`)
+	w = request(devHandler)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d with a broken template, want 500",
+			w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "unable to reload templates") {
+		t.Fatalf("parse error not rendered: %s", w.Body.String())
+	}
+
+	// Once fixed, the template is served again.
+	editNotFound(`{{ template "header" . }}Fixed 404` +
+		`{{ template "footer" . }}`)
+	w = request(devHandler)
+	if !strings.Contains(w.Body.String(), "Fixed 404") {
+		t.Fatalf("fixed template not picked up: %s", w.Body.String())
+	}
+}
+
+// TestNewServers checks that the http and https servers listen on the
+// configured addresses.
+func TestNewServers(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BindAddr = ":8080"
+	cfg.HTTPSBindAddr = "127.0.0.1:8443"
+	handler := http.NotFoundHandler()
+
+	if srv := newPlainServer(cfg, handler); srv.Addr != ":8080" {
+		t.Fatalf("got http server on %q, want :8080", srv.Addr)
+	}
+
+	srv := newTLSServer(cfg, handler, nil)
+	if srv.Addr != "127.0.0.1:8443" {
+		t.Fatalf("got https server on %q, want 127.0.0.1:8443",
+			srv.Addr)
+	}
+	if srv.TLSConfig == nil || srv.TLSConfig.ClientAuth != clientAuth(cfg) {
+		t.Fatalf("https server doesn't use the hub's TLS config")
+	}
+}
+
+// TestNewPlainServerTimeouts checks that the http server's timeouts and
+// header size limit are set from the config.
+func TestNewPlainServerTimeouts(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.HTTPReadTimeout = 5 * time.Second
+	cfg.HTTPWriteTimeout = 10 * time.Second
+	cfg.HTTPIdleTimeout = time.Minute
+	cfg.MaxHeaderBytes = 4096
+
+	srv := newPlainServer(cfg, http.NotFoundHandler())
+	if srv.ReadTimeout != 5*time.Second {
+		t.Errorf("got read timeout %v, want 5s", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != 10*time.Second {
+		t.Errorf("got write timeout %v, want 10s", srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != time.Minute {
+		t.Errorf("got idle timeout %v, want 1m", srv.IdleTimeout)
+	}
+	if srv.MaxHeaderBytes != 4096 {
+		t.Errorf("got max header bytes %d, want 4096",
+			srv.MaxHeaderBytes)
+	}
+}
+
+// TestLoadTemplates checks that the templates are parsed from the passed
+// static directory, which must hold all the required ones.
+func TestLoadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	templates := map[string]string{
+		"header.html": `{{ define "header" }}<h1>{{ end }}`,
+		"footer.html": `{{ define "footer" }}</h1>{{ end }}`,
+		"index.html": `{{ template "header" . }}{{ .Alias }}` +
+			`{{ template "footer" . }}`,
+		"channels.html":    "channels",
+		"donate.html":      "donate",
+		"status.html":      "status",
+		"unavailable.html": "unavailable",
+		"widget.html":      "widget",
+	}
+	for name, text := range templates {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatalf("unable to write %v: %v", name, err)
+		}
+	}
+
+	hubTemplate, err := loadTemplates(dir)
+	if err != nil {
+		t.Fatalf("unable to load templates: %v", err)
+	}
+	var b strings.Builder
+	err = hubTemplate.ExecuteTemplate(&b, "index.html", &templateContext{
+		Alias: "hub",
+	})
+	if err != nil {
+		t.Fatalf("unable to render index.html: %v", err)
+	}
+	if b.String() != "<h1>hub</h1>" {
+		t.Fatalf("got index.html %q, want <h1>hub</h1>", b.String())
+	}
+
+	if err := os.Remove(filepath.Join(dir, "donate.html")); err != nil {
+		t.Fatalf("unable to remove donate.html: %v", err)
+	}
+	_, err = loadTemplates(dir)
+	if err == nil || !strings.Contains(err.Error(), "donate.html") {
+		t.Fatalf("got error %v, want donate.html not found", err)
+	}
+
+	if _, err := loadTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("templates loaded from a missing directory")
+	}
+}
+
+// TestShutdown checks that shutting the servers down lets the requests in
+// flight complete, while new connections are refused.
+func TestShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter,
+			r *http.Request) {
+
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		}),
+	}
+	go serve(srv, func() error { return srv.Serve(ln) })
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		inFlight <- result{string(body), err}
+	}()
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		shutdown([]*http.Server{srv}, 5*time.Second)
+		close(stopped)
+	}()
+
+	// The listener is closed as soon as the shutdown starts.
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("new connections still accepted after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("shutdown returned before the request in flight")
+	default:
+	}
+
+	close(release)
+	res := <-inFlight
+	if res.err != nil || res.body != "done" {
+		t.Fatalf("request in flight got %q (%v), want done", res.body,
+			res.err)
+	}
+	<-stopped
+}
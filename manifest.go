@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	// manifestVersion is the version of the node manifest format, bumped
+	// whenever a field is changed or removed.
+	manifestVersion = 1
+)
+
+// channelPolicy describes the hub's policy regarding channels requested by
+// visitors.
+type channelPolicy struct {
+	// OpenChannelEnabled is true if visitors can request the hub to open
+	// a channel to their node.
+	OpenChannelEnabled bool `json:"open_channel_enabled"`
+
+	// MinChannelSize and MaxChannelSize bound the size in atoms of the
+	// channels visitors can request.
+	MinChannelSize int64 `json:"min_channel_size"`
+	MaxChannelSize int64 `json:"max_channel_size"`
+}
+
+// nodeManifest is a machine-readable description of the hub's node, served
+// at a well-known path for automated discovery by other Lightning tooling.
+type nodeManifest struct {
+	Version int           `json:"version"`
+	Pubkey  string        `json:"pubkey"`
+	Alias   string        `json:"alias"`
+	Network string        `json:"network"`
+	URIs    []string      `json:"uris"`
+	Hub     string        `json:"hub"`
+	Policy  channelPolicy `json:"policy"`
+}
+
+// channelPolicy returns the hub's current channel policy.
+func (h *lightningHub) channelPolicy() channelPolicy {
+	return channelPolicy{
+		OpenChannelEnabled: h.writesAllowed(),
+		MinChannelSize:     h.cfg.MinChannelSize,
+		MaxChannelSize:     h.cfg.MaxChannelSize,
+	}
+}
+
+// NodeManifest serves the node manifest, built from the cached context.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) NodeManifest(w http.ResponseWriter, r *http.Request) {
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	uris := homeInfo.NodeURIs
+	if uris == nil {
+		uris = []string{}
+	}
+
+	// Aggregators are expected to poll the manifest, let them cache it
+	// for as long as we'd serve it without refreshing.
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d",
+		int(h.cfg.MaxStaleness.Seconds())))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeJSON(w, &nodeManifest{
+		Version: manifestVersion,
+		Pubkey:  homeInfo.NodePubkey,
+		Alias:   homeInfo.Alias,
+		Network: homeInfo.Network,
+		URIs:    uris,
+		Hub:     userAgent(),
+		Policy:  h.channelPolicy(),
+	})
+}
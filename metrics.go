@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// metricsNamespace prefixes the name of every metric exported by the
+	// hub.
+	metricsNamespace = "dcrlnhub"
+)
+
+// hubMetrics holds the Prometheus metrics exported by the hub. A nil
+// *hubMetrics is valid and records nothing, which is what the hub uses when
+// metrics are disabled.
+type hubMetrics struct {
+	registry *prometheus.Registry
+
+	channels *prometheus.GaugeVec
+	capacity prometheus.Gauge
+	balance  prometheus.Gauge
+
+	homePageRequests prometheus.Counter
+	homePageLatency  prometheus.Histogram
+}
+
+// newHubMetrics creates the hub's metrics, registered on their own registry
+// so only the hub's metrics are exported.
+func newHubMetrics() *hubMetrics {
+	m := &hubMetrics{
+		registry: prometheus.NewRegistry(),
+		channels: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "channels",
+			Help:      "Number of channels of the node by state.",
+		}, []string{"state"}),
+		capacity: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_atoms",
+			Help:      "Total capacity of the node's channels in atoms.",
+		}),
+		balance: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "wallet_confirmed_balance_atoms",
+			Help:      "Confirmed balance of the node's on-chain wallet in atoms.",
+		}),
+		homePageRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "home_page_requests_total",
+			Help:      "Number of requests to the home page.",
+		}),
+		homePageLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "home_page_duration_seconds",
+			Help:      "Time taken to serve the home page.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.channels, m.capacity, m.balance, m.homePageRequests,
+		m.homePageLatency,
+	)
+
+	return m
+}
+
+// update sets the gauges from a freshly fetched template context.
+func (m *hubMetrics) update(homeCtx *templateContext) {
+	if m == nil {
+		return
+	}
+
+	m.channels.WithLabelValues("active").Set(float64(homeCtx.ChannelsCount))
+	m.channels.WithLabelValues("inactive").Set(float64(homeCtx.InactiveCount))
+	m.capacity.Set(float64(homeCtx.Capacity))
+	m.balance.Set(float64(homeCtx.Balance))
+}
+
+// observeHomePage records a home page request which started at the passed
+// time.
+func (m *hubMetrics) observeHomePage(start time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.homePageRequests.Inc()
+	m.homePageLatency.Observe(time.Since(start).Seconds())
+}
+
+// handler returns the http.Handler serving the metrics to Prometheus.
+func (m *hubMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
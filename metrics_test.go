@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMetrics checks that the gauges scraped from /metrics are taken from the
+// cached context.
+func TestMetrics(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	hub.metrics = newHubMetrics()
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		ChannelsCount: 3,
+		InactiveCount: 1,
+		Capacity:      150000000,
+		Balance:       300000,
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	hub.metrics.handler().ServeHTTP(w,
+		httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`dcrlnhub_channels{state="active"} 3`,
+		`dcrlnhub_channels{state="inactive"} 1`,
+		`dcrlnhub_capacity_atoms 1.5e+08`,
+		`dcrlnhub_wallet_confirmed_balance_atoms 300000`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics don't contain %q:\n%s", want, body)
+		}
+	}
+}
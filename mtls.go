@@ -0,0 +1,54 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// adminPathPrefix is the path prefix of the admin endpoints.
+const adminPathPrefix = "/admin/"
+
+// loadCertPool loads the PEM encoded certificates of the file at path into a
+// new certificate pool.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM encoded certificate found in %v",
+			path)
+	}
+
+	return pool, nil
+}
+
+// requireAdminClientCert is a middleware rejecting the requests to the admin
+// endpoints which weren't made with a client certificate signed by the
+// configured admin CA. The TLS server only verifies the certificates the
+// clients present, so the other endpoints stay open to everyone. It does
+// nothing when no admin CA is configured.
+func (h *lightningHub) requireAdminClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.cfg.adminClientCAs == nil ||
+			!strings.HasPrefix(r.URL.Path, adminPathPrefix) {
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			log.Debugf("Rejecting request for %v without a valid "+
+				"client certificate", r.URL.Path)
+			http.Error(w, "403 Forbidden.", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestCert creates a certificate for the passed common name, valid for
+// both clients and servers on 127.0.0.1, signed by parent or self-signed when
+// parent is nil.
+func newTestCert(t *testing.T, name string, isCA bool,
+	parent *tls.Certificate) tls.Certificate {
+
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth,
+		},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer, signerKey := template, interface{}(key)
+	if parent != nil {
+		signer = parent.Leaf
+		signerKey = parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(
+		rand.Reader, template, signer, &key.PublicKey, signerKey,
+	)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unable to parse certificate: %v", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+// TestRequireAdminClientCert checks that the admin endpoints are only
+// served to clients presenting a certificate signed by the admin CA, while
+// the other endpoints stay open.
+func TestRequireAdminClientCert(t *testing.T) {
+	ca := newTestCert(t, "admin ca", true, nil)
+	otherCA := newTestCert(t, "other ca", true, nil)
+	validCert := newTestCert(t, "admin", false, &ca)
+	invalidCert := newTestCert(t, "intruder", false, &otherCA)
+
+	cfg := newTestConfig()
+	cfg.adminClientCAs = x509.NewCertPool()
+	cfg.adminClientCAs.AddCert(ca.Leaf)
+	hub := newTestHub(t, cfg, &fakeLnd{})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewUnstartedServer(hub.requireAdminClientCert(ok))
+	server.TLS = &tls.Config{
+		ClientCAs:  cfg.adminClientCAs,
+		ClientAuth: clientAuth(cfg),
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		cert     *tls.Certificate
+		path     string
+		wantCode int
+	}{{
+		name:     "valid cert",
+		cert:     &validCert,
+		path:     "/admin/refresh",
+		wantCode: http.StatusOK,
+	}, {
+		name:     "no cert",
+		path:     "/admin/refresh",
+		wantCode: http.StatusForbidden,
+	}, {
+		name:     "invalid cert",
+		cert:     &invalidCert,
+		path:     "/admin/refresh",
+		wantCode: http.StatusForbidden,
+	}, {
+		name:     "public path without cert",
+		path:     "/",
+		wantCode: http.StatusOK,
+	}}
+
+	for _, test := range tests {
+		// Each request gets its own transport, so no connection made
+		// with another certificate is reused.
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		if test.cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{
+				*test.cert,
+			}
+		}
+
+		client := &http.Client{Transport: transport}
+		res, err := client.Get(server.URL + test.path)
+		transport.CloseIdleConnections()
+
+		// The TLS server refuses the handshake of a client presenting
+		// a certificate it can't verify, which is as good as a 403.
+		if err != nil {
+			if test.cert == &invalidCert {
+				continue
+			}
+			t.Errorf("%s: unable to make request: %v", test.name, err)
+			continue
+		}
+		res.Body.Close()
+
+		if res.StatusCode != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				res.StatusCode, test.wantCode)
+		}
+	}
+}
+
+// TestRequireAdminClientCertDisabled checks that the admin endpoints aren't
+// restricted to client certificates without a configured admin CA.
+func TestRequireAdminClientCertDisabled(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	hub.requireAdminClientCert(ok).ServeHTTP(
+		w, httptest.NewRequest("GET", "/admin/refresh", nil),
+	)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+}
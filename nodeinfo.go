@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// nodeInfoFile is the connection info of the hub's node, downloaded by
+// wallet users to import the hub as a peer.
+type nodeInfoFile struct {
+	Pubkey  string   `json:"pubkey"`
+	URIs    []string `json:"uris"`
+	Alias   string   `json:"alias"`
+	Network string   `json:"network"`
+}
+
+// attachment sets the Content-Disposition header so browsers download the
+// response as a file with the passed name.
+func attachment(w http.ResponseWriter, filename string) {
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", filename))
+}
+
+// NodeInfoJSON serves the connection info of the hub's node as a JSON file.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) NodeInfoJSON(w http.ResponseWriter, r *http.Request) {
+	homeInfo := h.cachedContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	uris := homeInfo.NodeURIs
+	if uris == nil {
+		uris = []string{}
+	}
+
+	attachment(w, "node.json")
+	writeJSON(w, &nodeInfoFile{
+		Pubkey:  homeInfo.NodePubkey,
+		URIs:    uris,
+		Alias:   homeInfo.Alias,
+		Network: homeInfo.Network,
+	})
+}
+
+// NodeInfoText serves the primary URI of the hub's node, in the pubkey@host
+// format, as a text file. Only the pubkey is served if the node doesn't
+// advertise any address.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) NodeInfoText(w http.ResponseWriter, r *http.Request) {
+	homeInfo := h.cachedContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+
+	uri := homeInfo.NodeAddr
+	if uri == "" {
+		uri = homeInfo.NodePubkey
+	}
+
+	attachment(w, "node.txt")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, uri)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNodeInfo checks that both node info files contain the hub's node URI
+// from the cached GetInfo data, and are served as attachments.
+func TestNodeInfo(t *testing.T) {
+	const uri = "02aa@203.0.113.9:9735"
+
+	info := testnetInfo()
+	info.Uris = []string{uri}
+	lnd := &fakeLnd{info: info}
+	hub := newTestHub(t, newTestConfig(), lnd)
+
+	w := httptest.NewRecorder()
+	hub.NodeInfoText(w, httptest.NewRequest("GET", "/node.txt", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d without a context, want 503", w.Code)
+	}
+
+	homeCtx, err := hub.fetchHomePage()
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	if err := hub.setContext(homeCtx); err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	hub.NodeInfoJSON(w, httptest.NewRequest("GET", "/node.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("node.json: got status %d, want 200", w.Code)
+	}
+	disposition := w.Header().Get("Content-Disposition")
+	if disposition != `attachment; filename="node.json"` {
+		t.Fatalf("node.json: got Content-Disposition %q", disposition)
+	}
+	var file nodeInfoFile
+	if err := json.NewDecoder(w.Body).Decode(&file); err != nil {
+		t.Fatalf("node.json: unable to decode: %v", err)
+	}
+	if len(file.URIs) != 1 || file.URIs[0] != uri {
+		t.Fatalf("node.json: got URIs %v, want %v", file.URIs, uri)
+	}
+	if file.Pubkey != "02aa" || file.Alias != "hub" ||
+		file.Network != "testnet" {
+
+		t.Fatalf("node.json: got %+v", file)
+	}
+
+	w = httptest.NewRecorder()
+	hub.NodeInfoText(w, httptest.NewRequest("GET", "/node.txt", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("node.txt: got status %d, want 200", w.Code)
+	}
+	disposition = w.Header().Get("Content-Disposition")
+	if disposition != `attachment; filename="node.txt"` {
+		t.Fatalf("node.txt: got Content-Disposition %q", disposition)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != uri {
+		t.Fatalf("node.txt: got %q, want %q", body, uri)
+	}
+
+	// The files are served from the cache, without any more RPC.
+	if n := lnd.callCount("GetInfo"); n != 1 {
+		t.Fatalf("got %d GetInfo calls, want 1", n)
+	}
+}
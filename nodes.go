@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+	"google.golang.org/grpc"
+)
+
+// nodeConfig is the configuration of an additional dcrlnd node whose stats
+// are aggregated with the ones of the hub's node.
+type nodeConfig struct {
+	Name         string
+	RPCHost      string
+	TLSCertPath  string
+	MacaroonPath string
+}
+
+// nodesConfig holds the options of the additional nodes, which are set in
+// the [node] section of the config file, or with the --node.* flags. Every
+// option is repeated once per node, in the same order, so the nth value of
+// each option belongs to the nth node:
+//
+//	[node]
+//	node.name=alpha
+//	node.rpchost=10.0.0.2:10009
+//	node.certpath=/etc/dcrlnhub/alpha/tls.cert
+//	node.macpath=/etc/dcrlnhub/alpha/readonly.macaroon
+//	node.name=beta
+//	...
+type nodesConfig struct {
+	Names         []string `long:"name" env:"DCRLNHUB_NODE_NAME" env-delim:";" description:"name of an additional dcrlnd node whose stats are aggregated with the hub's node, may be specified multiple times"`
+	RPCHosts      []string `long:"rpchost" env:"DCRLNHUB_NODE_RPCHOST" env-delim:";" description:"rpc listening address of the additional node, as host:port, once per node name"`
+	TLSCertPaths  []string `long:"certpath" env:"DCRLNHUB_NODE_CERTPATH" env-delim:";" description:"TLS certificate path of the additional node, once per node name"`
+	MacaroonPaths []string `long:"macpath" env:"DCRLNHUB_NODE_MACPATH" env-delim:";" description:"macaroon path of the additional node, once per node name"`
+}
+
+// parseNodeConfigs zips the configured options of the additional nodes into
+// one config per node.
+func parseNodeConfigs(nodes nodesConfig) ([]nodeConfig, error) {
+	n := len(nodes.Names)
+	if len(nodes.RPCHosts) != n || len(nodes.TLSCertPaths) != n ||
+		len(nodes.MacaroonPaths) != n {
+
+		return nil, fmt.Errorf("got %d node names, %d rpchosts, %d "+
+			"certpaths and %d macpaths, every node needs one of "+
+			"each", n, len(nodes.RPCHosts),
+			len(nodes.TLSCertPaths), len(nodes.MacaroonPaths))
+	}
+
+	configs := make([]nodeConfig, 0, n)
+	names := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		node := nodeConfig{
+			Name:         strings.TrimSpace(nodes.Names[i]),
+			RPCHost:      strings.TrimSpace(nodes.RPCHosts[i]),
+			TLSCertPath:  strings.TrimSpace(nodes.TLSCertPaths[i]),
+			MacaroonPath: strings.TrimSpace(nodes.MacaroonPaths[i]),
+		}
+		if node.Name == "" || node.RPCHost == "" ||
+			node.TLSCertPath == "" || node.MacaroonPath == "" {
+
+			return nil, fmt.Errorf("invalid node %d, empty field",
+				i+1)
+		}
+		if names[node.Name] {
+			return nil, fmt.Errorf("duplicate node name %q",
+				node.Name)
+		}
+		names[node.Name] = true
+
+		if _, port, err := net.SplitHostPort(node.RPCHost); err != nil ||
+			port == "" {
+
+			return nil, fmt.Errorf("invalid rpchost %q of node %v, "+
+				"expected host:port", node.RPCHost, node.Name)
+		}
+
+		configs = append(configs, node)
+	}
+
+	return configs, nil
+}
+
+// backendNode is an additional dcrlnd node. The hub only reads its stats,
+// everything else is done through the hub's own node.
+type backendNode struct {
+	name string
+	conn *grpc.ClientConn
+	lnd  lndClient
+}
+
+// nodeDialConfig returns the config used to connect to an additional node,
+// which is the hub's config for everything but the node's address and
+// credentials. None of the hub's own credentials may be sent to the node,
+// so its inline ones and its read-only macaroon are cleared.
+func nodeDialConfig(cfg *config, node nodeConfig) *config {
+	nodeCfg := *cfg
+	nodeCfg.RPCHost = node.RPCHost
+	nodeCfg.TLSCertPath = node.TLSCertPath
+	nodeCfg.TLSCertPEM = ""
+	nodeCfg.MacaroonPath = node.MacaroonPath
+	nodeCfg.MacaroonHex = ""
+	nodeCfg.ReadOnlyMacaroonPath = ""
+
+	return &nodeCfg
+}
+
+// dialNode connects to an additional node, using the hub's config for
+// everything but the node's address and credentials.
+func dialNode(cfg *config, node nodeConfig) (*backendNode, error) {
+	conn, err := dialLnd(nodeDialConfig(cfg, node))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to node %v: %v",
+			node.Name, err)
+	}
+
+	return &backendNode{
+		name: node.Name,
+		conn: conn,
+		lnd:  lnrpc.NewLightningClient(conn),
+	}, nil
+}
+
+// nodeStats are the stats of a single node shown in the per node breakdown.
+type nodeStats struct {
+	Name            string
+	Alias           string
+	ChannelsCount   uint32
+	InactiveCount   uint32
+	Capacity        int64
+	InboundCapacity dcrutil.Amount
+	Balance         dcrutil.Amount
+
+	// UnconfirmedBalance is the node's unconfirmed on-chain balance,
+	// Balance being the confirmed one.
+	UnconfirmedBalance dcrutil.Amount
+}
+
+// CapacityDCR returns the capacity of the node's channels formatted in DCR.
+func (s *nodeStats) CapacityDCR() string {
+	return formatDCR(s.Capacity)
+}
+
+// fetchNodeStats fetches the stats of the passed node.
+func fetchNodeStats(node *backendNode, cfg *config) (*nodeStats, error) {
+	ctx, cancel := cfg.rpcContext()
+	nodeInfo, err := node.lnd.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("rpc GetInfo() failed: %w", err)
+	}
+	if len(nodeInfo.Chains) == 0 {
+		return nil, fmt.Errorf("dcrlnd returned no chain info")
+	}
+	if network := nodeInfo.Chains[0].Network; network != cfg.Network {
+		return nil, fmt.Errorf("node is on %v instead of %v", network,
+			cfg.Network)
+	}
+
+	listChanReq := &lnrpc.ListChannelsRequest{}
+	ctx, cancel = cfg.rpcContext()
+	listChanRes, err := node.lnd.ListChannels(ctx, listChanReq)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("rpc ListChannels() failed: %w", err)
+	}
+	// Like for the hub's node, the remote reserve can't be received.
+	var capacity, inboundCapacity int64
+	var inactiveCount uint32
+	for _, channel := range listChanRes.Channels {
+		capacity += channel.Capacity
+		if !channel.Active {
+			inactiveCount++
+			continue
+		}
+
+		inbound := channel.RemoteBalance - channel.RemoteChanReserveAtoms
+		if inbound > 0 {
+			inboundCapacity += inbound
+		}
+	}
+
+	walletBalanceReq := &lnrpc.WalletBalanceRequest{}
+	ctx, cancel = cfg.rpcContext()
+	walletBalanceRes, err := node.lnd.WalletBalance(ctx, walletBalanceReq)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("rpc WalletBalance() failed: %w", err)
+	}
+
+	return &nodeStats{
+		Name:            node.name,
+		Alias:           nodeInfo.Alias,
+		ChannelsCount:   nodeInfo.NumActiveChannels,
+		InactiveCount:   inactiveCount,
+		Capacity:        capacity,
+		InboundCapacity: dcrutil.Amount(inboundCapacity),
+		Balance:         dcrutil.Amount(walletBalanceRes.ConfirmedBalance),
+
+		UnconfirmedBalance: dcrutil.Amount(
+			walletBalanceRes.UnconfirmedBalance,
+		),
+	}, nil
+}
+
+// aggregateNodes adds the stats of the additional nodes to the passed home
+// page context, along with a per node breakdown. A node that can't be reached
+// is skipped, so it doesn't take the whole page down.
+func (h *lightningHub) aggregateNodes(homeCtx *templateContext) {
+	if len(h.nodes) == 0 {
+		return
+	}
+
+	homeCtx.Nodes = []*nodeStats{{
+		Name:            "hub",
+		Alias:           homeCtx.Alias,
+		ChannelsCount:   homeCtx.ChannelsCount,
+		InactiveCount:   homeCtx.InactiveCount,
+		Capacity:        homeCtx.Capacity,
+		InboundCapacity: homeCtx.InboundCapacity,
+		Balance:         homeCtx.Balance,
+
+		UnconfirmedBalance: homeCtx.UnconfirmedBalance,
+	}}
+	for _, node := range h.nodes {
+		stats, err := fetchNodeStats(node, h.cfg)
+		if err != nil {
+			log.Warnf("Skipping node %v: %v", node.name, err)
+			continue
+		}
+
+		homeCtx.ChannelsCount += stats.ChannelsCount
+		homeCtx.InactiveCount += stats.InactiveCount
+		homeCtx.Capacity += stats.Capacity
+		homeCtx.InboundCapacity += stats.InboundCapacity
+		homeCtx.Balance += stats.Balance
+		homeCtx.UnconfirmedBalance += stats.UnconfirmedBalance
+		homeCtx.TotalBalance += stats.Balance +
+			stats.UnconfirmedBalance
+		homeCtx.Nodes = append(homeCtx.Nodes, stats)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	flags "github.com/jessevdk/go-flags"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestNodeDialConfig checks that none of the hub's credentials are used to
+// connect to an additional node.
+func TestNodeDialConfig(t *testing.T) {
+	cfg := &config{
+		RPCHost:              "127.0.0.1:10009",
+		TLSCertPath:          "hub/tls.cert",
+		TLSCertPEM:           "hub cert",
+		MacaroonPath:         "hub/admin.macaroon",
+		MacaroonHex:          "0201",
+		ReadOnlyMacaroonPath: "hub/readonly.macaroon",
+	}
+	node := nodeConfig{
+		Name:         "alpha",
+		RPCHost:      "10.0.0.2:10009",
+		TLSCertPath:  "alpha/tls.cert",
+		MacaroonPath: "alpha/admin.macaroon",
+	}
+
+	nodeCfg := nodeDialConfig(cfg, node)
+	if nodeCfg.RPCHost != node.RPCHost ||
+		nodeCfg.TLSCertPath != node.TLSCertPath ||
+		nodeCfg.MacaroonPath != node.MacaroonPath {
+
+		t.Fatalf("node settings not used: %+v", nodeCfg)
+	}
+	if nodeCfg.TLSCertPEM != "" || nodeCfg.MacaroonHex != "" ||
+		nodeCfg.ReadOnlyMacaroonPath != "" {
+
+		t.Fatalf("hub credentials leaked to the node: %+v", nodeCfg)
+	}
+	if nodeCfg.readOnly() {
+		t.Fatal("node config must use the node's own macaroon")
+	}
+	if cfg.ReadOnlyMacaroonPath == "" {
+		t.Fatal("hub config modified")
+	}
+}
+
+// TestAggregateNodesBalances checks that every on-chain balance of the
+// additional nodes is summed with the hub's, so they stay consistent.
+func TestAggregateNodesBalances(t *testing.T) {
+	hubLnd := &fakeLnd{
+		info: testnetInfo(),
+		balance: &lnrpc.WalletBalanceResponse{
+			ConfirmedBalance:   1000,
+			UnconfirmedBalance: 200,
+		},
+	}
+	otherLnd := &fakeLnd{
+		info: testnetInfo(),
+		balance: &lnrpc.WalletBalanceResponse{
+			ConfirmedBalance:   3000,
+			UnconfirmedBalance: 400,
+		},
+	}
+	h := newTestHub(t, newTestConfig(), hubLnd)
+	h.nodes = []*backendNode{{name: "other", lnd: otherLnd}}
+
+	homeCtx, err := h.fetchHomePage()
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+
+	if homeCtx.Balance != 4000 || homeCtx.UnconfirmedBalance != 600 ||
+		homeCtx.TotalBalance != 4600 {
+
+		t.Fatalf("unexpected balances: confirmed %d, unconfirmed %d, "+
+			"total %d", int64(homeCtx.Balance),
+			int64(homeCtx.UnconfirmedBalance),
+			int64(homeCtx.TotalBalance))
+	}
+	if homeCtx.HubBalance != 1000 {
+		t.Fatalf("expected the hub's balance to be 1000, got %d",
+			int64(homeCtx.HubBalance))
+	}
+}
+
+// TestNodesConfigIni checks that the additional nodes are read from the
+// [node] section of the config file, one value of each option per node.
+func TestNodesConfigIni(t *testing.T) {
+	const ini = `
+[Application Options]
+rpchost=127.0.0.1:10009
+
+[node]
+node.name=alpha
+node.rpchost=10.0.0.2:10009
+node.certpath=alpha/tls.cert
+node.macpath=alpha/readonly.macaroon
+node.name=beta
+node.rpchost=10.0.0.3:10009
+node.certpath=beta/tls.cert
+node.macpath=beta/readonly.macaroon
+`
+
+	var cfg config
+	parser := flags.NewParser(&cfg, flags.Default)
+	err := flags.NewIniParser(parser).Parse(strings.NewReader(ini))
+	if err != nil {
+		t.Fatalf("unable to parse config: %v", err)
+	}
+
+	nodes, err := parseNodeConfigs(cfg.Nodes)
+	if err != nil {
+		t.Fatalf("unable to parse nodes: %v", err)
+	}
+	want := []nodeConfig{{
+		Name:         "alpha",
+		RPCHost:      "10.0.0.2:10009",
+		TLSCertPath:  "alpha/tls.cert",
+		MacaroonPath: "alpha/readonly.macaroon",
+	}, {
+		Name:         "beta",
+		RPCHost:      "10.0.0.3:10009",
+		TLSCertPath:  "beta/tls.cert",
+		MacaroonPath: "beta/readonly.macaroon",
+	}}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Fatalf("got nodes %+v, want %+v", nodes, want)
+	}
+	if cfg.RPCHost != "127.0.0.1:10009" {
+		t.Fatalf("hub rpchost overridden by a node: %v", cfg.RPCHost)
+	}
+}
+
+// TestParseNodeConfigsInvalid checks that incomplete or conflicting nodes
+// are rejected.
+func TestParseNodeConfigsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes nodesConfig
+	}{{
+		name: "missing macpath",
+		nodes: nodesConfig{
+			Names:        []string{"alpha"},
+			RPCHosts:     []string{"10.0.0.2:10009"},
+			TLSCertPaths: []string{"alpha/tls.cert"},
+		},
+	}, {
+		name: "empty name",
+		nodes: nodesConfig{
+			Names:         []string{" "},
+			RPCHosts:      []string{"10.0.0.2:10009"},
+			TLSCertPaths:  []string{"alpha/tls.cert"},
+			MacaroonPaths: []string{"alpha/admin.macaroon"},
+		},
+	}, {
+		name: "duplicate name",
+		nodes: nodesConfig{
+			Names:    []string{"alpha", "alpha"},
+			RPCHosts: []string{"10.0.0.2:10009", "10.0.0.3:10009"},
+			TLSCertPaths: []string{
+				"alpha/tls.cert", "beta/tls.cert",
+			},
+			MacaroonPaths: []string{
+				"alpha/admin.macaroon", "beta/admin.macaroon",
+			},
+		},
+	}, {
+		name: "rpchost without port",
+		nodes: nodesConfig{
+			Names:         []string{"alpha"},
+			RPCHosts:      []string{"10.0.0.2"},
+			TLSCertPaths:  []string{"alpha/tls.cert"},
+			MacaroonPaths: []string{"alpha/admin.macaroon"},
+		},
+	}}
+
+	for _, test := range tests {
+		if _, err := parseNodeConfigs(test.nodes); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+// TestAggregateNodes checks that the channels, capacity and inbound
+// capacity of two additional nodes are summed with the hub's, and that a
+// node which can't be reached is skipped.
+func TestAggregateNodes(t *testing.T) {
+	hubLnd := &fakeLnd{
+		info: testnetInfo(),
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				Active:        true,
+				Capacity:      100000,
+				RemoteBalance: 40000,
+			}},
+		},
+	}
+	alphaInfo := testnetInfo()
+	alphaInfo.Alias = "alpha"
+	alphaInfo.NumActiveChannels = 1
+	alphaLnd := &fakeLnd{
+		info: alphaInfo,
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				Active:                 true,
+				Capacity:               200000,
+				RemoteBalance:          50000,
+				RemoteChanReserveAtoms: 2000,
+			}, {
+				Capacity:      30000,
+				RemoteBalance: 10000,
+			}},
+		},
+	}
+	betaInfo := testnetInfo()
+	betaInfo.Alias = "beta"
+	betaInfo.NumActiveChannels = 1
+	betaLnd := &fakeLnd{
+		info: betaInfo,
+		channels: &lnrpc.ListChannelsResponse{
+			Channels: []*lnrpc.Channel{{
+				Active:        true,
+				Capacity:      70000,
+				RemoteBalance: 5000,
+			}},
+		},
+	}
+	downLnd := &fakeLnd{
+		errs: map[string][]error{
+			"GetInfo": {status.Error(codes.Unavailable, "down")},
+		},
+	}
+
+	hubLnd.info.NumActiveChannels = 1
+	h := newTestHub(t, newTestConfig(), hubLnd)
+	h.nodes = []*backendNode{
+		{name: "alpha", lnd: alphaLnd},
+		{name: "down", lnd: downLnd},
+		{name: "beta", lnd: betaLnd},
+	}
+
+	homeCtx, err := h.fetchHomePage()
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+
+	if homeCtx.ChannelsCount != 3 {
+		t.Fatalf("expected 3 active channels, got %d",
+			homeCtx.ChannelsCount)
+	}
+	if homeCtx.InactiveCount != 1 {
+		t.Fatalf("expected 1 inactive channel, got %d",
+			homeCtx.InactiveCount)
+	}
+	if homeCtx.Capacity != 400000 {
+		t.Fatalf("expected capacity 400000, got %d", homeCtx.Capacity)
+	}
+	if homeCtx.InboundCapacity != 93000 {
+		t.Fatalf("expected inbound capacity 93000, got %d",
+			int64(homeCtx.InboundCapacity))
+	}
+	if homeCtx.HubInboundCapacity != 40000 {
+		t.Fatalf("expected the hub's inbound capacity to be 40000, "+
+			"got %d", int64(homeCtx.HubInboundCapacity))
+	}
+
+	var names []string
+	for _, stats := range homeCtx.Nodes {
+		names = append(names, stats.Name)
+	}
+	want := []string{"hub", "alpha", "beta"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got nodes %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// openChannelRequest is a channel open requested by a visitor through the
+// home page form.
+type openChannelRequest struct {
+	Pubkey string
+	Host   string
+	Amount dcrutil.Amount
+}
+
+// parseOpenChannelRequest parses and validates the channel open form,
+// checking the amount against the configured channel size limits.
+func (h *lightningHub) parseOpenChannelRequest(r *http.Request) (
+	*openChannelRequest, error) {
+
+	pubkey := strings.TrimSpace(r.FormValue("pubkey"))
+	if err := checkNodePubkey(pubkey); err != nil {
+		return nil, err
+	}
+
+	host := strings.TrimSpace(r.FormValue("host"))
+	if host == "" {
+		return nil, fmt.Errorf("the host of the node is required")
+	}
+
+	atoms, err := strconv.ParseInt(r.FormValue("amount"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount, expected atoms")
+	}
+	amount := dcrutil.Amount(atoms)
+	minSize := dcrutil.Amount(h.cfg.MinChannelSize)
+	maxSize := dcrutil.Amount(h.cfg.MaxChannelSize)
+	if amount < minSize || amount > maxSize {
+		return nil, fmt.Errorf("the channel size must be between %v "+
+			"and %v", minSize, maxSize)
+	}
+
+	return &openChannelRequest{
+		Pubkey: pubkey,
+		Host:   host,
+		Amount: amount,
+	}, nil
+}
+
+// fundableAmount returns the largest channel the hub can currently fund out
+// of the passed confirmed wallet balance, keeping the configured reserve
+// aside for fees.
+func fundableAmount(balance dcrutil.Amount, cfg *config) dcrutil.Amount {
+	fundable := balance - dcrutil.Amount(cfg.WalletReserve)
+	if fundable < 0 {
+		return 0
+	}
+
+	return fundable
+}
+
+// checkFundable returns an error telling the visitor how large a channel the
+// hub can fund if the passed amount exceeds it.
+func checkFundable(amount, balance dcrutil.Amount, cfg *config) error {
+	if fundable := fundableAmount(balance, cfg); amount > fundable {
+		return fmt.Errorf("we can currently fund channels of up to %v",
+			fundable)
+	}
+
+	return nil
+}
+
+// suggestChannelSize returns the channel size suggested to visitors. Unless a
+// size is configured, it's the median capacity of the passed channels, so the
+// suggestion follows what the hub's peers usually commit. It's always within
+// the configured channel size limits.
+func suggestChannelSize(channels []*lnrpc.Channel, cfg *config) dcrutil.Amount {
+	if cfg.SuggestedChannelSize != 0 {
+		return dcrutil.Amount(cfg.SuggestedChannelSize)
+	}
+
+	minSize := dcrutil.Amount(cfg.MinChannelSize)
+	maxSize := dcrutil.Amount(cfg.MaxChannelSize)
+	if len(channels) == 0 {
+		return minSize
+	}
+
+	capacities := make([]int64, 0, len(channels))
+	for _, channel := range channels {
+		capacities = append(capacities, channel.Capacity)
+	}
+	sort.Slice(capacities, func(i, j int) bool {
+		return capacities[i] < capacities[j]
+	})
+
+	mid := len(capacities) / 2
+	median := capacities[mid]
+	if len(capacities)%2 == 0 {
+		median = (capacities[mid-1] + capacities[mid]) / 2
+	}
+
+	switch suggested := dcrutil.Amount(median); {
+	case suggested < minSize:
+		return minSize
+	case suggested > maxSize:
+		return maxSize
+	default:
+		return suggested
+	}
+}
+
+// fundingTxid returns the txid of the funding transaction of the passed
+// channel point.
+func fundingTxid(chanPoint *lnrpc.ChannelPoint) (string, error) {
+	if txid := chanPoint.GetFundingTxidStr(); txid != "" {
+		return txid, nil
+	}
+
+	hash, err := chainhash.NewHash(chanPoint.GetFundingTxidBytes())
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+// openChannel connects to the requested node and opens a channel to it,
+// returning the txid of the funding transaction.
+func (h *lightningHub) openChannel(req *openChannelRequest) (string, error) {
+	lnd := h.client()
+
+	// The request was checked against the cached balance, which may be
+	// out of date, or already committed to another channel being opened.
+	// Opens are serialized and checked again against the current balance
+	// so we never commit more than we have.
+	h.openMtx.Lock()
+	defer h.openMtx.Unlock()
+
+	ctx, cancel := h.cfg.rpcContext()
+	balanceRes, err := lnd.WalletBalance(ctx, &lnrpc.WalletBalanceRequest{})
+	cancel()
+	if err != nil {
+		return "", fmt.Errorf("rpc WalletBalance() failed: %w", err)
+	}
+	balance := dcrutil.Amount(balanceRes.ConfirmedBalance)
+	if err := checkFundable(req.Amount, balance, h.cfg); err != nil {
+		return "", err
+	}
+
+	// We must be connected to the node before we can open a channel to
+	// it. It's fine if we already are.
+	connectReq := &lnrpc.ConnectPeerRequest{
+		Addr: &lnrpc.LightningAddress{
+			Pubkey: req.Pubkey,
+			Host:   req.Host,
+		},
+	}
+	ctx, cancel = h.cfg.rpcContext()
+	_, err = lnd.ConnectPeer(ctx, connectReq)
+	cancel()
+	if err != nil && !isAlreadyConnected(err) {
+		return "", fmt.Errorf("unable to connect to %v@%v: %w",
+			req.Pubkey, req.Host, err)
+	}
+
+	openReq := &lnrpc.OpenChannelRequest{
+		NodePubkeyString:   req.Pubkey,
+		LocalFundingAmount: int64(req.Amount),
+	}
+	ctx, cancel = h.cfg.rpcContext()
+	defer cancel()
+	chanPoint, err := lnd.OpenChannelSync(ctx, openReq)
+	if err != nil {
+		return "", fmt.Errorf("unable to open channel: %w", err)
+	}
+
+	return fundingTxid(chanPoint)
+}
+
+// OpenChannel opens a channel from the hub to the node submitted through the
+// home page form, rendering the outcome back into the home page.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) OpenChannel(w http.ResponseWriter, r *http.Request) {
+	homeTemplate := h.lookupTemplate("index.html")
+	if homeTemplate == nil {
+		log.Error("unable to lookup index")
+		h.renderError(w, http.StatusInternalServerError)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+	page := h.homePage(r, homeInfo)
+
+	req, err := h.parseOpenChannelRequest(r)
+	if err == nil {
+		err = checkFundable(req.Amount, homeInfo.HubBalance, h.cfg)
+	}
+	if err != nil {
+		page.OpenChannelError = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		homeTemplate.Execute(w, page)
+		return
+	}
+
+	log.Infof("Opening channel of %v to %v@%v", req.Amount, req.Pubkey,
+		req.Host)
+	txid, err := h.openChannel(req)
+	if err != nil {
+		log.Errorf("unable to open channel to %v: %v", req.Pubkey, err)
+		page.OpenChannelError = err.Error()
+		w.WriteHeader(http.StatusBadGateway)
+		homeTemplate.Execute(w, page)
+		return
+	}
+
+	log.Infof("Opened channel to %v, funding txid %v", req.Pubkey, txid)
+	page.FundingTxid = txid
+	homeTemplate.Execute(w, page)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestOpenChannelHubBalance checks that channel opens are checked against
+// the balance of the hub's own wallet, not the one summed with the other
+// nodes.
+func TestOpenChannelHubBalance(t *testing.T) {
+	hubLnd := &fakeLnd{
+		info: testnetInfo(),
+		balance: &lnrpc.WalletBalanceResponse{
+			ConfirmedBalance: 150000,
+		},
+	}
+	otherLnd := &fakeLnd{
+		info: testnetInfo(),
+		balance: &lnrpc.WalletBalanceResponse{
+			ConfirmedBalance: 10000000,
+		},
+	}
+
+	cfg := newTestConfig()
+	cfg.WalletReserve = 100000
+	cfg.EnableMainnetWrites = true
+	h := newTestHub(t, cfg, hubLnd)
+	h.nodes = []*backendNode{{name: "other", lnd: otherLnd}}
+
+	homeCtx, err := h.fetchHomePage()
+	if err != nil {
+		t.Fatalf("unable to fetch home page: %v", err)
+	}
+	h.stats.set(homeCtx)
+
+	if homeCtx.Balance != 10150000 {
+		t.Fatalf("expected an aggregated balance of 10150000, got %d",
+			int64(homeCtx.Balance))
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	if fundable := h.homePage(r, homeCtx).MaxFundable; fundable != 50000 {
+		t.Fatalf("expected 50000 fundable, got %d", int64(fundable))
+	}
+
+	form := url.Values{
+		"pubkey": {"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28" +
+			"d959f2815b16f81798"},
+		"host":   {"127.0.0.1:9735"},
+		"amount": {"100000"},
+	}
+	r = httptest.NewRequest("POST", "/openchannel",
+		strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.OpenChannel(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "fund channels of up to") {
+		t.Fatal("expected the fundable amount to be reported")
+	}
+	if hubLnd.callCount("OpenChannelSync") != 0 {
+		t.Fatal("channel opened beyond the hub's balance")
+	}
+}
+
+// TestOpenChannelFundable checks that channel opens above the hub's balance
+// minus the reserve are rejected, both against the cached balance and the
+// current one at open time.
+func TestOpenChannelFundable(t *testing.T) {
+	const (
+		pubkey = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28" +
+			"d959f2815b16f81798"
+		txid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127" +
+			"b7afdeda33b"
+	)
+
+	tests := []struct {
+		name          string
+		cachedBalance int64
+		balance       int64
+		amount        string
+		wantCode      int
+		wantOpen      bool
+		wantBody      string
+	}{{
+		name:          "above the amount",
+		cachedBalance: 1000000,
+		balance:       1000000,
+		amount:        "200000",
+		wantCode:      http.StatusOK,
+		wantOpen:      true,
+		wantBody:      txid,
+	}, {
+		name:          "exactly fundable",
+		cachedBalance: 300000,
+		balance:       300000,
+		amount:        "200000",
+		wantCode:      http.StatusOK,
+		wantOpen:      true,
+		wantBody:      txid,
+	}, {
+		name:          "below the amount",
+		cachedBalance: 250000,
+		balance:       250000,
+		amount:        "200000",
+		wantCode:      http.StatusBadRequest,
+		wantBody:      "fund channels of up to 0.0015 DCR",
+	}, {
+		name:          "balance spent since cached",
+		cachedBalance: 1000000,
+		balance:       250000,
+		amount:        "200000",
+		wantCode:      http.StatusBadGateway,
+		wantBody:      "fund channels of up to 0.0015 DCR",
+	}}
+
+	for _, test := range tests {
+		lnd := &fakeLnd{
+			info: testnetInfo(),
+			balance: &lnrpc.WalletBalanceResponse{
+				ConfirmedBalance: test.balance,
+			},
+			chanPoint: &lnrpc.ChannelPoint{
+				FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{
+					FundingTxidStr: txid,
+				},
+			},
+		}
+		cfg := newTestConfig()
+		cfg.WalletReserve = 100000
+		h := newTestHub(t, cfg, lnd)
+		err := h.setContext(&templateContext{
+			DcrlndVersion: "0.2.1",
+			HubBalance:    dcrutil.Amount(test.cachedBalance),
+		})
+		if err != nil {
+			t.Fatalf("%s: unable to set context: %v", test.name, err)
+		}
+
+		form := url.Values{
+			"pubkey": {pubkey},
+			"host":   {"127.0.0.1:9735"},
+			"amount": {test.amount},
+		}
+		r := httptest.NewRequest("POST", "/openchannel",
+			strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type",
+			"application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.OpenChannel(w, r)
+
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+		}
+		if !strings.Contains(w.Body.String(), test.wantBody) {
+			t.Errorf("%s: response doesn't contain %q", test.name,
+				test.wantBody)
+		}
+		opened := lnd.callCount("OpenChannelSync") != 0
+		if opened != test.wantOpen {
+			t.Errorf("%s: got channel opened %v, want %v",
+				test.name, opened, test.wantOpen)
+		}
+		if !opened {
+			continue
+		}
+
+		// The channel is opened to the submitted node, for the
+		// submitted amount, once connected to its host.
+		req := lnd.lastRequest("OpenChannelSync")
+		openReq := req.(*lnrpc.OpenChannelRequest)
+		if openReq.NodePubkeyString != pubkey {
+			t.Errorf("%s: got channel opened to %q, want %q",
+				test.name, openReq.NodePubkeyString, pubkey)
+		}
+		amount := strconv.FormatInt(openReq.LocalFundingAmount, 10)
+		if amount != test.amount {
+			t.Errorf("%s: got funding amount %s, want %s",
+				test.name, amount, test.amount)
+		}
+		req = lnd.lastRequest("ConnectPeer")
+		connectReq := req.(*lnrpc.ConnectPeerRequest)
+		if connectReq.Addr.Pubkey != pubkey ||
+			connectReq.Addr.Host != "127.0.0.1:9735" {
+
+			t.Errorf("%s: got peer connected at %s@%s, want "+
+				"%s@127.0.0.1:9735", test.name,
+				connectReq.Addr.Pubkey, connectReq.Addr.Host,
+				pubkey)
+		}
+	}
+}
+
+// TestSuggestChannelSize checks that the suggested channel size is the
+// median capacity of the hub's channels, within the channel size limits,
+// unless a size is configured.
+func TestSuggestChannelSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		capacities []int64
+		configured int64
+		want       dcrutil.Amount
+	}{{
+		name: "no channels",
+		want: 20000,
+	}, {
+		name:       "odd count",
+		capacities: []int64{500000, 100000, 300000},
+		want:       300000,
+	}, {
+		name:       "even count",
+		capacities: []int64{400000, 100000, 300000, 200000},
+		want:       250000,
+	}, {
+		name:       "below minimum",
+		capacities: []int64{10000, 5000, 30000},
+		want:       20000,
+	}, {
+		name:       "above maximum",
+		capacities: []int64{200000000, 300000000},
+		want:       100000000,
+	}, {
+		name:       "configured",
+		capacities: []int64{500000, 100000, 300000},
+		configured: 42000,
+		want:       42000,
+	}}
+
+	for _, test := range tests {
+		var channels []*lnrpc.Channel
+		for _, capacity := range test.capacities {
+			channels = append(channels, &lnrpc.Channel{
+				Capacity: capacity,
+			})
+		}
+		cfg := newTestConfig()
+		cfg.SuggestedChannelSize = test.configured
+
+		if got := suggestChannelSize(channels, cfg); got != test.want {
+			t.Errorf("%s: got size %d, want %d", test.name,
+				int64(got), int64(test.want))
+		}
+	}
+}
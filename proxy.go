@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseTrustedProxies parses the configured trusted proxies, which may either
+// be single IP addresses or CIDR ranges.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy "+
+					"address %q", proxy)
+			}
+
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range "+
+				"%q: %v", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// isTrustedProxy returns true if the passed IP address belongs to one of the
+// configured trusted proxies.
+func (h *lightningHub) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range h.cfg.trustedProxyNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remoteIP returns the IP address the request was received from.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+// fromTrustedProxy returns true if the request was received directly from one
+// of the configured trusted proxies.
+func (h *lightningHub) fromTrustedProxy(r *http.Request) bool {
+	return h.isTrustedProxy(remoteIP(r))
+}
+
+// clientIP returns the IP address of the visitor. When the request was
+// received from a trusted proxy, the X-Forwarded-For header is walked from
+// the right, skipping the trusted proxies, since only the entries added by
+// them can be trusted.
+func (h *lightningHub) clientIP(r *http.Request) net.IP {
+	ip := remoteIP(r)
+	if !h.isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !h.isTrustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// requestScheme returns the scheme the visitor used to reach the hub. When
+// behind a TLS-terminating proxy the hub only sees plain HTTP, so the
+// X-Forwarded-Proto header is honored, but only when set by a trusted proxy.
+// The configured forced scheme takes precedence over everything else.
+func (h *lightningHub) requestScheme(r *http.Request) string {
+	if h.cfg.ForceScheme != "" {
+		return h.cfg.ForceScheme
+	}
+
+	if h.fromTrustedProxy(r) {
+		proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto"))
+		if proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// isHTTPS returns true if the visitor reached the hub over HTTPS.
+func (h *lightningHub) isHTTPS(r *http.Request) bool {
+	return h.requestScheme(r) == "https"
+}
+
+// requestHost returns the host the visitor used to reach the hub, honoring the
+// X-Forwarded-Host header only when set by a trusted proxy.
+func (h *lightningHub) requestHost(r *http.Request) string {
+	if h.fromTrustedProxy(r) {
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+
+	return r.Host
+}
+
+// parseDomains parses the comma-separated list of domains the hub is served
+// on. The domains are bare host names, without scheme nor port.
+func parseDomains(list string) ([]string, error) {
+	var domains []string
+	for _, domain := range strings.Split(list, ",") {
+		domain = strings.TrimSpace(domain)
+		if domain == "" {
+			continue
+		}
+		if strings.ContainsAny(domain, ":/") {
+			return nil, fmt.Errorf("invalid domain %q, expected a "+
+				"host name without scheme nor port", domain)
+		}
+		domains = append(domains, strings.ToLower(domain))
+	}
+
+	return domains, nil
+}
+
+// primaryDomain returns the domain used to build the hub's absolute URLs,
+// which is the first configured one, or an empty string if none is
+// configured.
+func (h *lightningHub) primaryDomain() string {
+	if len(h.cfg.domains) == 0 {
+		return ""
+	}
+
+	return h.cfg.domains[0]
+}
+
+// hostMatchesDomain returns true if the visitor reached the hub through one
+// of the configured domains, or if no domain is configured.
+func (h *lightningHub) hostMatchesDomain(r *http.Request) bool {
+	if len(h.cfg.domains) == 0 {
+		return true
+	}
+
+	host := h.requestHost(r)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	for _, domain := range h.cfg.domains {
+		if strings.EqualFold(host, domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// absoluteURL builds the absolute URL of the passed path on the hub. The
+// primary domain is always preferred over the request's host, so a forged
+// Host header can't be used to produce URLs pointing elsewhere.
+func (h *lightningHub) absoluteURL(r *http.Request, path string) string {
+	host := h.primaryDomain()
+	if host == "" {
+		host = h.requestHost(r)
+	}
+
+	return fmt.Sprintf("%s://%s%s", h.requestScheme(r), host, path)
+}
+
+// requireDomainHost wraps a handler that builds absolute URLs, rejecting the
+// requests that didn't reach the hub through the configured domain when host
+// validation is enabled.
+func (h *lightningHub) requireDomainHost(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.cfg.ValidateHost && !h.hostMatchesDomain(r) {
+			log.Debugf("Rejecting request for %v with unexpected "+
+				"host %q", r.URL.Path, h.requestHost(r))
+			http.Error(w, "421 Misdirected Request.",
+				http.StatusMisdirectedRequest)
+			return
+		}
+
+		next(w, r)
+	}
+}
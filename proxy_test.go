@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseDomains checks that a comma-separated list of domains is parsed
+// into bare lowercase host names.
+func TestParseDomains(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr bool
+	}{{
+		name: "empty",
+		list: "",
+	}, {
+		name: "single domain",
+		list: "hub.example.com",
+		want: []string{"hub.example.com"},
+	}, {
+		name: "multiple domains",
+		list: "hub.example.com, WWW.Hub.Example.com,,",
+		want: []string{"hub.example.com", "www.hub.example.com"},
+	}, {
+		name:    "scheme",
+		list:    "hub.example.com,https://www.hub.example.com",
+		wantErr: true,
+	}, {
+		name:    "port",
+		list:    "hub.example.com:443",
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		domains, err := parseDomains(test.list)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(domains, test.want) {
+			t.Errorf("%s: got domains %q, want %q", test.name,
+				domains, test.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	// maxQRDataLen is the maximum length of the data encoded in a QR code,
+	// long enough for any invoice the hub creates.
+	maxQRDataLen = 2048
+
+	// qrSize is the width and height in pixels of the QR codes.
+	qrSize = 256
+
+	// maxQRCacheEntries is the number of QR codes kept in the QR cache,
+	// which is emptied once full.
+	maxQRCacheEntries = 32
+)
+
+// qrCache caches the PNG QR codes rendered by the QR endpoint, keyed by the
+// data they encode.
+type qrCache struct {
+	mtx  sync.Mutex
+	pngs map[string][]byte
+
+	// pinnedData and pinnedPNG are the prefetched donation invoice and its
+	// QR code. They're kept apart from the other entries, so emptying the
+	// cache once full never evicts the QR code of the invoice currently
+	// shown.
+	pinnedData string
+	pinnedPNG  []byte
+}
+
+// get returns the QR code of the passed data, or nil if it isn't cached.
+func (c *qrCache) get(data string) []byte {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if data == c.pinnedData {
+		return c.pinnedPNG
+	}
+	return c.pngs[data]
+}
+
+// pin caches the QR code of the passed data until another one is pinned.
+func (c *qrCache) pin(data string, png []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.pinnedData = data
+	c.pinnedPNG = png
+}
+
+// set caches the QR code of the passed data.
+func (c *qrCache) set(data string, png []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.pngs == nil || len(c.pngs) >= maxQRCacheEntries {
+		c.pngs = make(map[string][]byte)
+	}
+	c.pngs[data] = png
+}
+
+// qrPNG returns the PNG QR code of the passed data, only rendering it if it
+// isn't cached yet.
+func (h *lightningHub) qrPNG(data string) ([]byte, error) {
+	if png := h.qrCache.get(data); png != nil {
+		return png, nil
+	}
+
+	png, err := qrcode.Encode(data, qrcode.Medium, qrSize)
+	if err != nil {
+		return nil, err
+	}
+
+	h.qrCache.set(data, png)
+	return png, nil
+}
+
+// prefetchInvoice renders the QR code of the passed donation invoice ahead of
+// the visitors asking for it, and pins it in the QR cache so it's served
+// right away for as long as the invoice is shown.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) prefetchInvoice(payReq string) {
+	png, err := qrcode.Encode(payReq, qrcode.Medium, qrSize)
+	if err != nil {
+		log.Warnf("Unable to prefetch the donation invoice QR code: %v",
+			err)
+		return
+	}
+
+	h.qrCache.pin(payReq, png)
+}
+
+// qrEncodable returns true if the passed data is shown by the hub and thus
+// may be encoded as a QR code. Only the node's URIs and donation details are
+// encodable, so the endpoint can't be used to generate QR codes for arbitrary
+// payloads.
+func (c *templateContext) qrEncodable(data string) bool {
+	if data == "" {
+		return false
+	}
+	if data == c.DonationAddr || data == c.DonationInvoice {
+		return true
+	}
+	for _, uri := range c.NodeURIs {
+		if data == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+// QRCode renders the data query parameter as a PNG QR code. The data must be
+// one of the values shown by the hub.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) QRCode(w http.ResponseWriter, r *http.Request) {
+	data := r.URL.Query().Get("data")
+	if len(data) > maxQRDataLen {
+		http.Error(w, fmt.Sprintf("data longer than %d bytes",
+			maxQRDataLen), http.StatusBadRequest)
+		return
+	}
+
+	homeInfo := h.freshContext()
+	if homeInfo == nil {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+	if !homeInfo.qrEncodable(data) {
+		http.Error(w, "404 Not Found.", http.StatusNotFound)
+		return
+	}
+
+	png, err := h.qrPNG(data)
+	if err != nil {
+		log.Errorf("unable to encode QR code: %v", err)
+		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	// The same data always yields the same QR code.
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(png)
+}
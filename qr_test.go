@@ -0,0 +1,152 @@
+package main
+
+import (
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TestQRCode checks that the QR codes of the values shown by the hub are
+// served, and only them.
+func TestQRCode(t *testing.T) {
+	const uri = "02aa@203.0.113.5:9735"
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		NodeURIs:      []string{uri},
+		DonationAddr:  "TsAddr",
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	request := func(data string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		target := "/qr?data=" + url.QueryEscape(data)
+		hub.QRCode(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	w := request(uri)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Fatalf("expected a PNG, got %q", ct)
+	}
+	img, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+
+	// Sample the center of every module of the image, which must match
+	// the modules of the QR code encoding the URI.
+	qr, err := qrcode.New(uri, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("unable to encode QR code: %v", err)
+	}
+	bitmap := qr.Bitmap()
+	size := img.Bounds().Dx()
+	if size != qrSize {
+		t.Fatalf("expected a %dpx QR code, got %dpx", qrSize, size)
+	}
+	for y, row := range bitmap {
+		for x, dark := range row {
+			px := (2*x + 1) * size / (2 * len(bitmap))
+			py := (2*y + 1) * size / (2 * len(bitmap))
+			r, _, _, _ := img.At(px, py).RGBA()
+			if (r < 0x8000) != dark {
+				t.Fatalf("module (%d, %d) doesn't match the "+
+					"URI", x, y)
+			}
+		}
+	}
+
+	if w := request("arbitrary payload"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for arbitrary data, got %d",
+			w.Code)
+	}
+	long := strings.Repeat("a", maxQRDataLen+1)
+	if w := request(long); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for long data, got %d", w.Code)
+	}
+}
+
+// waitPrefetched waits for the QR code of the passed payment request to be
+// rendered.
+func waitPrefetched(t *testing.T, hub *lightningHub, payReq string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.qrCache.get(payReq) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("invoice %v not prefetched", payReq)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPrefetchDonationInvoice checks that the QR code of every new donation
+// invoice is rendered as soon as it's created when enabled, and kept until
+// the next invoice even once the cache is full.
+func TestPrefetchDonationInvoice(t *testing.T) {
+	const payReq = "lntdcr10u1p0hub"
+	cfg := newTestConfig()
+	cfg.PrefetchDonationInvoice = true
+	hub := newTestHub(t, cfg, &fakeLnd{})
+
+	setInvoice := func(payReq string) {
+		t.Helper()
+
+		err := hub.setContext(&templateContext{
+			DcrlndVersion:   "0.2.1",
+			DonationInvoice: payReq,
+		})
+		if err != nil {
+			t.Fatalf("unable to set context: %v", err)
+		}
+	}
+
+	setInvoice(payReq)
+	waitPrefetched(t, hub, payReq)
+
+	// The QR endpoint serves the prefetched QR code.
+	w := httptest.NewRecorder()
+	hub.QRCode(w, httptest.NewRequest("GET", "/qr?data="+payReq, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got QR status %d, want 200", w.Code)
+	}
+	if string(w.Body.Bytes()) != string(hub.qrCache.get(payReq)) {
+		t.Fatalf("QR code not served from the cache")
+	}
+
+	// Filling the cache doesn't evict the prefetched QR code.
+	for i := 0; i <= maxQRCacheEntries; i++ {
+		hub.qrCache.set(strconv.Itoa(i), []byte{1})
+	}
+	if hub.qrCache.get(payReq) == nil {
+		t.Fatal("prefetched QR code evicted")
+	}
+
+	// A new invoice replaces the prefetched one.
+	setInvoice("lntdcr10u1p0new")
+	waitPrefetched(t, hub, "lntdcr10u1p0new")
+	if hub.qrCache.get(payReq) != nil {
+		t.Fatal("QR code of the previous invoice still pinned")
+	}
+
+	// Nothing is prefetched when disabled.
+	hub = newTestHub(t, newTestConfig(), &fakeLnd{})
+	setInvoice(payReq)
+	time.Sleep(50 * time.Millisecond)
+	if hub.qrCache.get(payReq) != nil {
+		t.Fatalf("QR code prefetched with prefetch disabled")
+	}
+}
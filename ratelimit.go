@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRateLimitBuckets is the number of buckets above which the
+	// buckets that are full again are dropped, to bound the memory used
+	// by the rate limiter.
+	maxRateLimitBuckets = 10000
+)
+
+// tokenBucket holds the tokens available to a single client.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter is a per-client token bucket rate limiter. Each client starts
+// with a full bucket of burst tokens, refilled at rate tokens per second, and
+// each request takes a token.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mtx     sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newRateLimiter creates a rate limiter allowing perMinute requests per
+// minute to each client, which may all be made at once.
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// refill adds the tokens accumulated by the bucket since it was last updated.
+func (l *rateLimiter) refill(bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+	bucket.updated = now
+}
+
+// allow takes a token from the bucket of the passed client. If the bucket is
+// empty, it returns false along with how long until a token is available.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.prune(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[client] = bucket
+	}
+	l.refill(bucket, now)
+
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / l.rate
+		return false, time.Duration(wait * float64(time.Second))
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+// prune drops the buckets which are full, as they're no different from a new
+// bucket.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, bucket := range l.buckets {
+		l.refill(bucket, now)
+		if bucket.tokens >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// rateLimit wraps a handler that spends the node's funds or changes its
+// state, limiting how often each visitor can call it. Requests above the limit
+// are rejected with 429 and a Retry-After header.
+func (h *lightningHub) rateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.limiter == nil {
+			next(w, r)
+			return
+		}
+
+		client := h.clientIP(r).String()
+		if ok, wait := h.limiter.allow(client); !ok {
+			log.Debugf("Rate limiting request for %v from %v",
+				r.URL.Path, client)
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+			http.Error(w, "429 Too Many Requests.",
+				http.StatusTooManyRequests)
+			return
+		}
+
+		next(w, r)
+	}
+}
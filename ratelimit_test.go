@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// TestRateLimit checks that requests past the limit are rejected with 429
+// and a Retry-After header, each client IP having its own limit.
+func TestRateLimit(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.trustedProxyNets, _ = parseTrustedProxies([]string{"10.0.0.1"})
+	hub := newTestHub(t, cfg, &fakeLnd{})
+	hub.limiter = newRateLimiter(2)
+
+	ok := hub.rateLimit(func(w http.ResponseWriter, r *http.Request) {})
+	request := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/openchannel", nil)
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		ok(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantCode     int
+	}{{
+		name:       "first request",
+		remoteAddr: "192.0.2.1:1234",
+		wantCode:   http.StatusOK,
+	}, {
+		name:       "second request",
+		remoteAddr: "192.0.2.1:1235",
+		wantCode:   http.StatusOK,
+	}, {
+		name:       "past the limit",
+		remoteAddr: "192.0.2.1:1236",
+		wantCode:   http.StatusTooManyRequests,
+	}, {
+		name:         "untrusted forwarded header",
+		remoteAddr:   "192.0.2.1:1237",
+		forwardedFor: "198.51.100.7",
+		wantCode:     http.StatusTooManyRequests,
+	}, {
+		name:       "other client",
+		remoteAddr: "192.0.2.2:1234",
+		wantCode:   http.StatusOK,
+	}, {
+		name:         "client behind trusted proxy",
+		remoteAddr:   "10.0.0.1:4321",
+		forwardedFor: "192.0.2.3",
+		wantCode:     http.StatusOK,
+	}, {
+		name:         "limited client behind trusted proxy",
+		remoteAddr:   "10.0.0.1:4322",
+		forwardedFor: "192.0.2.1",
+		wantCode:     http.StatusTooManyRequests,
+	}}
+
+	for _, test := range tests {
+		w := request(test.remoteAddr, test.forwardedFor)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name,
+				w.Code, test.wantCode)
+			continue
+		}
+
+		retryAfter := w.Header().Get("Retry-After")
+		if test.wantCode != http.StatusTooManyRequests {
+			if retryAfter != "" {
+				t.Errorf("%s: got Retry-After %q on an allowed "+
+					"request", test.name, retryAfter)
+			}
+			continue
+		}
+		secs, err := strconv.Atoi(retryAfter)
+		if err != nil || secs < 1 || secs > 30 {
+			t.Errorf("%s: got Retry-After %q, want 1-30 seconds",
+				test.name, retryAfter)
+		}
+	}
+}
+
+// TestRateLimitDisabled checks that no request is limited without a
+// configured rate limit.
+func TestRateLimitDisabled(t *testing.T) {
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	ok := hub.rateLimit(func(w http.ResponseWriter, r *http.Request) {})
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		ok(w, httptest.NewRequest("POST", "/connect", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want 200", i,
+				w.Code)
+		}
+	}
+}
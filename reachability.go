@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	// reachabilityDialTimeout is how long to wait for a connection to one
+	// of the node's addresses before considering it unreachable.
+	reachabilityDialTimeout = 30 * time.Second
+)
+
+// addrReachability is the result of checking whether one of the node's
+// advertised addresses accepts connections.
+type addrReachability struct {
+	Address   string
+	Onion     bool
+	Checked   bool
+	Reachable bool
+	Error     string
+	CheckedAt time.Time
+}
+
+// reachabilityChecker holds the result of the last reachability check of
+// each of the node's advertised addresses.
+type reachabilityChecker struct {
+	mtx     sync.RWMutex
+	results []addrReachability
+}
+
+// get returns the results of the last reachability check.
+func (c *reachabilityChecker) get() []addrReachability {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.results
+}
+
+// set replaces the results of the last reachability check.
+func (c *reachabilityChecker) set(results []addrReachability) {
+	c.mtx.Lock()
+	c.results = results
+	c.mtx.Unlock()
+}
+
+// checkAddr attempts a connection to the passed host:port address, going
+// through the configured Tor proxy for onion addresses. Onion addresses are
+// left unchecked when no Tor proxy is configured.
+func (h *lightningHub) checkAddr(addr string) addrReachability {
+	host, _, _ := net.SplitHostPort(addr)
+	result := addrReachability{
+		Address:   addr,
+		Onion:     strings.HasSuffix(host, ".onion"),
+		CheckedAt: time.Now(),
+	}
+
+	var conn net.Conn
+	var err error
+	switch {
+	case result.Onion && h.cfg.TorProxy == "":
+		result.Error = "no Tor proxy configured"
+		return result
+
+	case result.Onion:
+		var dialer proxy.Dialer
+		dialer, err = proxy.SOCKS5(
+			"tcp", h.cfg.TorProxy, nil,
+			&net.Dialer{Timeout: reachabilityDialTimeout},
+		)
+		if err == nil {
+			conn, err = dialer.Dial("tcp", addr)
+		}
+
+	default:
+		conn, err = net.DialTimeout("tcp", addr, reachabilityDialTimeout)
+	}
+
+	result.Checked = true
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conn.Close()
+	result.Reachable = true
+
+	return result
+}
+
+// checkURIs checks the reachability of the address of each of the passed
+// node URIs.
+func (h *lightningHub) checkURIs(uris []string) []addrReachability {
+	results := make([]addrReachability, 0, len(uris))
+	for _, uri := range uris {
+		// URIs are in the pubkey@host:port format.
+		addr := uri[strings.Index(uri, "@")+1:]
+		result := h.checkAddr(addr)
+		if result.Checked && !result.Reachable {
+			log.Warnf("Node address %v is unreachable: %v", addr,
+				result.Error)
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// checkReachability periodically attempts a connection to each of the
+// node's advertised addresses, so operators can notice when one of them,
+// e.g. their onion service, is down while the others still work. The first
+// check runs as soon as the node's addresses are known, which may only be
+// once a degraded hub reaches dcrlnd.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) checkReachability() {
+	select {
+	case <-h.ready:
+	case <-h.quit:
+		return
+	}
+
+	ticker := time.NewTicker(h.cfg.ReachabilityInterval)
+	defer ticker.Stop()
+
+	for {
+		h.reachability.set(h.checkURIs(h.cachedContext().NodeURIs))
+
+		select {
+		case <-ticker.C:
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// String returns a human readable description of the reachability.
+func (r addrReachability) String() string {
+	switch {
+	case !r.Checked:
+		return fmt.Sprintf("unchecked (%s)", r.Error)
+	case r.Reachable:
+		return "reachable"
+	default:
+		return fmt.Sprintf("unreachable (%s)", r.Error)
+	}
+}
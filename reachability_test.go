@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listenLocal returns a listener on a free local port accepting and closing
+// every connection.
+func listenLocal(t *testing.T) net.Listener {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	return l
+}
+
+// closedAddr returns a local address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	return addr
+}
+
+// TestCheckURIs checks the reachability reported for reachable, unreachable
+// and onion addresses.
+func TestCheckURIs(t *testing.T) {
+	l := listenLocal(t)
+	defer l.Close()
+
+	hub := newTestHub(t, newTestConfig(), &fakeLnd{})
+	results := hub.checkURIs([]string{
+		"02aa@" + l.Addr().String(),
+		"02aa@" + closedAddr(t),
+		"02aa@hubhubhubhubhubh.onion:9735",
+	})
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	if r := results[0]; !r.Checked || !r.Reachable {
+		t.Errorf("listening address: got %v, want reachable", r)
+	}
+	if r := results[1]; !r.Checked || r.Reachable {
+		t.Errorf("closed address: got %v, want unreachable", r)
+	}
+	if r := results[2]; r.Checked || !r.Onion {
+		t.Errorf("onion address without Tor proxy: got %v, want "+
+			"unchecked", r)
+	}
+}
+
+// TestCheckReachabilityDegraded checks that the reachability is checked as
+// soon as a hub started in degraded mode gets its first context, instead of
+// a full interval later.
+func TestCheckReachabilityDegraded(t *testing.T) {
+	l := listenLocal(t)
+	defer l.Close()
+
+	cfg := newTestConfig()
+	cfg.ReachabilityInterval = time.Hour
+	hub := newTestHub(t, cfg, &fakeLnd{})
+	defer close(hub.quit)
+
+	go hub.checkReachability()
+
+	// Nothing can be checked until dcrlnd is reached.
+	time.Sleep(50 * time.Millisecond)
+	if results := hub.reachability.get(); results != nil {
+		t.Fatalf("got results %v before the first context", results)
+	}
+
+	err := hub.setContext(&templateContext{
+		DcrlndVersion: "0.2.1",
+		NodeURIs:      []string{"02aa@" + l.Addr().String()},
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		results := hub.reachability.get()
+		if len(results) == 1 {
+			if !results[0].Reachable {
+				t.Fatalf("got %v, want reachable", results[0])
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("reachability not checked after the first context")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// statsCache holds the last successfully fetched template context along with
+// the time it was fetched. The context is nil while the hub is running
+// degraded and dcrlnd hasn't been reached yet.
+type statsCache struct {
+	mtx     sync.RWMutex
+	context *templateContext
+	updated time.Time
+}
+
+// get returns the cached template context, or nil if none was fetched yet.
+func (c *statsCache) get() *templateContext {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.context
+}
+
+// age returns the cached template context along with how long ago it was
+// fetched.
+func (c *statsCache) age() (*templateContext, time.Duration) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.context, time.Since(c.updated)
+}
+
+// set replaces the cached template context with the passed freshly fetched
+// one, stamping it with the time it was fetched. The context must not be
+// modified once cached, since it's shared by every reader.
+func (c *statsCache) set(homeCtx *templateContext) {
+	now := time.Now()
+	homeCtx.LastUpdated = now
+
+	c.mtx.Lock()
+	c.context = homeCtx
+	c.updated = now
+	c.mtx.Unlock()
+}
+
+// refreshLoop refreshes the template context from dcrlnd on every refresh
+// interval until the hub is stopped. The refreshes go through
+// triggerRefresh, so a tick while a stale read or an admin is already
+// refreshing waits for that refresh instead of starting another one. A failed
+// refresh keeps the last good context around, so the hub keeps serving it
+// instead of erroring.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *lightningHub) refreshLoop() {
+	ticker := time.NewTicker(h.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.quit:
+			return
+		}
+
+		select {
+		case <-h.triggerRefresh().done:
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// freshContext returns the cached template context, making sure it's not
+// older than the configured maximum staleness. When it is, a refresh is
+// triggered and waited for, up to the configured timeout, before returning.
+// Concurrent stale reads share a single refresh, so only one burst of RPCs
+// is sent to dcrlnd. If the refresh fails or times out the stale context is
+// returned, which is nil if dcrlnd was never reached.
+func (h *lightningHub) freshContext() *templateContext {
+	homeInfo, age := h.stats.age()
+	if homeInfo != nil && age <= h.cfg.MaxStaleness {
+		return homeInfo
+	}
+
+	select {
+	case <-h.triggerRefresh().done:
+	case <-time.After(h.cfg.StaleRefreshTimeout):
+		log.Warnf("Refresh of stale data took longer than %v",
+			h.cfg.StaleRefreshTimeout)
+	}
+
+	return h.cachedContext()
+}
+
+// pendingRefresh is a refresh of the template context in flight, shared by
+// everyone waiting for it.
+type pendingRefresh struct {
+	// started is the time the refresh started.
+	started time.Time
+
+	// done is closed once the refresh is done, err being the error it
+	// failed with, if any.
+	done chan struct{}
+	err  error
+}
+
+// triggerRefresh starts refreshing the template context unless a refresh is
+// already in flight, and returns the refresh in flight.
+func (h *lightningHub) triggerRefresh() *pendingRefresh {
+	h.refreshMtx.Lock()
+	defer h.refreshMtx.Unlock()
+
+	if h.refreshing != nil {
+		return h.refreshing
+	}
+
+	refresh := &pendingRefresh{
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	h.refreshing = refresh
+	go func() {
+		degraded := h.cachedContext() == nil
+		homeInfo, err := h.refresh()
+		if err != nil {
+			log.Warnf("Unable to refresh data from dcrlnd, serving "+
+				"the last known data: %v", err)
+		} else if err = h.setContext(homeInfo); err != nil {
+			log.Errorf("%v, staying in degraded mode", err)
+		} else if degraded {
+			log.Infof("dcrlnd is reachable, leaving degraded mode")
+		}
+		refresh.err = err
+
+		h.refreshMtx.Lock()
+		h.refreshing = nil
+		h.refreshMtx.Unlock()
+		close(refresh.done)
+	}()
+
+	return refresh
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStatsCacheConcurrent hammers the stats cache from concurrent readers
+// and writers, so data races are caught when run with -race.
+func TestStatsCacheConcurrent(t *testing.T) {
+	var cache statsCache
+	var wg sync.WaitGroup
+
+	const workers, iterations = 8, 200
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				cache.set(&templateContext{
+					ChannelsCount: uint32(i*iterations + j),
+				})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if homeCtx := cache.get(); homeCtx != nil {
+					_ = homeCtx.ChannelsCount
+				}
+				if homeCtx, age := cache.age(); homeCtx != nil &&
+					age < 0 {
+
+					t.Errorf("negative age %v", age)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.get() == nil {
+		t.Fatal("no context cached")
+	}
+}
+
+// TestRefreshLoopShared checks that a tick of the refresh loop while a stale
+// read is refreshing the context waits for that refresh instead of sending
+// its own burst of RPCs to dcrlnd.
+func TestRefreshLoopShared(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.RefreshInterval = 10 * time.Millisecond
+	cfg.StaleRefreshTimeout = time.Second
+	lnd := &fakeLnd{info: testnetInfo(), delay: 100 * time.Millisecond}
+	hub := newTestHub(t, cfg, lnd)
+
+	read := make(chan *templateContext)
+	go func() {
+		read <- hub.freshContext()
+	}()
+	for lnd.callCount("GetInfo") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	loopDone := make(chan struct{})
+	go func() {
+		hub.refreshLoop()
+		close(loopDone)
+	}()
+
+	// Let the loop tick a few times while the stale read's refresh is
+	// still in flight.
+	time.Sleep(5 * cfg.RefreshInterval)
+	close(hub.quit)
+	<-loopDone
+
+	if homeCtx := <-read; homeCtx == nil {
+		t.Fatal("stale read returned no context")
+	}
+	if n := lnd.callCount("GetInfo"); n != 1 {
+		t.Fatalf("expected a single shared refresh, got %d GetInfo "+
+			"calls", n)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// rpcMaxBackoff is the maximum time to wait between two attempts of
+	// an RPC to dcrlnd.
+	rpcMaxBackoff = 5 * time.Second
+)
+
+// isRetryable returns true if the passed RPC error is likely transient, so
+// the RPC may succeed if attempted again. Other errors, such as a rejected
+// macaroon, won't go away by retrying.
+func isRetryable(err error) bool {
+	switch grpcCode(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryRPC calls the passed RPC, retrying it with an exponential backoff up
+// to the configured number of retries as long as it fails with a retryable
+// error. The error of the last attempt is returned.
+func retryRPC(cfg *config, name string, call func() error) error {
+	backoff := cfg.RPCRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || !isRetryable(err) || attempt >= cfg.RPCRetries {
+			return err
+		}
+
+		log.Debugf("rpc %v() failed (retry %d/%d in %v): %v", name,
+			attempt+1, cfg.RPCRetries, backoff, err)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > rpcMaxBackoff {
+			backoff = rpcMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRetryRPC checks that the RPCs fetching the home page are retried on
+// transient errors only.
+func TestRetryRPC(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	unauthenticated := status.Error(codes.Unauthenticated, "bad macaroon")
+
+	tests := []struct {
+		name      string
+		rpc       string
+		errs      []error
+		wantCalls int
+		wantCode  codes.Code
+	}{{
+		name:      "transient errors",
+		rpc:       "GetInfo",
+		errs:      []error{unavailable, unavailable, nil},
+		wantCalls: 3,
+		wantCode:  codes.OK,
+	}, {
+		name:      "retries exhausted",
+		rpc:       "GetInfo",
+		errs:      []error{unavailable},
+		wantCalls: 4,
+		wantCode:  codes.Unavailable,
+	}, {
+		name:      "rejected macaroon",
+		rpc:       "GetInfo",
+		errs:      []error{unauthenticated, nil},
+		wantCalls: 1,
+		wantCode:  codes.Unauthenticated,
+	}, {
+		name:      "pending channels",
+		rpc:       "PendingChannels",
+		errs:      []error{unavailable, nil},
+		wantCalls: 2,
+		wantCode:  codes.OK,
+	}}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.RPCRetries = 3
+		cfg.RPCRetryBackoff = time.Millisecond
+
+		lnd := &fakeLnd{
+			info: testnetInfo(),
+			errs: map[string][]error{test.rpc: test.errs},
+		}
+		_, err := fetchHomePage(lnd, cfg)
+
+		var code codes.Code
+		if err != nil {
+			code = grpcCode(err)
+		}
+		if code != test.wantCode {
+			t.Errorf("%s: got error %v, want code %v", test.name,
+				err, test.wantCode)
+		}
+		if n := lnd.callCount(test.rpc); n != test.wantCalls {
+			t.Errorf("%s: got %d %s calls, want %d", test.name,
+				n, test.rpc, test.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// defaultNoIndexPrefixes are the path prefixes of the operational
+	// endpoints that are kept out of search indexes by default.
+	defaultNoIndexPrefixes = []string{"/api/", "/admin/", "/status"}
+
+	// dynamicPrefixes are the path prefixes of the endpoints which create
+	// or look up data on the node on every request, such as donation
+	// invoices. Crawlers are never allowed on them.
+	dynamicPrefixes = []string{"/donate", "/invoice/", "/qr", "/ws"}
+)
+
+// RobotsTxt serves the crawler policy of the hub. The dynamic endpoints and
+// the ones kept out of search indexes are always disallowed, while the rest of
+// the hub, home page included, may be crawled.
+//
+// Whether the public pages are indexed is left to the X-Robots-Tag header set
+// by robotsTag according to allow_indexing. Crawlers must be able to fetch a
+// page to see that header, so disallowing the whole hub here would hide it.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) RobotsTxt(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, prefix := range dynamicPrefixes {
+		fmt.Fprintf(&b, "Disallow: %s\n", prefix)
+	}
+	for _, prefix := range h.cfg.NoIndexPrefixes {
+		fmt.Fprintf(&b, "Disallow: %s\n", prefix)
+	}
+	b.WriteString("Allow: /\n")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// robotsTag is a middleware setting the X-Robots-Tag header so search engines
+// don't index the operational endpoints (API, admin, status...), whose path
+// prefixes are configurable. The remaining public pages, such as the home
+// page, are only indexable when indexing is allowed.
+func (h *lightningHub) robotsTag(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range h.cfg.NoIndexPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if !h.cfg.AllowIndexing {
+			w.Header().Set("X-Robots-Tag", "noindex")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
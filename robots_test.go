@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRobotsTxt checks that the dynamic and operational endpoints are
+// disallowed, while the home page stays crawlable whether indexing is
+// allowed or not.
+func TestRobotsTxt(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		cfg := newTestConfig()
+		cfg.AllowIndexing = allow
+		cfg.NoIndexPrefixes = defaultNoIndexPrefixes
+		h := &lightningHub{cfg: cfg}
+
+		w := httptest.NewRecorder()
+		h.RobotsTxt(w, httptest.NewRequest("GET", "/robots.txt", nil))
+		lines := strings.Split(w.Body.String(), "\n")
+
+		has := func(line string) bool {
+			for _, l := range lines {
+				if l == line {
+					return true
+				}
+			}
+			return false
+		}
+		for _, rule := range []string{
+			"Disallow: /donate", "Disallow: /invoice/",
+			"Disallow: /api/", "Disallow: /admin/", "Allow: /",
+		} {
+			if !has(rule) {
+				t.Fatalf("allow_indexing=%v: missing %q in:\n%s",
+					allow, rule, w.Body.String())
+			}
+		}
+		if has("Disallow: /") {
+			t.Fatalf("allow_indexing=%v: home page disallowed",
+				allow)
+		}
+	}
+}
+
+// TestRobotsTag checks that the operational endpoints are never indexed,
+// and the public pages only when indexing is allowed.
+func TestRobotsTag(t *testing.T) {
+	tests := []struct {
+		path  string
+		allow bool
+		tag   string
+	}{
+		{"/api/v1/info", true, "noindex, nofollow"},
+		{"/status", false, "noindex, nofollow"},
+		{"/", false, "noindex"},
+		{"/", true, ""},
+	}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.AllowIndexing = test.allow
+		cfg.NoIndexPrefixes = defaultNoIndexPrefixes
+		h := &lightningHub{cfg: cfg}
+
+		w := httptest.NewRecorder()
+		h.robotsTag(http.NotFoundHandler()).ServeHTTP(w,
+			httptest.NewRequest("GET", test.path, nil))
+		if tag := w.Header().Get("X-Robots-Tag"); tag != test.tag {
+			t.Fatalf("%v (allow_indexing=%v): expected %q, got %q",
+				test.path, test.allow, test.tag, tag)
+		}
+	}
+}
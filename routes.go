@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// longLivedRoutes are the routes serving long-lived connections, which are
+// never subject to a timeout.
+var longLivedRoutes = map[string]bool{
+	"ws": true,
+}
+
+// applyRouteTimeouts wraps the handler of every named route with a timeout.
+// Routes use the timeout configured for their name, if any, and the global
+// request timeout otherwise. Configuring a timeout for a route that doesn't
+// exist is an error, so typos don't go unnoticed.
+func applyRouteTimeouts(r *mux.Router, cfg *config) error {
+	routes := make(map[string]*mux.Route)
+	err := r.Walk(func(route *mux.Route, _ *mux.Router,
+		_ []*mux.Route) error {
+
+		if name := route.GetName(); name != "" {
+			routes[name] = route
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for name := range cfg.RouteTimeouts {
+		if longLivedRoutes[name] {
+			return fmt.Errorf("route %q in route_timeout serves "+
+				"long-lived connections and can't have a "+
+				"timeout", name)
+		}
+		if _, ok := routes[name]; ok {
+			continue
+		}
+
+		names := make([]string, 0, len(routes))
+		for name := range routes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown route %q in route_timeout, must be "+
+			"one of %s", name, strings.Join(names, ", "))
+	}
+
+	for name, route := range routes {
+		if longLivedRoutes[name] {
+			continue
+		}
+
+		timeout := cfg.RequestTimeout
+		if routeTimeout, ok := cfg.RouteTimeouts[name]; ok {
+			timeout = routeTimeout
+		}
+		if timeout <= 0 {
+			continue
+		}
+
+		route.Handler(http.TimeoutHandler(
+			route.GetHandler(), timeout, "503 Service Unavailable.",
+		))
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"html/template"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// defaultSanitizeTags are the HTML tags allowed by default in content
+	// provided by the operator.
+	defaultSanitizeTags = []string{
+		"a", "b", "br", "code", "em", "i", "li", "ol", "p", "pre",
+		"strong", "ul",
+	}
+
+	// defaultSanitizeAttrs are the HTML attributes allowed by default, on
+	// any of the allowed tags, in content provided by the operator.
+	defaultSanitizeAttrs = []string{"href", "title"}
+)
+
+// newSanitizer creates the HTML sanitizer applied to all content provided by
+// the operator (custom messages, branding, descriptions...) before it
+// reaches a template context. Only the passed tags and attributes are kept,
+// links are restricted to safe schemes and get rel="nofollow noopener".
+func newSanitizer(tags, attrs []string) *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardURLs()
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowElements(tags...)
+	if len(attrs) > 0 && len(tags) > 0 {
+		p.AllowAttrs(attrs...).OnElements(tags...)
+	}
+
+	return p
+}
+
+// sanitizeHTML sanitizes HTML provided by the operator so it can be safely
+// rendered unescaped by the templates. This is the only way operator-provided
+// content should be marked as template.HTML.
+func (h *lightningHub) sanitizeHTML(s string) template.HTML {
+	return template.HTML(h.sanitizer.Sanitize(s))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+const (
+	// maxSelfTestAmount is the hard cap on the amount of a routing test,
+	// which is only meant to prove the node can route.
+	maxSelfTestAmount dcrutil.Amount = 10000
+)
+
+// probeReachedDest returns true if the passed payment error was returned by
+// the destination of a probe, rejecting its unknown payment hash. Older nodes
+// report the failure as UnknownPaymentHash.
+func probeReachedDest(paymentErr string) bool {
+	return strings.Contains(paymentErr, "IncorrectOrUnknownPaymentDetails") ||
+		strings.Contains(paymentErr, "UnknownPaymentHash")
+}
+
+// probeDestination returns the default destination of a routing test: the
+// peer of the active channel with the largest local balance, which is the
+// most likely to carry the probe. It returns an empty string if the node
+// has no channel able to send the passed amount.
+func probeDestination(channels []*lnrpc.Channel, amt dcrutil.Amount) string {
+	var dest string
+	var best int64
+	for _, channel := range channels {
+		if !channel.Active || channel.LocalBalance < int64(amt) ||
+			channel.LocalBalance <= best {
+
+			continue
+		}
+		dest = channel.RemotePubkey
+		best = channel.LocalBalance
+	}
+
+	return dest
+}
+
+// selfTestResponse is the response of the routing test endpoint.
+type selfTestResponse struct {
+	Success     bool   `json:"success"`
+	Amount      int64  `json:"amount"`
+	Destination string `json:"destination"`
+	PaymentHash string `json:"payment_hash"`
+	LatencyMs   int64  `json:"latency_ms"`
+	Hops        int    `json:"hops,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SelfTest verifies the node can actually route by probing a route to a
+// destination, reporting whether it succeeded and how long it took.
+//
+// dcrlnd can't pay its own invoices, its path finding having no support for
+// circular payments, so the node is tested with a probe instead: a route to
+// the destination is queried and an HTLC with a random payment hash is sent
+// along it. The destination can't settle it and fails it back, which proves
+// the route works without moving any funds.
+//
+// The optional dest form value is the pubkey of the destination, which
+// defaults to the peer of the channel with the largest local balance. The
+// optional amount form value overrides the configured amount, up to
+// maxSelfTestAmount.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) SelfTest(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(r) {
+		http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+
+	amt := dcrutil.Amount(h.cfg.SelfTestAmount)
+	if v := r.FormValue("amount"); v != "" {
+		atoms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || atoms <= 0 {
+			http.Error(w, "invalid amount", http.StatusBadRequest)
+			return
+		}
+		amt = dcrutil.Amount(atoms)
+	}
+	if amt > maxSelfTestAmount {
+		http.Error(w, fmt.Sprintf("amount must be at most %v",
+			maxSelfTestAmount), http.StatusBadRequest)
+		return
+	}
+
+	dest := r.FormValue("dest")
+	if dest != "" {
+		if err := checkNodePubkey(dest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		homeInfo := h.freshContext()
+		if homeInfo == nil {
+			http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+			return
+		}
+		dest = probeDestination(homeInfo.ActiveChannels, amt)
+		if dest == "" {
+			http.Error(w, fmt.Sprintf("no active channel can send "+
+				"%v", amt), http.StatusConflict)
+			return
+		}
+	}
+
+	var paymentHash [32]byte
+	if _, err := rand.Read(paymentHash[:]); err != nil {
+		log.Errorf("unable to generate probe payment hash: %v", err)
+		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		return
+	}
+
+	resp := &selfTestResponse{
+		Amount:      int64(amt),
+		Destination: dest,
+		PaymentHash: hex.EncodeToString(paymentHash[:]),
+	}
+	h.probe(resp, paymentHash[:])
+
+	log.Infof("Routing test of %v to %v: success=%v latency=%dms %v",
+		amt, dest, resp.Success, resp.LatencyMs, resp.Error)
+
+	writeJSON(w, resp)
+}
+
+// probe sends a probe with the passed payment hash to the destination of
+// the passed response, filling it with the outcome.
+func (h *lightningHub) probe(resp *selfTestResponse, paymentHash []byte) {
+	start := time.Now()
+	defer func() {
+		resp.LatencyMs = time.Since(start).Milliseconds()
+	}()
+
+	routesReq := &lnrpc.QueryRoutesRequest{
+		PubKey: resp.Destination,
+		Amt:    resp.Amount,
+	}
+	ctx, cancel := h.cfg.rpcContext()
+	routesRes, err := h.client().QueryRoutes(ctx, routesReq)
+	cancel()
+	switch {
+	case err != nil:
+		resp.Error = fmt.Sprintf("rpc QueryRoutes() failed: %v", err)
+		return
+	case len(routesRes.Routes) == 0:
+		resp.Error = "no route found"
+		return
+	}
+	route := routesRes.Routes[0]
+	resp.Hops = len(route.Hops)
+
+	sendReq := &lnrpc.SendToRouteRequest{
+		PaymentHash: paymentHash,
+		Route:       route,
+	}
+	ctx, cancel = h.cfg.rpcContext()
+	defer cancel()
+	sendRes, err := h.client().SendToRouteSync(ctx, sendReq)
+	switch {
+	case err != nil:
+		resp.Error = fmt.Sprintf("rpc SendToRouteSync() failed: %v",
+			err)
+
+	// The probe can't be settled, so the only successful outcome is the
+	// destination rejecting it.
+	case probeReachedDest(sendRes.PaymentError):
+		resp.Success = true
+
+	case sendRes.PaymentError != "":
+		resp.Error = sendRes.PaymentError
+
+	default:
+		resp.Error = "probe unexpectedly settled"
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestProbeDestination checks that the routing test defaults to the peer of
+// the active channel with the largest local balance able to send the amount.
+func TestProbeDestination(t *testing.T) {
+	channels := []*lnrpc.Channel{
+		{RemotePubkey: "small", Active: true, LocalBalance: 500},
+		{RemotePubkey: "inactive", LocalBalance: 90000},
+		{RemotePubkey: "large", Active: true, LocalBalance: 50000},
+		{RemotePubkey: "medium", Active: true, LocalBalance: 20000},
+	}
+
+	if dest := probeDestination(channels, 1000); dest != "large" {
+		t.Fatalf("expected large, got %q", dest)
+	}
+	if dest := probeDestination(channels, 60000); dest != "" {
+		t.Fatalf("expected no destination, got %q", dest)
+	}
+}
+
+// TestSelfTest checks the outcome reported for the possible results of a
+// probe.
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		paymentErr string
+		success    bool
+	}{{
+		name: "rejected by destination",
+		paymentErr: "IncorrectOrUnknownPaymentDetails(amt=1000, " +
+			"height=100)",
+		success: true,
+	}, {
+		name:       "failed on the way",
+		paymentErr: "TemporaryChannelFailure",
+	}, {
+		name: "settled",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lnd := &fakeLnd{
+				routes: &lnrpc.QueryRoutesResponse{
+					Routes: []*lnrpc.Route{{
+						Hops: []*lnrpc.Hop{{}, {}},
+					}},
+				},
+				payment: &lnrpc.SendResponse{
+					PaymentError: test.paymentErr,
+				},
+			}
+			cfg := newTestConfig()
+			cfg.AdminToken = "token"
+			cfg.SelfTestAmount = 1000
+			h := &lightningHub{cfg: cfg, lnd: lnd}
+			h.stats.set(&templateContext{
+				ActiveChannels: []*lnrpc.Channel{{
+					RemotePubkey: "02bb",
+					Active:       true,
+					LocalBalance: 50000,
+				}},
+			})
+
+			r := httptest.NewRequest("POST", "/admin/selftest", nil)
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			h.SelfTest(w, r)
+
+			var resp selfTestResponse
+			err := json.NewDecoder(w.Body).Decode(&resp)
+			if err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if resp.Success != test.success {
+				t.Fatalf("expected success=%v, got %+v",
+					test.success, resp)
+			}
+			if resp.Destination != "02bb" || resp.Hops != 2 {
+				t.Fatalf("unexpected probe %+v", resp)
+			}
+			if lnd.callCount("AddInvoice") != 0 {
+				t.Fatal("the routing test must not create invoices")
+			}
+
+			req := lnd.lastRequest("SendToRouteSync")
+			sendReq := req.(*lnrpc.SendToRouteRequest)
+			if len(sendReq.PaymentHash) != 32 {
+				t.Fatalf("unexpected payment hash %x",
+					sendReq.PaymentHash)
+			}
+		})
+	}
+}
+
+// TestSelfTestUnauthorized checks that the routing test requires the admin
+// token.
+func TestSelfTestUnauthorized(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminToken = "token"
+	lnd := &fakeLnd{}
+	h := &lightningHub{cfg: cfg, lnd: lnd}
+
+	req := httptest.NewRequest("POST", "/admin/selftest",
+		strings.NewReader(""))
+	w := httptest.NewRecorder()
+	h.SelfTest(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if lnd.callCount("SendToRouteSync") != 0 {
+		t.Fatal("unauthorized routing test sent a probe")
+	}
+}
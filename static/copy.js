@@ -0,0 +1,10 @@
+// Copies the value of the data-copy attribute of the clicked elements to the
+// clipboard. It's kept out of the pages since the Content-Security-Policy
+// doesn't allow inline scripts.
+(function () {
+    document.querySelectorAll("[data-copy]").forEach(function (el) {
+        el.addEventListener("click", function () {
+            navigator.clipboard.writeText(el.dataset.copy);
+        });
+    });
+})();
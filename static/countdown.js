@@ -0,0 +1,22 @@
+// Counts down to the expiry of the elements with a data-expiry attribute,
+// holding a unix timestamp in seconds.
+(function () {
+    var elements = document.querySelectorAll("[data-expiry]");
+
+    function pad(n) {
+        return n < 10 ? "0" + n : "" + n;
+    }
+
+    function update() {
+        var now = Date.now() / 1000;
+        elements.forEach(function (el) {
+            var left = Math.max(0, Math.floor(el.dataset.expiry - now));
+            var h = Math.floor(left / 3600);
+            var m = Math.floor(left % 3600 / 60);
+            el.textContent = h + ":" + pad(m) + ":" + pad(left % 60);
+        });
+    }
+
+    update();
+    setInterval(update, 1000);
+})();
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// hubStarted is the time the hub process started.
+var hubStarted = time.Now()
+
+// hubUptime returns how long the hub process has been running at the passed
+// time, formatted by formatUptime.
+func hubUptime(now time.Time) string {
+	return formatUptime(now.Sub(hubStarted))
+}
+
+// formatUptime formats the passed duration as days, hours and minutes, e.g.
+// "3d 4h 5m", leaving out the leading zero units. Durations under a minute
+// are formatted as "0m".
+func formatUptime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if days > 0 || hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	parts = append(parts, fmt.Sprintf("%dm", minutes))
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFormatUptime checks the formatting of the hub's uptime.
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		uptime time.Duration
+		want   string
+	}{
+		{0, "0m"},
+		{59 * time.Second, "0m"},
+		{3 * time.Minute, "3m"},
+		{2*time.Hour + 5*time.Second, "2h 0m"},
+		{26*time.Hour + 3*time.Minute, "1d 2h 3m"},
+		{48 * time.Hour, "2d 0h 0m"},
+		{-time.Minute, "0m"},
+	}
+
+	for _, test := range tests {
+		if got := formatUptime(test.uptime); got != test.want {
+			t.Errorf("formatUptime(%v) = %q, want %q", test.uptime,
+				got, test.want)
+		}
+	}
+}
+
+// TestHubUptime checks that the uptime is counted from the hub's start.
+func TestHubUptime(t *testing.T) {
+	now := hubStarted.Add(26*time.Hour + 3*time.Minute)
+	if got := hubUptime(now); got != "1d 2h 3m" {
+		t.Fatalf("got uptime %q, want 1d 2h 3m", got)
+	}
+}
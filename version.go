@@ -0,0 +1,94 @@
+// Copyright (c) 2020 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// appName is the name reported by dcrlnhub when identifying itself,
+	// for example as part of the gRPC user-agent sent to dcrlnd.
+	appName = "dcrlnhub"
+
+	appMajor uint = 0
+	appMinor uint = 1
+	appPatch uint = 0
+)
+
+// appBuild is defined as a variable so it can be overridden during the build
+// process with '-ldflags "-X main.appBuild=foo"' if needed.
+var appBuild string
+
+// version returns the application version as a properly formed string per the
+// semantic versioning 2.0.0 spec (http://semver.org/).
+func version() string {
+	v := fmt.Sprintf("%d.%d.%d", appMajor, appMinor, appPatch)
+	if appBuild != "" {
+		v = fmt.Sprintf("%s+%s", v, appBuild)
+	}
+
+	return v
+}
+
+// semverPattern matches the major.minor.patch version at the start of a
+// version string, such as the "0.2.1-beta commit=v0.2.1-beta" reported by
+// dcrlnd.
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+// parseSemver parses the major, minor and patch numbers of the passed
+// version string. Anything after them, such as a pre-release or the commit,
+// is ignored.
+func parseSemver(s string) ([3]uint64, error) {
+	var v [3]uint64
+	m := semverPattern.FindStringSubmatch(s)
+	if m == nil {
+		return v, fmt.Errorf("invalid version %q, expected "+
+			"major.minor.patch", s)
+	}
+	for i := range v {
+		n, err := strconv.ParseUint(m[i+1], 10, 32)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: %v", s, err)
+		}
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// checkDcrlndVersion returns an error if the passed dcrlnd version is older
+// than the configured minimum version, or can't be parsed.
+func checkDcrlndVersion(dcrlndVersion string, cfg *config) error {
+	minVersion, err := parseSemver(cfg.MinDcrlndVersion)
+	if err != nil {
+		return err
+	}
+	v, err := parseSemver(dcrlndVersion)
+	if err != nil {
+		return err
+	}
+
+	for i := range v {
+		switch {
+		case v[i] > minVersion[i]:
+			return nil
+		case v[i] < minVersion[i]:
+			return fmt.Errorf("dcrlnd %v is older than the minimum "+
+				"supported version %v", dcrlndVersion,
+				cfg.MinDcrlndVersion)
+		}
+	}
+
+	return nil
+}
+
+// userAgent returns the user-agent string dcrlnhub uses to identify itself to
+// dcrlnd, e.g. "dcrlnhub/0.1.0".
+func userAgent() string {
+	return fmt.Sprintf("%s/%s", appName, version())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestCheckDcrlndVersion checks that dcrlnd versions older than the
+// configured minimum are rejected.
+func TestCheckDcrlndVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		wantErr bool
+	}{
+		{"too old", "0.1.9-beta commit=v0.1.9-beta", true},
+		{"older major", "0.0.12", true},
+		{"minimum", "0.2.0-beta", false},
+		{"newer patch", "0.2.1-beta commit=v0.2.1-beta", false},
+		{"newer major", "1.0.0", false},
+		{"unparsable", "unknown", true},
+	}
+
+	cfg := newTestConfig()
+	cfg.MinDcrlndVersion = "0.2.0"
+	for _, test := range tests {
+		err := checkDcrlndVersion(test.version, cfg)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+		}
+	}
+}
+
+// TestSetContextVersion checks that the version of dcrlnd is checked on the
+// first context, so a degraded hub only leaves degraded mode once it reaches
+// a supported dcrlnd when strict_version is set.
+func TestSetContextVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		strict  bool
+		version string
+		wantErr bool
+	}{
+		{"too old", false, "0.1.0", false},
+		{"too old strict", true, "0.1.0", true},
+		{"acceptable strict", true, "0.2.1", false},
+	}
+
+	for _, test := range tests {
+		cfg := newTestConfig()
+		cfg.MinDcrlndVersion = "0.2.0"
+		cfg.StrictVersion = test.strict
+		hub := newTestHub(t, cfg, &fakeLnd{})
+
+		err := hub.setContext(&templateContext{
+			DcrlndVersion: test.version,
+		})
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got error %v, want error %v", test.name,
+				err, test.wantErr)
+			continue
+		}
+
+		cached := hub.cachedContext() != nil
+		if cached == test.wantErr {
+			t.Errorf("%s: got cached context %v, want %v",
+				test.name, cached, !test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// faviconSize is the width and height in pixels of the generated
+	// favicon.
+	faviconSize = 32
+
+	// webAppBackgroundColor is the background color of the splash screen
+	// shown while the installed hub loads.
+	webAppBackgroundColor = "#ffffff"
+)
+
+// webAppIcon is an icon listed in the web app manifest.
+type webAppIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// webAppManifest is the manifest letting browsers install the hub as a web
+// app.
+type webAppManifest struct {
+	Name            string       `json:"name"`
+	ShortName       string       `json:"short_name"`
+	StartURL        string       `json:"start_url"`
+	Display         string       `json:"display"`
+	ThemeColor      string       `json:"theme_color"`
+	BackgroundColor string       `json:"background_color"`
+	Icons           []webAppIcon `json:"icons"`
+}
+
+// parseHexColor parses a color in the #rrggbb format.
+func parseHexColor(s string) (color.RGBA, error) {
+	if !nodeColorPattern.MatchString(s) {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected "+
+			"#rrggbb", s)
+	}
+	rgb, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	return color.RGBA{
+		R: uint8(rgb >> 16),
+		G: uint8(rgb >> 8),
+		B: uint8(rgb),
+		A: 0xff,
+	}, nil
+}
+
+// renderIcon renders the hub's icon, a disc of the passed color, as a PNG of
+// the passed size.
+func renderIcon(size int, c color.RGBA) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	center := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx := float64(x) + 0.5 - center
+			dy := float64(y) + 0.5 - center
+			if dx*dx+dy*dy <= center*center {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// encodeICO wraps the passed PNG image of the passed size in an ICO file,
+// which every browser accepts as a favicon.
+func encodeICO(pngData []byte, size int) []byte {
+	const headerLen = 6 + 16
+
+	var b bytes.Buffer
+
+	// ICONDIR: reserved, type (1 for icons) and number of images.
+	binary.Write(&b, binary.LittleEndian, [3]uint16{0, 1, 1})
+
+	// ICONDIRENTRY: width, height, palette size and reserved, followed
+	// by the color planes, bits per pixel, size and offset of the image.
+	b.Write([]byte{byte(size), byte(size), 0, 0})
+	binary.Write(&b, binary.LittleEndian, [2]uint16{1, 32})
+	binary.Write(&b, binary.LittleEndian, [2]uint32{
+		uint32(len(pngData)), headerLen,
+	})
+
+	b.Write(pngData)
+	return b.Bytes()
+}
+
+// writeIcon renders the hub's icon of the passed size and writes it as a
+// response of the passed content type, wrapped in an ICO file if asked to.
+func (h *lightningHub) writeIcon(w http.ResponseWriter, size int,
+	contentType string, ico bool) {
+
+	c, err := parseHexColor(h.cfg.ThemeColor)
+	if err != nil {
+		log.Errorf("unable to parse theme color: %v", err)
+		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		return
+	}
+	icon, err := renderIcon(size, c)
+	if err != nil {
+		log.Errorf("unable to render icon: %v", err)
+		http.Error(w, "500 Internal Server Error.", http.StatusInternalServerError)
+		return
+	}
+	if ico {
+		icon = encodeICO(icon, size)
+	}
+
+	// The icon only changes with the config.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(icon)
+}
+
+// Favicon serves the favicon.ico of the static directory, or a generated one
+// in the theme color if there's none.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Favicon(w http.ResponseWriter, r *http.Request) {
+	path := filepath.Join(h.cfg.StaticDir, "favicon.ico")
+	if _, err := os.Stat(path); err == nil {
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	h.writeIcon(w, faviconSize, "image/x-icon", true)
+}
+
+// Icon serves the icons listed in the web app manifest.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) Icon(w http.ResponseWriter, r *http.Request) {
+	// The route only matches the sizes listed in the manifest.
+	size, err := strconv.Atoi(mux.Vars(r)["size"])
+	if err != nil {
+		http.Error(w, "404 Not Found.", http.StatusNotFound)
+		return
+	}
+
+	h.writeIcon(w, size, "image/png", false)
+}
+
+// WebManifest serves the web app manifest, which lets browsers install the
+// hub on a home screen.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) WebManifest(w http.ResponseWriter, r *http.Request) {
+	manifest := &webAppManifest{
+		Name:            h.cfg.SiteName,
+		ShortName:       h.cfg.SiteName,
+		StartURL:        "/",
+		Display:         "standalone",
+		ThemeColor:      h.cfg.ThemeColor,
+		BackgroundColor: webAppBackgroundColor,
+	}
+	for _, size := range []string{"192", "512"} {
+		manifest.Icons = append(manifest.Icons, webAppIcon{
+			Src:   "/icon-" + size + ".png",
+			Sizes: size + "x" + size,
+			Type:  "image/png",
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/manifest+json")
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		log.Errorf("unable to encode web app manifest: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFavicon checks that the favicon is served from the static directory,
+// or generated in the theme color when there's none.
+func TestFavicon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dcrlnhub")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := newTestConfig()
+	cfg.StaticDir = dir
+	cfg.ThemeColor = defaultThemeColor
+	hub := newTestHub(t, cfg, &fakeLnd{})
+
+	// Without a favicon in the static directory, a generated ICO file
+	// wrapping a PNG is served.
+	w := httptest.NewRecorder()
+	hub.Favicon(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/x-icon" {
+		t.Fatalf("got content type %q, want image/x-icon", ct)
+	}
+	ico := w.Body.Bytes()
+	if len(ico) < 22 || !bytes.Equal(ico[:4], []byte{0, 0, 1, 0}) {
+		t.Fatalf("generated favicon isn't an ICO file")
+	}
+	if _, err := png.Decode(bytes.NewReader(ico[22:])); err != nil {
+		t.Fatalf("generated favicon doesn't wrap a PNG: %v", err)
+	}
+
+	// The operator's favicon takes precedence.
+	favicon := []byte{0, 0, 1, 0, 'o', 'w', 'n'}
+	path := filepath.Join(dir, "favicon.ico")
+	if err := ioutil.WriteFile(path, favicon, 0600); err != nil {
+		t.Fatalf("unable to write favicon: %v", err)
+	}
+	w = httptest.NewRecorder()
+	hub.Favicon(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	// The content type comes from the system's MIME types, which may
+	// use either name of the ICO type.
+	ct := w.Header().Get("Content-Type")
+	if ct != "image/x-icon" && ct != "image/vnd.microsoft.icon" {
+		t.Fatalf("got content type %q, want an icon type", ct)
+	}
+	if !bytes.Equal(w.Body.Bytes(), favicon) {
+		t.Fatalf("static favicon not served")
+	}
+}
+
+// TestWebManifest checks that the web app manifest is populated from the
+// config.
+func TestWebManifest(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.SiteName = "Test Hub"
+	cfg.ThemeColor = "#112233"
+	hub := newTestHub(t, cfg, &fakeLnd{})
+
+	w := httptest.NewRecorder()
+	hub.WebManifest(w, httptest.NewRequest(
+		"GET", "/manifest.webmanifest", nil,
+	))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200", w.Code)
+	}
+	ct := w.Header().Get("Content-Type")
+	if ct != "application/manifest+json" {
+		t.Fatalf("got content type %q, want application/manifest+json",
+			ct)
+	}
+
+	var manifest webAppManifest
+	if err := json.NewDecoder(w.Body).Decode(&manifest); err != nil {
+		t.Fatalf("unable to decode manifest: %v", err)
+	}
+	if manifest.Name != "Test Hub" || manifest.ThemeColor != "#112233" {
+		t.Fatalf("got name %q and theme color %q, want Test Hub and "+
+			"#112233", manifest.Name, manifest.ThemeColor)
+	}
+	if len(manifest.Icons) != 2 {
+		t.Fatalf("got %d icons, want 2", len(manifest.Icons))
+	}
+}
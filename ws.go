@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteTimeout is the maximum time to write a message to a
+	// WebSocket client before dropping it.
+	wsWriteTimeout = 10 * time.Second
+
+	// wsPingInterval is how often WebSocket clients are pinged, to detect
+	// the ones gone away without closing their connection.
+	wsPingInterval = 30 * time.Second
+)
+
+// statsBroadcaster fans out every new template context to the WebSocket
+// clients, so they all share the updates of the background refresher.
+type statsBroadcaster struct {
+	mtx         sync.Mutex
+	subscribers map[chan *templateContext]struct{}
+}
+
+// newStatsBroadcaster creates a broadcaster with no subscribers.
+func newStatsBroadcaster() *statsBroadcaster {
+	return &statsBroadcaster{
+		subscribers: make(map[chan *templateContext]struct{}),
+	}
+}
+
+// subscribe returns a channel receiving every new template context, unless
+// the maximum number of subscribers was reached.
+func (b *statsBroadcaster) subscribe(max int) (chan *templateContext, bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if len(b.subscribers) >= max {
+		return nil, false
+	}
+
+	updates := make(chan *templateContext, 1)
+	b.subscribers[updates] = struct{}{}
+	return updates, true
+}
+
+// unsubscribe stops sending updates to the passed channel.
+func (b *statsBroadcaster) unsubscribe(updates chan *templateContext) {
+	b.mtx.Lock()
+	delete(b.subscribers, updates)
+	b.mtx.Unlock()
+}
+
+// publish sends the passed template context to every subscriber. A
+// subscriber that hasn't received the previous update yet only gets the
+// latest one, so a slow client never blocks the refresher.
+func (b *statsBroadcaster) publish(homeCtx *templateContext) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for updates := range b.subscribers {
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- homeCtx
+	}
+}
+
+// wsUpgrader upgrades the WebSocket requests. Only same origin requests are
+// accepted.
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// LiveStats upgrades the request to a WebSocket and pushes the public stats
+// of the info API (see APIInfo) whenever they're refreshed, starting with the
+// current ones.
+//
+// NOTE: This method implements the http.Handler interface.
+func (h *lightningHub) LiveStats(w http.ResponseWriter, r *http.Request) {
+	updates, ok := h.broadcaster.subscribe(h.cfg.MaxWSClients)
+	if !ok {
+		http.Error(w, "503 Service Unavailable.", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.broadcaster.unsubscribe(updates)
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debugf("Unable to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// We don't expect any message from the client, but must read from
+	// the connection to process the control messages and find out when
+	// it's closed.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(homeInfo *templateContext) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return conn.WriteJSON(newInfoResponse(homeInfo))
+	}
+	if homeInfo := h.cachedContext(); homeInfo != nil {
+		if err := send(homeInfo); err != nil {
+			return
+		}
+	}
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case homeInfo := <-updates:
+			if err := send(homeInfo); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			deadline := time.Now().Add(wsWriteTimeout)
+			err := conn.WriteControl(websocket.PingMessage, nil, deadline)
+			if err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+
+		case <-h.quit:
+			return
+		}
+	}
+}
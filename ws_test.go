@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestLiveStats checks that WebSocket clients get the current stats, then
+// every update, and that the number of clients is capped.
+func TestLiveStats(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxWSClients = 1
+	hub := newTestHub(t, cfg, &fakeLnd{})
+	defer close(hub.quit)
+
+	err := hub.setContext(&templateContext{
+		Alias:         "hub",
+		DcrlndVersion: "0.2.1",
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(hub.LiveStats))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	defer conn.Close()
+
+	receive := func() *infoResponse {
+		t.Helper()
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var info infoResponse
+		if err := conn.ReadJSON(&info); err != nil {
+			t.Fatalf("unable to read stats: %v", err)
+		}
+		return &info
+	}
+	if info := receive(); info.Alias != "hub" {
+		t.Fatalf("got current stats of %q, want hub", info.Alias)
+	}
+
+	err = hub.setContext(&templateContext{
+		Alias:         "renamed hub",
+		DcrlndVersion: "0.2.1",
+	})
+	if err != nil {
+		t.Fatalf("unable to set context: %v", err)
+	}
+	if info := receive(); info.Alias != "renamed hub" {
+		t.Fatalf("got updated stats of %q, want renamed hub",
+			info.Alias)
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("client above the maximum accepted")
+	}
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got response %v, want status 503", resp)
+	}
+}